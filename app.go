@@ -5,8 +5,10 @@ import (
 	"log"
 	"os"
 	"sync"
+	"time"
 
 	"github.com/insionng/zenpress/helper"
+	"github.com/insionng/zenpress/model"
 	"github.com/insionng/zenpress/module/core"
 	"github.com/insionng/zenpress/module/hook"
 
@@ -86,6 +88,10 @@ func main() {
 
 	//------------------------------------------------------//
 
+	//定时发布文章
+	stopScheduler := model.StartFuturePostScheduler(time.Minute)
+	defer close(stopScheduler)
+
 	//------------------------------------------------------//
 
 	watcher, err := fsnotify.NewWatcher()
@@ -19,16 +19,15 @@ var Exports = map[string]interface{}{
 	"TIME_FMT":         helper.TIME_FMT,
 	"TIME_FMT_CN":      helper.TIME_FMT_CN,
 
-	"AesConstKey":   helper.AesConstKey,
-	"AesKey":        helper.AesKey,
-	"AesPublicKey":  helper.AesPublicKey,
-	"MailAdline":    helper.MailAdline,
-	"MailPassword":  helper.MailPassword,
-	"MailUser":      helper.MailUser,
-	"RsaPrivateKey": helper.RsaPrivateKey,
-	"RsaPublicKey":  helper.RsaPublicKey,
-	"SmtpHost":      helper.SmtpHost,
-	"SmtpPort":      helper.SmtpPort,
+	"AesConstKey":      helper.AesConstKey,
+	"AesKey":           helper.AesKey,
+	"AesPublicKey":     helper.AesPublicKey,
+	"ErrImageTooLarge": helper.ErrImageTooLarge,
+	"ErrNoExif":        helper.ErrNoExif,
+	"ImageMaxPixels":   helper.ImageMaxPixels,
+	"PasswordHashCost": helper.PasswordHashCost,
+	"RsaPrivateKey":    helper.RsaPrivateKey,
+	"RsaPublicKey":     helper.RsaPublicKey,
 
 	"Aes128COMDecrypt":             helper.Aes128COMDecrypt,
 	"Aes128COMEncrypt":             helper.Aes128COMEncrypt,
@@ -36,6 +35,8 @@ var Exports = map[string]interface{}{
 	"AesCBCEncrypt":                helper.AesCBCEncrypt,
 	"AesCFBDecrypt":                helper.AesCFBDecrypt,
 	"AesCFBEncrypt":                helper.AesCFBEncrypt,
+	"AesGCMDecrypt":                helper.AesGCMDecrypt,
+	"AesGCMEncrypt":                helper.AesGCMEncrypt,
 	"AtPages":                      helper.AtPages,
 	"AtPagesGetImages":             helper.AtPagesGetImages,
 	"AtUsers":                      helper.AtUsers,
@@ -43,6 +44,7 @@ var Exports = map[string]interface{}{
 	"Base64Encoding":               helper.Base64Encoding,
 	"C2C":                          helper.C2C,
 	"CheckEmail":                   helper.CheckEmail,
+	"CheckImageSize":               helper.CheckImageSize,
 	"CheckPassword":                helper.CheckPassword,
 	"CheckUsername":                helper.CheckUsername,
 	"Compare":                      helper.Compare,
@@ -96,9 +98,11 @@ var Exports = map[string]interface{}{
 	"GetTimestampInMilliString":    helper.GetTimestampInMilliString,
 	"GetTimestampString":           helper.GetTimestampString,
 	"GetVideoAddress":              helper.GetVideoAddress,
+	"GraphicsFilterProcess":        helper.GraphicsFilterProcess,
 	"GraphicsProcess":              helper.GraphicsProcess,
 	"Gravatar":                     helper.Gravatar,
 	"HTML2str":                     helper.HTML2str,
+	"HashPassword":                 helper.HashPassword,
 	"Hotness":                      helper.Hotness,
 	"Htm2Str":                      helper.Htm2Str,
 	"Htmlquote":                    helper.Htmlquote,
@@ -119,6 +123,7 @@ var Exports = map[string]interface{}{
 	"Markdown2String":              helper.Markdown2String,
 	"Markdown2Text":                helper.Markdown2Text,
 	"MarkdownByPongo2":             helper.MarkdownByPongo2,
+	"MarkdownUnsafe":               helper.MarkdownUnsafe,
 	"Metric":                       helper.Metric,
 	"MoveFile":                     helper.MoveFile,
 	"Nrand":                        helper.Nrand,
@@ -143,9 +148,11 @@ var Exports = map[string]interface{}{
 	"QhotQScore":                   helper.QhotQScore,
 	"QhotVote":                     helper.QhotVote,
 	"RangeRand":                    helper.RangeRand,
+	"ReadExif":                     helper.ReadExif,
 	"Rename":                       helper.Rename,
 	"Resample":                     helper.Resample,
 	"Resize":                       helper.Resize,
+	"ResizeImage":                  helper.ResizeImage,
 	"Rex":                          helper.Rex,
 	"Round":                        helper.Round,
 	"RsaAesReceivingPacket":        helper.RsaAesReceivingPacket,
@@ -154,12 +161,12 @@ var Exports = map[string]interface{}{
 	"RsaEncrypt":                   helper.RsaEncrypt,
 	"S2T":                          helper.S2T,
 	"SHA1":                         helper.SHA1,
+	"SafeURL":                      helper.SafeURL,
 	"Score":                        helper.Score,
-	"SendEmail":                    helper.SendEmail,
-	"SendMail":                     helper.SendMail,
 	"SendPacket":                   helper.SendPacket,
 	"SetJsonCOMEncrypt":            helper.SetJsonCOMEncrypt,
 	"SetSuffix":                    helper.SetSuffix,
+	"SizeFormat":                   helper.SizeFormat,
 	"SmcTimeSince":                 helper.SmcTimeSince,
 	"Split":                        helper.Split,
 	"SplitByPongo2":                helper.SplitByPongo2,
@@ -186,6 +193,7 @@ var Exports = map[string]interface{}{
 	"Thumbnail":         helper.Thumbnail,
 	"TimeSince":         helper.TimeSince,
 	"TouchFile":         helper.TouchFile,
+	"TransformImage":    helper.TransformImage,
 	"URL2local":         helper.URL2local,
 	"UnionSets":         helper.UnionSets,
 	"Unix2Time":         helper.Unix2Time,
@@ -193,6 +201,7 @@ var Exports = map[string]interface{}{
 	"UnixNS2Time":       helper.UnixNS2Time,
 	"Unlink":            helper.Unlink,
 	"ValidateHash":      helper.ValidateHash,
+	"VerifyPassword":    helper.VerifyPassword,
 	"VerifyUserfile":    helper.VerifyUserfile,
 	"VideoTags":         helper.VideoTags,
 	"Watermark":         helper.Watermark,
@@ -202,4 +211,11 @@ var Exports = map[string]interface{}{
 
 	"Page":     spec.StructOf((*helper.Page)(nil)),
 	"PageUtil": helper.PageUtil,
+
+	"DiffDelete":   helper.DiffDelete,
+	"DiffEqual":    helper.DiffEqual,
+	"DiffInsert":   helper.DiffInsert,
+	"DiffLine":     spec.StructOf((*helper.DiffLine)(nil)),
+	"DiffLines":    helper.DiffLines,
+	"DiffMaxCells": helper.DiffMaxCells,
 }
@@ -18,6 +18,244 @@ var Exports = map[string]interface{}{
 	"Enforcer":            model.Enforcer,
 	"HasDatabase":         model.HasDatabase,
 
+	"NavMenuItemPostType": model.NavMenuItemPostType,
+	"NavMenuTaxonomy":     model.NavMenuTaxonomy,
+
+	"ErrInvalidPostStatus": model.ErrInvalidPostStatus,
+	"ErrPostNotInTrash":    model.ErrPostNotInTrash,
+	"PostStatusAutoDraft":  model.PostStatusAutoDraft,
+	"PostStatusDraft":      model.PostStatusDraft,
+	"PostStatusFuture":     model.PostStatusFuture,
+	"PostStatusInherit":    model.PostStatusInherit,
+	"PostStatusPending":    model.PostStatusPending,
+	"PostStatusPrivate":    model.PostStatusPrivate,
+	"PostStatusPublish":    model.PostStatusPublish,
+	"PostStatusTrash":      model.PostStatusTrash,
+
+	"DefaultPostFormatOption": model.DefaultPostFormatOption,
+	"ErrInvalidPostFormat":    model.ErrInvalidPostFormat,
+	"PostFormatHTML":          model.PostFormatHTML,
+	"PostFormatMarkdown":      model.PostFormatMarkdown,
+	"PostFormatMetaKey":       model.PostFormatMetaKey,
+
+	"ErrNotRevision":      model.ErrNotRevision,
+	"PostRevisionsOption": model.PostRevisionsOption,
+	"PostTypeRevision":    model.PostTypeRevision,
+
+	"ErrBuiltinPostType":  model.ErrBuiltinPostType,
+	"ErrInvalidPostType":  model.ErrInvalidPostType,
+	"ErrPostTypeNotFound": model.ErrPostTypeNotFound,
+
+	"AttachedFileMetaKey":       model.AttachedFileMetaKey,
+	"AttachmentAltMetaKey":      model.AttachmentAltMetaKey,
+	"AttachmentMetadataMetaKey": model.AttachmentMetadataMetaKey,
+	"AttachmentPostType":        model.AttachmentPostType,
+	"ErrUploadTooLarge":         model.ErrUploadTooLarge,
+	"ErrUploadType":             model.ErrUploadType,
+	"UploadDir":                 model.UploadDir,
+	"UploadMaxRequestSize":      model.UploadMaxRequestSize,
+	"UploadMaxSize":             model.UploadMaxSize,
+	"UploadMimeTypes":           model.UploadMimeTypes,
+	"UploadURLPrefix":           model.UploadURLPrefix,
+
+	"ErrImageTransformForbidden": model.ErrImageTransformForbidden,
+	"ErrImageTransformInvalid":   model.ErrImageTransformInvalid,
+	"ImageCacheDir":              model.ImageCacheDir,
+	"ImageTransformFormats":      model.ImageTransformFormats,
+	"ImageTransformMaxSize":      model.ImageTransformMaxSize,
+	"ImageTransformModes":        model.ImageTransformModes,
+	"ImageTransformSizesOption":  model.ImageTransformSizesOption,
+	"ImageTransformURLPrefix":    model.ImageTransformURLPrefix,
+	"ImageWatermarkFile":         model.ImageWatermarkFile,
+	"SecretKeyOption":            model.SecretKeyOption,
+
+	"AttachmentPHAMetaKey":          model.AttachmentPHAMetaKey,
+	"DefaultDuplicateImageDistance": model.DefaultDuplicateImageDistance,
+	"DuplicateImageDistanceOption":  model.DuplicateImageDistanceOption,
+	"ErrUploadFileAttached":         model.ErrUploadFileAttached,
+
+	"CommentApprove":             model.CommentApprove,
+	"CommentHold":                model.CommentHold,
+	"CommentMaxLength":           model.CommentMaxLength,
+	"CommentModerationOption":    model.CommentModerationOption,
+	"CommentSpam":                model.CommentSpam,
+	"CommentStatusClosed":        model.CommentStatusClosed,
+	"CommentStatusOpen":          model.CommentStatusOpen,
+	"CommentTrash":               model.CommentTrash,
+	"DefaultThreadCommentsDepth": model.DefaultThreadCommentsDepth,
+	"ErrCommentAuthorRequired":   model.ErrCommentAuthorRequired,
+	"ErrCommentNotSpam":          model.ErrCommentNotSpam,
+	"ErrCommentTooDeep":          model.ErrCommentTooDeep,
+	"ErrCommentTooLong":          model.ErrCommentTooLong,
+	"ErrCommentsClosed":          model.ErrCommentsClosed,
+	"ErrEmptyComment":            model.ErrEmptyComment,
+	"ErrInvalidCommentAuthorURL": model.ErrInvalidCommentAuthorURL,
+	"ErrInvalidCommentParent":    model.ErrInvalidCommentParent,
+	"ErrInvalidCommentStatus":    model.ErrInvalidCommentStatus,
+	"ThreadCommentsDepthOption":  model.ThreadCommentsDepthOption,
+
+	"CommentBlocklistOption":      model.CommentBlocklistOption,
+	"CommentCaptchaOption":        model.CommentCaptchaOption,
+	"CommentFloodIntervalOption":  model.CommentFloodIntervalOption,
+	"CommentHoneypotField":        model.CommentHoneypotField,
+	"CommentHourlyLimitOption":    model.CommentHourlyLimitOption,
+	"CommentMaxLinksOption":       model.CommentMaxLinksOption,
+	"CommentSpamBayesFile":        model.CommentSpamBayesFile,
+	"CommentSpamMinTrained":       model.CommentSpamMinTrained,
+	"CommentSpamReasonsMetaKey":   model.CommentSpamReasonsMetaKey,
+	"CommentSpamThresholdOption":  model.CommentSpamThresholdOption,
+	"CommentSpamTrainedMetaKey":   model.CommentSpamTrainedMetaKey,
+	"DefaultCommentFloodInterval": model.DefaultCommentFloodInterval,
+	"DefaultCommentHourlyLimit":   model.DefaultCommentHourlyLimit,
+	"DefaultCommentMaxLinks":      model.DefaultCommentMaxLinks,
+	"DefaultCommentSpamThreshold": model.DefaultCommentSpamThreshold,
+	"ErrCommentFlood":             model.ErrCommentFlood,
+
+	"CommentOrderBest":          model.CommentOrderBest,
+	"CommentOrderControversial": model.CommentOrderControversial,
+	"CommentOrderNewest":        model.CommentOrderNewest,
+	"CommentOrderOldest":        model.CommentOrderOldest,
+	"CommentOrderOption":        model.CommentOrderOption,
+	"ErrInvalidVote":            model.ErrInvalidVote,
+	"ErrInvalidVoter":           model.ErrInvalidVoter,
+	"HomePostOrderDate":         model.HomePostOrderDate,
+	"HomePostOrderHot":          model.HomePostOrderHot,
+	"HomePostOrderOption":       model.HomePostOrderOption,
+	"PostHotnessMetaKey":        model.PostHotnessMetaKey,
+	"VoteDown":                  model.VoteDown,
+	"VoteDownsMetaKey":          model.VoteDownsMetaKey,
+	"VoteUp":                    model.VoteUp,
+	"VoteUpsMetaKey":            model.VoteUpsMetaKey,
+	"VoterMetaKeyPrefix":        model.VoterMetaKeyPrefix,
+
+	"AdminEmailOption":            model.AdminEmailOption,
+	"BlogNameOption":              model.BlogNameOption,
+	"CommentModerationEmail":      model.CommentModerationEmail,
+	"CommentNotifyEmail":          model.CommentNotifyEmail,
+	"CommentNotifyField":          model.CommentNotifyField,
+	"CommentNotifyMetaKey":        model.CommentNotifyMetaKey,
+	"CommentReplyEmail":           model.CommentReplyEmail,
+	"CommentReplyNotifiedMetaKey": model.CommentReplyNotifiedMetaKey,
+	"CommentReplyNotifyOption":    model.CommentReplyNotifyOption,
+	"CommentUnsubscribePath":      model.CommentUnsubscribePath,
+	"CommentsNotifyOption":        model.CommentsNotifyOption,
+	"DefaultBlogName":             model.DefaultBlogName,
+	"ErrEmailRendererNotSet":      model.ErrEmailRendererNotSet,
+	"ErrInvalidUnsubscribeLink":   model.ErrInvalidUnsubscribeLink,
+	"ModerationNotifyOption":      model.ModerationNotifyOption,
+	"SiteURLOption":               model.SiteURLOption,
+
+	"DefaultMailSpoolDir":      model.DefaultMailSpoolDir,
+	"ErrInvalidMailTransport":  model.ErrInvalidMailTransport,
+	"ErrMailNotFound":          model.ErrMailNotFound,
+	"MailBatchSize":            model.MailBatchSize,
+	"MailClaimTimeout":         model.MailClaimTimeout,
+	"MailFromOption":           model.MailFromOption,
+	"MailKeepSentDays":         model.MailKeepSentDays,
+	"MailMaxAttempts":          model.MailMaxAttempts,
+	"MailRetryDelays":          model.MailRetryDelays,
+	"MailSMTPEncryptionOption": model.MailSMTPEncryptionOption,
+	"MailSMTPHostOption":       model.MailSMTPHostOption,
+	"MailSMTPPasswordOption":   model.MailSMTPPasswordOption,
+	"MailSMTPPortOption":       model.MailSMTPPortOption,
+	"MailSMTPUserOption":       model.MailSMTPUserOption,
+	"MailSendmailPathOption":   model.MailSendmailPathOption,
+	"MailSpoolDirOption":       model.MailSpoolDirOption,
+	"MailStatusFailed":         model.MailStatusFailed,
+	"MailStatusPending":        model.MailStatusPending,
+	"MailStatusSending":        model.MailStatusSending,
+	"MailStatusSent":           model.MailStatusSent,
+	"MailTransportFile":        model.MailTransportFile,
+	"MailTransportOption":      model.MailTransportOption,
+	"MailTransportSMTP":        model.MailTransportSMTP,
+	"MailTransportSendmail":    model.MailTransportSendmail,
+
+	"ErrInvalidCredentials": model.ErrInvalidCredentials,
+	"ErrUserDisabled":       model.ErrUserDisabled,
+	"LoginAccountLimit":     model.LoginAccountLimit,
+	"LoginAccountThrottle":  model.LoginAccountThrottle,
+	"LoginIPLimit":          model.LoginIPLimit,
+	"LoginIPThrottle":       model.LoginIPThrottle,
+	"LoginThrottleWindow":   model.LoginThrottleWindow,
+	"RememberMetaPrefix":    model.RememberMetaPrefix,
+	"SessionEpochMetaKey":   model.SessionEpochMetaKey,
+
+	"ActivatePath":            model.ActivatePath,
+	"DefaultRoleOption":       model.DefaultRoleOption,
+	"ErrInvalidActivationKey": model.ErrInvalidActivationKey,
+	"ErrInvalidDefaultRole":   model.ErrInvalidDefaultRole,
+	"ErrInvalidPassword":      model.ErrInvalidPassword,
+	"ErrInvalidResetKey":      model.ErrInvalidResetKey,
+	"ErrInvalidUserEmail":     model.ErrInvalidUserEmail,
+	"ErrInvalidUserLogin":     model.ErrInvalidUserLogin,
+	"ErrRegistrationClosed":   model.ErrRegistrationClosed,
+	"ErrUserEmailExists":      model.ErrUserEmailExists,
+	"ErrUserLoginExists":      model.ErrUserLoginExists,
+	"ErrUserNotFound":         model.ErrUserNotFound,
+	"PasswordMaxLength":       model.PasswordMaxLength,
+	"PasswordMinLength":       model.PasswordMinLength,
+	"PasswordResetEmail":      model.PasswordResetEmail,
+	"PasswordResetTTL":        model.PasswordResetTTL,
+	"ResetPasswordPath":       model.ResetPasswordPath,
+	"SignupActivationEmail":   model.SignupActivationEmail,
+	"SignupActivationTTL":     model.SignupActivationTTL,
+	"SubscriberRoleName":      model.SubscriberRoleName,
+	"UsersCanRegisterOption":  model.UsersCanRegisterOption,
+
+	"ErrInvalidTwoFactorCode":       model.ErrInvalidTwoFactorCode,
+	"ErrTwoFactorNotEnabled":        model.ErrTwoFactorNotEnabled,
+	"ErrTwoFactorNotEnrolling":      model.ErrTwoFactorNotEnrolling,
+	"ErrTwoFactorSecretCorrupt":     model.ErrTwoFactorSecretCorrupt,
+	"TwoFactorLastStepMetaKey":      model.TwoFactorLastStepMetaKey,
+	"TwoFactorPendingSecretMetaKey": model.TwoFactorPendingSecretMetaKey,
+	"TwoFactorPermissionName":       model.TwoFactorPermissionName,
+	"TwoFactorPermissionURL":        model.TwoFactorPermissionURL,
+	"TwoFactorRecoveryCodeCount":    model.TwoFactorRecoveryCodeCount,
+	"TwoFactorRecoveryMetaKey":      model.TwoFactorRecoveryMetaKey,
+	"TwoFactorSecretMetaKey":        model.TwoFactorSecretMetaKey,
+	"TwoFactorThrottle":             model.TwoFactorThrottle,
+
+	"AdminPermissionName":   model.AdminPermissionName,
+	"AdminPermissionURL":    model.AdminPermissionURL,
+	"AdministratorRoleName": model.AdministratorRoleName,
+
+	"AccessTokenHintLength":      model.AccessTokenHintLength,
+	"AccessTokenMaxExpireDays":   model.AccessTokenMaxExpireDays,
+	"AccessTokenNameMaxLength":   model.AccessTokenNameMaxLength,
+	"AccessTokenPrefix":          model.AccessTokenPrefix,
+	"AccessTokenTouchInterval":   model.AccessTokenTouchInterval,
+	"ErrAccessTokenNotFound":     model.ErrAccessTokenNotFound,
+	"ErrAccessTokenScope":        model.ErrAccessTokenScope,
+	"ErrAccessTokenSessionOnly":  model.ErrAccessTokenSessionOnly,
+	"ErrAccessTokenTwoFactor":    model.ErrAccessTokenTwoFactor,
+	"ErrInvalidAccessToken":      model.ErrInvalidAccessToken,
+	"ErrInvalidAccessTokenName":  model.ErrInvalidAccessTokenName,
+	"ErrInvalidAccessTokenScope": model.ErrInvalidAccessTokenScope,
+	"ErrInvalidAccessTokenTTL":   model.ErrInvalidAccessTokenTTL,
+
+	"ErrSearchBackendNotFound": model.ErrSearchBackendNotFound,
+	"SearchBackendIndex":       model.SearchBackendIndex,
+	"SearchBackendMysql":       model.SearchBackendMysql,
+	"SearchBackendOption":      model.SearchBackendOption,
+	"SearchBackendPostgres":    model.SearchBackendPostgres,
+	"SearchBackendSqlite":      model.SearchBackendSqlite,
+
+	"AuthorAvatarMetaKey":      model.AuthorAvatarMetaKey,
+	"AuthorDescriptionMetaKey": model.AuthorDescriptionMetaKey,
+	"AuthorURLMetaKey":         model.AuthorURLMetaKey,
+
+	"ErrInvalidPermalink":      model.ErrInvalidPermalink,
+	"OldSlugMetaKey":           model.OldSlugMetaKey,
+	"PermalinkStructureOption": model.PermalinkStructureOption,
+	"PermalinkStructures":      model.PermalinkStructures,
+
+	"ErrBuiltinTaxonomy":   model.ErrBuiltinTaxonomy,
+	"ErrEmptyTermName":     model.ErrEmptyTermName,
+	"ErrInvalidTaxonomy":   model.ErrInvalidTaxonomy,
+	"ErrInvalidTermParent": model.ErrInvalidTermParent,
+	"ErrTaxonomyNotFound":  model.ErrTaxonomyNotFound,
+	"ErrTermExists":        model.ErrTermExists,
+
 	"AddLink":                                 model.AddLink,
 	"AddOption":                               model.AddOption,
 	"ConnDatabase":                            model.ConnDatabase,
@@ -42,6 +280,7 @@ var Exports = map[string]interface{}{
 	"GetLink":                                 model.GetLink,
 	"GetOption":                               model.GetOption,
 	"Init":                                    model.Init,
+	"LoadEnforcer":                            model.LoadEnforcer,
 	"Login":                                   model.Login,
 	"NewDatabase":                             model.NewDatabase,
 	"NewLink":                                 model.NewLink,
@@ -53,36 +292,398 @@ var Exports = map[string]interface{}{
 	"SaveUser":                                model.SaveUser,
 	"SaveUserRole":                            model.SaveUserRole,
 	"SetDatabase":                             model.SetDatabase,
+	"SetUserDisabled":                         model.SetUserDisabled,
+	"SetUserPassword":                         model.SetUserPassword,
 	"UpdateLink":                              model.UpdateLink,
 	"UpdateOption":                            model.UpdateOption,
 	"UpdatePermission":                        model.UpdatePermission,
 	"UpdateRole":                              model.UpdateRole,
 	"UpdateUser":                              model.UpdateUser,
 
+	"AddNavMenuItem":           model.AddNavMenuItem,
+	"CreateNavMenu":            model.CreateNavMenu,
+	"DeleteNavMenu":            model.DeleteNavMenu,
+	"DeleteNavMenuItem":        model.DeleteNavMenuItem,
+	"DeletePostmetaByKey":      model.DeletePostmetaByKey,
+	"GetNavMenu":               model.GetNavMenu,
+	"GetNavMenuByName":         model.GetNavMenuByName,
+	"GetNavMenuItem":           model.GetNavMenuItem,
+	"GetNavMenuItems":          model.GetNavMenuItems,
+	"GetNavMenuLocations":      model.GetNavMenuLocations,
+	"GetNavMenuTree":           model.GetNavMenuTree,
+	"GetNavMenuTreeByLocation": model.GetNavMenuTreeByLocation,
+	"GetNavMenus":              model.GetNavMenus,
+	"GetOptionValue":           model.GetOptionValue,
+	"GetPermalink":             model.GetPermalink,
+	"GetPost":                  model.GetPost,
+	"GetPostmetaByKey":         model.GetPostmetaByKey,
+	"GetPostsByType":           model.GetPostsByType,
+	"GetPostmetaValue":         model.GetPostmetaValue,
+	"GetTerm":                  model.GetTerm,
+	"GetTermLink":              model.GetTermLink,
+	"GetTermsByTaxonomy":       model.GetTermsByTaxonomy,
+	"SaveNavMenuStructure":     model.SaveNavMenuStructure,
+	"SetNavMenuLocation":       model.SetNavMenuLocation,
+	"SetNavMenuLocations":      model.SetNavMenuLocations,
+	"SetOptionValue":           model.SetOptionValue,
+	"SetPostmetaValue":         model.SetPostmetaValue,
+	"UpdateNavMenuItem":        model.UpdateNavMenuItem,
+
+	"InsertPost":               model.InsertPost,
+	"IsValidPostStatus":        model.IsValidPostStatus,
+	"PagePosts":                model.PagePosts,
+	"PublishFuturePosts":       model.PublishFuturePosts,
+	"PublishPost":              model.PublishPost,
+	"RemovePost":               model.RemovePost,
+	"SavePost":                 model.SavePost,
+	"SetPostStatus":            model.SetPostStatus,
+	"StartFuturePostScheduler": model.StartFuturePostScheduler,
+	"TrashPost":                model.TrashPost,
+	"UntrashPost":              model.UntrashPost,
+	"UpdatePostCommentCount":   model.UpdatePostCommentCount,
+
+	"GetDefaultPostFormat":       model.GetDefaultPostFormat,
+	"GetPostContentHTML":         model.GetPostContentHTML,
+	"GetPostFormat":              model.GetPostFormat,
+	"GetPostFormats":             model.GetPostFormats,
+	"IsValidPostFormat":          model.IsValidPostFormat,
+	"RebuildPostContentFiltered": model.RebuildPostContentFiltered,
+	"RegisterPostFormat":         model.RegisterPostFormat,
+	"RenderPostContent":          model.RenderPostContent,
+	"SetPostFormat":              model.SetPostFormat,
+
+	"AutosavePost":          model.AutosavePost,
+	"DeletePostAutosaves":   model.DeletePostAutosaves,
+	"DiffPostRevisions":     model.DiffPostRevisions,
+	"GetPostAutosave":       model.GetPostAutosave,
+	"GetPostRevision":       model.GetPostRevision,
+	"GetPostRevisions":      model.GetPostRevisions,
+	"GetPostRevisionsLimit": model.GetPostRevisionsLimit,
+	"RestorePostRevision":   model.RestorePostRevision,
+	"SavePostRevision":      model.SavePostRevision,
+
+	"GetAdminPostTypes":        model.GetAdminPostTypes,
+	"GetChildPosts":            model.GetChildPosts,
+	"GetCustomPostTypes":       model.GetCustomPostTypes,
+	"GetPostType":              model.GetPostType,
+	"GetPostTypeByArchiveSlug": model.GetPostTypeByArchiveSlug,
+	"GetPostTypes":             model.GetPostTypes,
+	"GetPublishedPostByName":   model.GetPublishedPostByName,
+	"PostTypeSupports":         model.PostTypeSupports,
+	"RegisterPostType":         model.RegisterPostTypeByMap,
+	"RegisterPostTypeOptions":  model.RegisterPostType,
+	"UnregisterPostType":       model.UnregisterPostType,
+
+	"DeleteAttachment":       model.DeleteAttachment,
+	"GetAttachedFile":        model.GetAttachedFile,
+	"GetAttachment":          model.GetAttachment,
+	"GetAttachmentMetadata":  model.GetAttachmentMetadata,
+	"GetAttachmentURL":       model.GetAttachmentURL,
+	"GetAttachments":         model.GetAttachments,
+	"GetPublishedAttachment": model.GetPublishedAttachment,
+	"InsertAttachment":       model.InsertAttachment,
+	"IsImageAttachment":      model.IsImageAttachment,
+	"PageAttachments":        model.PageAttachments,
+	"ParseUploadForm":        model.ParseUploadForm,
+	"SetAttachmentMetadata":  model.SetAttachmentMetadata,
+	"UploadAttachments":      model.UploadAttachments,
+
+	"GetAttachmentImage":       model.GetAttachmentImage,
+	"GetAttachmentImageSizes":  model.GetAttachmentImageSizes,
+	"GetAttachmentImageSrc":    model.GetAttachmentImageSrc,
+	"GetAttachmentImageSrcset": model.GetAttachmentImageSrcset,
+	"GetImageSize":             model.GetImageSize,
+	"GetImageSizes":            model.GetImageSizes,
+	"ImageSizeDimensions":      model.ImageSizeDimensions,
+	"ImageSizeQuality":         model.ImageSizeQuality,
+	"RegenerateImageSizes":     model.RegenerateImageSizes,
+	"RegisterImageSize":        model.RegisterImageSize,
+	"SetBuiltinImageSize":      model.SetBuiltinImageSize,
+	"UnregisterImageSize":      model.UnregisterImageSize,
+
+	"ClearImageCache":        model.ClearImageCache,
+	"ImageTransformURL":      model.ImageTransformURL,
+	"MakeImageTransform":     model.MakeImageTransform,
+	"ParseImageTransform":    model.ParseImageTransform,
+	"SetImageTransformSizes": model.SetImageTransformSizes,
+
+	"Authenticate":             model.Authenticate,
+	"CreateRememberToken":      model.CreateRememberToken,
+	"GetSignedUser":            model.GetSignedUser,
+	"RevokeRememberToken":      model.RevokeRememberToken,
+	"RevokeUserLogins":         model.RevokeUserLogins,
+	"RevokeUserRememberTokens": model.RevokeUserRememberTokens,
+	"RevokeUserSessions":       model.RevokeUserSessions,
+	"UserSessionEpoch":         model.UserSessionEpoch,
+	"ValidateRememberToken":    model.ValidateRememberToken,
+
+	"ActivateSignup":         model.ActivateSignup,
+	"ActivationURL":          model.ActivationURL,
+	"CheckPasswordResetKey":  model.CheckPasswordResetKey,
+	"DefaultUserRole":        model.DefaultUserRole,
+	"FindUserByLoginOrEmail": model.FindUserByLoginOrEmail,
+	"PasswordResetURL":       model.PasswordResetURL,
+	"Register":               model.Register,
+	"RequestPasswordReset":   model.RequestPasswordReset,
+	"ResetPassword":          model.ResetPassword,
+	"SendActivationEmail":    model.SendActivationEmail,
+	"SendPasswordResetEmail": model.SendPasswordResetEmail,
+	"SetDefaultRole":         model.SetDefaultRole,
+	"UsersCanRegister":       model.UsersCanRegister,
+	"ValidatePassword":       model.ValidatePassword,
+
+	"CountRecoveryCodes":      model.CountRecoveryCodes,
+	"DisableTwoFactor":        model.DisableTwoFactor,
+	"EnableTwoFactor":         model.EnableTwoFactor,
+	"RegenerateRecoveryCodes": model.RegenerateRecoveryCodes,
+	"RoleRequiresTwoFactor":   model.RoleRequiresTwoFactor,
+	"SetRoleTwoFactor":        model.SetRoleTwoFactor,
+	"TwoFactorEnabled":        model.TwoFactorEnabled,
+	"TwoFactorEnrollment":     model.TwoFactorEnrollment,
+	"TwoFactorPermission":     model.TwoFactorPermission,
+	"TwoFactorRoleIDs":        model.TwoFactorRoleIDs,
+	"UserRequiresTwoFactor":   model.UserRequiresTwoFactor,
+	"VerifyTwoFactor":         model.VerifyTwoFactor,
+
+	"AdminPermission":         model.AdminPermission,
+	"CanAccessAdmin":          model.CanAccessAdmin,
+	"EnsureAdministratorRole": model.EnsureAdministratorRole,
+
+	"AuthenticateAccessToken":  model.AuthenticateAccessToken,
+	"CreateAccessToken":        model.CreateAccessToken,
+	"FindAccessToken":          model.FindAccessToken,
+	"FindAccessTokensByUserID": model.FindAccessTokensByUserID,
+	"RevokeAccessToken":        model.RevokeAccessToken,
+	"RevokeUserAccessTokens":   model.RevokeUserAccessTokens,
+
+	"GetSecretKey":    model.GetSecretKey,
+	"SignValue":       model.SignValue,
+	"VerifySignature": model.VerifySignature,
+
+	"FindDuplicateAttachments":  model.FindDuplicateAttachments,
+	"GetDuplicateImageDistance": model.GetDuplicateImageDistance,
+	"GetDuplicateImageScan":     model.GetDuplicateImageScan,
+	"ImagePHA":                  model.ImagePHA,
+	"RemoveUploadFile":          model.RemoveUploadFile,
+	"ScanDuplicateImages":       model.ScanDuplicateImages,
+	"StartDuplicateImageScan":   model.StartDuplicateImageScan,
+
+	"BulkCommentAction":      model.BulkCommentAction,
+	"CommentsOpen":           model.CommentsOpen,
+	"DeleteCommentmetaByKey": model.DeleteCommentmetaByKey,
+	"GetCommentCounts":       model.GetCommentCounts,
+	"GetCommentDepth":        model.GetCommentDepth,
+	"GetCommentmetaByKey":    model.GetCommentmetaByKey,
+	"GetCommentmetaValue":    model.GetCommentmetaValue,
+	"GetThreadCommentsDepth": model.GetThreadCommentsDepth,
+	"GetThreadedComments":    model.GetThreadedComments,
+	"InsertComment":          model.InsertComment,
+	"IsValidCommentStatus":   model.IsValidCommentStatus,
+	"PageComments":           model.PageComments,
+	"ParseCommentIDs":        model.ParseCommentIDs,
+	"RemoveComment":          model.RemoveComment,
+	"RestoreComment":         model.RestoreComment,
+	"SetCommentStatus":       model.SetCommentStatus,
+	"SetCommentmetaValue":    model.SetCommentmetaValue,
+	"UnspamComment":          model.UnspamComment,
+
+	"CheckCommentFlood":           model.CheckCommentFlood,
+	"CheckCommentSpam":            model.CheckCommentSpam,
+	"CommentSpamClassifier":       model.CommentSpamClassifier,
+	"GetCommentSpamReasons":       model.GetCommentSpamReasons,
+	"GetCommentSpamScorers":       model.GetCommentSpamScorers,
+	"GetCommentSpamThreshold":     model.GetCommentSpamThreshold,
+	"RegisterCommentSpamScorer":   model.RegisterCommentSpamScorer,
+	"TrainCommentSpam":            model.TrainCommentSpam,
+	"UnregisterCommentSpamScorer": model.UnregisterCommentSpamScorer,
+
+	"GetCommentOrder":           model.GetCommentOrder,
+	"GetCommentVote":            model.GetCommentVote,
+	"GetCommentVotes":           model.GetCommentVotes,
+	"GetHomePostOrder":          model.GetHomePostOrder,
+	"GetPostVote":               model.GetPostVote,
+	"GetPostVotes":              model.GetPostVotes,
+	"GetSortedThreadedComments": model.GetSortedThreadedComments,
+	"IsValidCommentOrder":       model.IsValidCommentOrder,
+	"NewVotes":                  model.NewVotes,
+	"PageHomePosts":             model.PageHomePosts,
+	"VoteComment":               model.VoteComment,
+	"VotePost":                  model.VotePost,
+	"Voter":                     model.Voter,
+
+	"CommentUnsubscribeURL":    model.CommentUnsubscribeURL,
+	"GetAdminEmail":            model.GetAdminEmail,
+	"GetBlogName":              model.GetBlogName,
+	"GetSiteURL":               model.GetSiteURL,
+	"NotifyCommentReply":       model.NotifyCommentReply,
+	"NotifyPostAuthor":         model.NotifyPostAuthor,
+	"RenderEmail":              model.RenderEmail,
+	"SetCommentNotify":         model.SetCommentNotify,
+	"SetEmailRenderer":         model.SetEmailRenderer,
+	"UnsubscribeCommentNotify": model.UnsubscribeCommentNotify,
+	"VerifyCommentUnsubscribe": model.VerifyCommentUnsubscribe,
+
+	"CountMailQueue":   model.CountMailQueue,
+	"DeleteMail":       model.DeleteMail,
+	"GetMail":          model.GetMail,
+	"GetMailFrom":      model.GetMailFrom,
+	"GetMailTransport": model.GetMailTransport,
+	"GetMailer":        model.GetMailer,
+	"PageMailQueue":    model.PageMailQueue,
+	"ProcessMailQueue": model.ProcessMailQueue,
+	"QueueMail":        model.QueueMail,
+	"RetryMail":        model.RetryMail,
+	"SendMail":         model.SendMail,
+	"SendTestMail":     model.SendTestMail,
+	"SetMailer":        model.SetMailer,
+	"StartMailQueue":   model.StartMailQueue,
+
+	"CurrentSearchBackend":  model.CurrentSearchBackend,
+	"GetSearchBackends":     model.GetSearchBackends,
+	"GetSearchPostTypes":    model.GetSearchPostTypes,
+	"RebuildSearchIndex":    model.RebuildSearchIndex,
+	"RegisterSearchBackend": model.RegisterSearchBackend,
+	"Search":                model.Search,
+	"SearchHighlight":       model.SearchHighlight,
+	"SearchPosts":           model.SearchPosts,
+	"SearchQueryTokens":     model.SearchQueryTokens,
+	"SearchTokenize":        model.SearchTokenize,
+	"SetSearchBackend":      model.SetSearchBackend,
+
+	"CompareAndSetUsermetaValue": model.CompareAndSetUsermetaValue,
+	"DeleteUsermetaByKey":        model.DeleteUsermetaByKey,
+	"GetAuthorLink":              model.GetAuthorLink,
+	"GetAuthorProfile":           model.GetAuthorProfile,
+	"GetUserByNicename":          model.GetUserByNicename,
+	"GetUsermetaByKey":           model.GetUsermetaByKey,
+	"GetUsermetaValue":           model.GetUsermetaValue,
+	"PageAuthorPosts":            model.PageAuthorPosts,
+	"SetUsermetaValue":           model.SetUsermetaValue,
+
+	"DateArchiveRange":   model.DateArchiveRange,
+	"GetDateArchiveLink": model.GetDateArchiveLink,
+	"GetMonthlyArchives": model.GetMonthlyArchives,
+	"GetYearlyArchives":  model.GetYearlyArchives,
+	"PageDatePosts":      model.PageDatePosts,
+
+	"ActivePermalinkStructure":   model.ActivePermalinkStructure,
+	"BuildPermalink":             model.BuildPermalink,
+	"CanonicalPermalink":         model.CanonicalPermalink,
+	"GetPermalinkStructure":      model.GetPermalinkStructure,
+	"GetPostByOldSlug":           model.GetPostByOldSlug,
+	"LoadPermalinkStructure":     model.LoadPermalinkStructure,
+	"PermalinkRoute":             model.PermalinkRoute,
+	"ResolvePermalink":           model.ResolvePermalink,
+	"SetPermalinkStructure":      model.SetPermalinkStructure,
+	"UniquePostName":             model.UniquePostName,
+	"ValidatePermalinkStructure": model.ValidatePermalinkStructure,
+
+	"DeleteObjectTermRelationships": model.DeleteObjectTermRelationships,
+	"DeleteTaxonomyTerm":            model.DeleteTaxonomyTerm,
+	"GetAdminTaxonomies":            model.GetAdminTaxonomies,
+	"GetObjectTaxonomies":           model.GetObjectTaxonomies,
+	"GetObjectTermIDs":              model.GetObjectTermIDs,
+	"GetObjectTermNames":            model.GetObjectTermNames,
+	"GetObjectTerms":                model.GetObjectTerms,
+	"GetTaxonomies":                 model.GetTaxonomies,
+	"GetTaxonomy":                   model.GetTaxonomy,
+	"GetTaxonomyByRewriteSlug":      model.GetTaxonomyByRewriteSlug,
+	"GetTaxonomyTerm":               model.GetTaxonomyTerm,
+	"GetTaxonomyTermByName":         model.GetTaxonomyTermByName,
+	"GetTaxonomyTermBySlug":         model.GetTaxonomyTermBySlug,
+	"GetTaxonomyTermLink":           model.GetTaxonomyTermLink,
+	"GetTaxonomyTermTree":           model.GetTaxonomyTermTree,
+	"GetTaxonomyTerms":              model.GetTaxonomyTerms,
+	"GetTaxonomyTermsHierarchy":     model.GetTaxonomyTermsHierarchy,
+	"GetTermAncestors":              model.GetTermAncestors,
+	"GetTermDescendantIDs":          model.GetTermDescendantIDs,
+	"InsertTerm":                    model.InsertTerm,
+	"PageTermPosts":                 model.PageTermPosts,
+	"ParseTermTaxonomyIDs":          model.ParseTermTaxonomyIDs,
+	"RegisterTaxonomy":              model.RegisterTaxonomyByMap,
+	"RegisterTaxonomyForObjectType": model.RegisterTaxonomyForObjectType,
+	"RegisterTaxonomyOptions":       model.RegisterTaxonomy,
+	"RemoveObjectTerms":             model.RemoveObjectTerms,
+	"SanitizeTermSlug":              model.SanitizeTermSlug,
+	"SetObjectTerms":                model.SetObjectTerms,
+	"SetObjectTermsByName":          model.SetObjectTermsByName,
+	"UniqueTermSlug":                model.UniqueTermSlug,
+	"UnregisterTaxonomy":            model.UnregisterTaxonomy,
+	"UpdateTaxonomyTerm":            model.UpdateTaxonomyTerm,
+	"UpdateTermCount":               model.UpdateTermCount,
+
 	"App":                spec.StructOf((*model.App)(nil)),
 	"AppVersion":         spec.StructOf((*model.AppVersion)(nil)),
+	"AttachmentMetadata": spec.StructOf((*model.AttachmentMetadata)(nil)),
+	"AttachmentOptions":  spec.StructOf((*model.AttachmentOptions)(nil)),
+	"AttachmentSize":     spec.StructOf((*model.AttachmentSize)(nil)),
+	"AuthorProfile":      spec.StructOf((*model.AuthorProfile)(nil)),
+
+	"DuplicateAttachment": spec.StructOf((*model.DuplicateAttachment)(nil)),
+	"DuplicateGroup":      spec.StructOf((*model.DuplicateGroup)(nil)),
+	"DuplicateImage":      spec.StructOf((*model.DuplicateImage)(nil)),
+	"DuplicateImageScan":  spec.StructOf((*model.DuplicateImageScan)(nil)),
+
+	"CommentBayes":      spec.StructOf((*model.CommentBayes)(nil)),
+	"CommentSpamReason": spec.StructOf((*model.CommentSpamReason)(nil)),
+	"CommentSpamResult": spec.StructOf((*model.CommentSpamResult)(nil)),
+
+	"Votes": spec.StructOf((*model.Votes)(nil)),
+
+	"AccessToken":         spec.StructOf((*model.AccessToken)(nil)),
+	"LoginThrottledError": spec.StructOf((*model.LoginThrottledError)(nil)),
+
+	"Mail":      spec.StructOf((*model.Mail)(nil)),
+	"MailQueue": spec.StructOf((*model.MailQueue)(nil)),
+
+	"ImageSize":          spec.StructOf((*model.ImageSize)(nil)),
+	"ImageTransform":     spec.StructOf((*model.ImageTransform)(nil)),
+	"SearchDocument":     spec.StructOf((*model.SearchDocument)(nil)),
+	"SearchHit":          spec.StructOf((*model.SearchHit)(nil)),
+	"SearchQuery":        spec.StructOf((*model.SearchQuery)(nil)),
+	"SearchResult":       spec.StructOf((*model.SearchResult)(nil)),
+	"ThreadedComment":    spec.StructOf((*model.ThreadedComment)(nil)),
 	"Comment":            spec.StructOf((*model.Comment)(nil)),
+	"CommentCounts":      spec.StructOf((*model.CommentCounts)(nil)),
+	"CommentListItem":    spec.StructOf((*model.CommentListItem)(nil)),
 	"Commentmeta":        spec.StructOf((*model.Commentmeta)(nil)),
 	"Link":               spec.StructOf((*model.Link)(nil)),
 	"Model":              spec.StructOf((*model.Model)(nil)),
+	"MonthArchive":       spec.StructOf((*model.MonthArchive)(nil)),
+	"NavMenu":            spec.StructOf((*model.NavMenu)(nil)),
+	"NavMenuItem":        spec.StructOf((*model.NavMenuItem)(nil)),
+	"NavMenuOrder":       spec.StructOf((*model.NavMenuOrder)(nil)),
 	"Option":             spec.StructOf((*model.Option)(nil)),
 	"Permission":         spec.StructOf((*model.Permission)(nil)),
 	"FindPermissionById": model.FindPermissionById,
 	"Post":               spec.StructOf((*model.Post)(nil)),
+	"PostTransition":     spec.StructOf((*model.PostTransition)(nil)),
+	"PostFormat":         spec.StructOf((*model.PostFormat)(nil)),
+	"PostType":           spec.StructOf((*model.PostType)(nil)),
+	"PostTypeLabels":     spec.StructOf((*model.PostTypeLabels)(nil)),
+	"PostTypeOptions":    spec.StructOf((*model.PostTypeOptions)(nil)),
 	"Postmeta":           spec.StructOf((*model.Postmeta)(nil)),
 	"RegistrationLog":    spec.StructOf((*model.RegistrationLog)(nil)),
 	"Result":             spec.StructOf((*model.Result)(nil)),
+	"RevisionDiff":       spec.StructOf((*model.RevisionDiff)(nil)),
 	"Role":               spec.StructOf((*model.Role)(nil)),
 	"FindRoleById":       model.FindRoleById,
 	"RolePermission":     spec.StructOf((*model.RolePermission)(nil)),
 	"Signup":             spec.StructOf((*model.Signup)(nil)),
 	"Site":               spec.StructOf((*model.Site)(nil)),
 	"Sitemeta":           spec.StructOf((*model.Sitemeta)(nil)),
+	"Taxonomy":           spec.StructOf((*model.Taxonomy)(nil)),
+	"TaxonomyLabels":     spec.StructOf((*model.TaxonomyLabels)(nil)),
+	"TaxonomyOptions":    spec.StructOf((*model.TaxonomyOptions)(nil)),
+	"TaxonomyTerm":       spec.StructOf((*model.TaxonomyTerm)(nil)),
 	"Term":               spec.StructOf((*model.Term)(nil)),
+	"TermOptions":        spec.StructOf((*model.TermOptions)(nil)),
 	"TermRelationship":   spec.StructOf((*model.TermRelationship)(nil)),
 	"TermTaxonomy":       spec.StructOf((*model.TermTaxonomy)(nil)),
 	"Termmeta":           spec.StructOf((*model.Termmeta)(nil)),
 	"User":               spec.StructOf((*model.User)(nil)),
+	"UserRole":           spec.StructOf((*model.UserRole)(nil)),
 	"UserRoleResult":     spec.StructOf((*model.UserRoleResult)(nil)),
 	"Usermeta":           spec.StructOf((*model.Usermeta)(nil)),
+	"YearArchive":        spec.StructOf((*model.YearArchive)(nil)),
 }
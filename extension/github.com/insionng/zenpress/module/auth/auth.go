@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"github.com/insionng/zenpress/module/auth"
+
+	"qlang.io/spec"
+)
+
+// Exports is the export table of this module.
+//
+var Exports = map[string]interface{}{
+	"_name": "github.com/insionng/zenpress/module/auth",
+
+	"AccountPathPrefix":        auth.AccountPathPrefix,
+	"AdminPathPrefix":          auth.AdminPathPrefix,
+	"CSRFField":                auth.CSRFField,
+	"CSRFHeader":               auth.CSRFHeader,
+	"ContextAccessTokenKey":    auth.ContextAccessTokenKey,
+	"ContextTokenUserKey":      auth.ContextTokenUserKey,
+	"RememberCookie":           auth.RememberCookie,
+	"RememberDuration":         auth.RememberDuration,
+	"ScopeAdmin":               auth.ScopeAdmin,
+	"ScopeRead":                auth.ScopeRead,
+	"ScopeWrite":               auth.ScopeWrite,
+	"Scopes":                   auth.Scopes,
+	"SessionCSRFKey":           auth.SessionCSRFKey,
+	"SessionEpochKey":          auth.SessionEpochKey,
+	"SessionSigninKey":         auth.SessionSigninKey,
+	"SessionTwoFactorKey":      auth.SessionTwoFactorKey,
+	"SessionUserKey":           auth.SessionUserKey,
+	"SigninCookie":             auth.SigninCookie,
+	"SigninPath":               auth.SigninPath,
+	"TOTPDigits":               auth.TOTPDigits,
+	"TOTPPeriod":               auth.TOTPPeriod,
+	"TOTPSecretSize":           auth.TOTPSecretSize,
+	"TOTPSkew":                 auth.TOTPSkew,
+	"TwoFactorPendingDuration": auth.TwoFactorPendingDuration,
+	"TwoFactorSigninPath":      auth.TwoFactorSigninPath,
+
+	"BearerToken":           auth.BearerToken,
+	"DeleteCookie":          auth.DeleteCookie,
+	"GetCookie":             auth.GetCookie,
+	"HasScope":              auth.HasScope,
+	"IsSafeMethod":          auth.IsSafeMethod,
+	"IsSecure":              auth.IsSecure,
+	"NewTOTPSecret":         auth.NewTOTPSecret,
+	"NewThrottle":           auth.NewThrottle,
+	"NewToken":              auth.NewToken,
+	"NewTwoFactorPending":   auth.NewTwoFactorPending,
+	"NextURL":               auth.NextURL,
+	"ParseTwoFactorPending": auth.ParseTwoFactorPending,
+	"QRCodeDataURI":         auth.QRCodeDataURI,
+	"RequestCSRFToken":      auth.RequestCSRFToken,
+	"RequiredScopes":        auth.RequiredScopes,
+	"SafeRedirect":          auth.SafeRedirect,
+	"SessionOnly":           auth.SessionOnly,
+	"SetCookie":             auth.SetCookie,
+	"SigninURL":             auth.SigninURL,
+	"TOTPCode":              auth.TOTPCode,
+	"TOTPStep":              auth.TOTPStep,
+	"TOTPURI":               auth.TOTPURI,
+	"VerifyTOTP":            auth.VerifyTOTP,
+	"VerifyToken":           auth.VerifyToken,
+
+	"Throttle": spec.StructOf((*auth.Throttle)(nil)),
+}
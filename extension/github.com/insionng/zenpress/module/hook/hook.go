@@ -15,6 +15,7 @@ var Exports = map[string]interface{}{
 
 	"AddActionHook":            hook.AddActionHook,
 	"AddFilterHook":            hook.AddFilterHook,
+	"ApplyFilterHook":          hook.ApplyFilterHook,
 	"DoActionHook":             hook.DoActionHook,
 	"DoFilterHook":             hook.DoFilterHook,
 	"HasActionHook":            hook.HasActionHook,
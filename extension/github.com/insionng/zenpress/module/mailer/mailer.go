@@ -0,0 +1,39 @@
+package mailer
+
+import (
+	"github.com/insionng/zenpress/module/mailer"
+
+	"qlang.io/spec"
+)
+
+// Exports is the export table of this module.
+//
+var Exports = map[string]interface{}{
+	"_name": "github.com/insionng/zenpress/module/mailer",
+
+	"DefaultSendmailPath": mailer.DefaultSendmailPath,
+	"DefaultTimeout":      mailer.DefaultTimeout,
+	"EncryptionNone":      mailer.EncryptionNone,
+	"EncryptionSSL":       mailer.EncryptionSSL,
+	"EncryptionSTARTTLS":  mailer.EncryptionSTARTTLS,
+
+	"ErrInvalidEncryption":    mailer.ErrInvalidEncryption,
+	"ErrNoRecipients":         mailer.ErrNoRecipients,
+	"ErrNoSender":             mailer.ErrNoSender,
+	"ErrSTARTTLSNotSupported": mailer.ErrSTARTTLSNotSupported,
+	"MailAdline":              mailer.MailAdline,
+	"MailPassword":            mailer.MailPassword,
+	"MailUser":                mailer.MailUser,
+	"SmtpHost":                mailer.SmtpHost,
+	"SmtpPort":                mailer.SmtpPort,
+
+	"HTMLToText": mailer.HTMLToText,
+	"SendEmail":  mailer.SendEmail,
+	"SendMail":   mailer.SendMail,
+
+	"Attachment":     spec.StructOf((*mailer.Attachment)(nil)),
+	"FileMailer":     spec.StructOf((*mailer.FileMailer)(nil)),
+	"Message":        spec.StructOf((*mailer.Message)(nil)),
+	"SMTPMailer":     spec.StructOf((*mailer.SMTPMailer)(nil)),
+	"SendmailMailer": spec.StructOf((*mailer.SendmailMailer)(nil)),
+}
@@ -0,0 +1,38 @@
+package theme
+
+import (
+	"github.com/insionng/zenpress/module/theme"
+
+	"qlang.io/spec"
+)
+
+// Exports is the export table of this module.
+//
+var Exports = map[string]interface{}{
+	"_name": "github.com/insionng/zenpress/module/theme",
+
+	"EmailTemplateDir": theme.EmailTemplateDir,
+	"ThemeDir":         theme.ThemeDir,
+
+	"AuthorTemplates":          theme.AuthorTemplates,
+	"Content":                  theme.Content,
+	"EmailRenderer":            theme.EmailRenderer,
+	"Funcs":                    theme.Funcs,
+	"LoadManifest":             theme.LoadManifest,
+	"Locate":                   theme.Locate,
+	"LocateAuthor":             theme.LocateAuthor,
+	"LocateEmail":              theme.LocateEmail,
+	"LocatePostTypeArchive":    theme.LocatePostTypeArchive,
+	"LocateSingle":             theme.LocateSingle,
+	"LocateTaxonomy":           theme.LocateTaxonomy,
+	"NavMenu":                  theme.NavMenu,
+	"Permalink":                theme.Permalink,
+	"PostTypeArchiveTemplates": theme.PostTypeArchiveTemplates,
+	"RegisterImageSizes":       theme.RegisterImageSizes,
+	"SingleTemplates":          theme.SingleTemplates,
+	"TaxonomyTemplates":        theme.TaxonomyTemplates,
+	"TermLink":                 theme.TermLink,
+	"Themer":                   theme.Themer,
+
+	"Manifest": spec.StructOf((*theme.Manifest)(nil)),
+}
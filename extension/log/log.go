@@ -0,0 +1,15 @@
+package log
+
+import (
+	"log"
+)
+
+// Exports is the export table of this module.
+//
+var Exports = map[string]interface{}{
+	"_name": "log",
+
+	"Print":   log.Print,
+	"Printf":  log.Printf,
+	"Println": log.Println,
+}
@@ -0,0 +1,112 @@
+package helper
+
+import (
+	"strings"
+)
+
+const (
+	DiffEqual  = "equal"  //未变更的行
+	DiffInsert = "insert" //新增的行
+	DiffDelete = "delete" //删除的行
+)
+
+// DiffLine 行级差异中的一行，OldLine与NewLine为行号，不存在时为0
+type DiffLine struct {
+	Type    string
+	OldLine int
+	NewLine int
+	Text    string
+}
+
+// DiffMaxCells 最长公共子序列表的最大单元数，去除首尾相同的行后仍超出时，不再逐行比较中间部分
+const DiffMaxCells = 4 << 20
+
+// DiffLines 按行比较两段文本，返回基于最长公共子序列的行级差异
+func DiffLines(oldText, newText string) []DiffLine {
+	a := splitLines(oldText)
+	b := splitLines(newText)
+
+	// 首尾相同的行无需进入最长公共子序列表
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var lines []DiffLine
+	for i := 0; i < prefix; i++ {
+		lines = append(lines, DiffLine{Type: DiffEqual, OldLine: i + 1, NewLine: i + 1, Text: a[i]})
+	}
+	lines = append(lines, diffMiddle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix], prefix, prefix)...)
+	for k := suffix; k > 0; k-- {
+		i, j := len(a)-k, len(b)-k
+		lines = append(lines, DiffLine{Type: DiffEqual, OldLine: i + 1, NewLine: j + 1, Text: a[i]})
+	}
+	return lines
+}
+
+// diffMiddle 以最长公共子序列比较a与b，offsetA与offsetB为其在原文中的起始行；
+// 表格超出DiffMaxCells时整段标记为删除及新增
+func diffMiddle(a, b []string, offsetA, offsetB int) []DiffLine {
+	var lines []DiffLine
+	if len(a) == 0 || len(b) == 0 || len(a)*len(b) > DiffMaxCells {
+		for i := range a {
+			lines = append(lines, DiffLine{Type: DiffDelete, OldLine: offsetA + i + 1, Text: a[i]})
+		}
+		for j := range b {
+			lines = append(lines, DiffLine{Type: DiffInsert, NewLine: offsetB + j + 1, Text: b[j]})
+		}
+		return lines
+	}
+
+	// lcs[i][j] 为 a[i:] 与 b[j:] 的最长公共子序列长度
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, DiffLine{Type: DiffEqual, OldLine: offsetA + i + 1, NewLine: offsetB + j + 1, Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, DiffLine{Type: DiffDelete, OldLine: offsetA + i + 1, Text: a[i]})
+			i++
+		default:
+			lines = append(lines, DiffLine{Type: DiffInsert, NewLine: offsetB + j + 1, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, DiffLine{Type: DiffDelete, OldLine: offsetA + i + 1, Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, DiffLine{Type: DiffInsert, NewLine: offsetB + j + 1, Text: b[j]})
+	}
+	return lines
+}
+
+func splitLines(text string) []string {
+	if len(text) == 0 {
+		return nil
+	}
+	text = strings.Replace(text, "\r\n", "\n", -1)
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
@@ -0,0 +1,160 @@
+package helper
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrNoExif 图片中没有EXIF信息
+var ErrNoExif = errors.New("no exif data")
+
+// exifTags 读取的EXIF标签，键为标签号，值为ReadExif返回的键名
+var exifTags = map[uint16]string{
+	0x010E: "caption",
+	0x010F: "make",
+	0x0110: "model",
+	0x0112: "orientation",
+	0x0132: "datetime",
+	0x013B: "credit",
+	0x8298: "copyright",
+	0x829A: "shutter_speed",
+	0x829D: "aperture",
+	0x8827: "iso",
+	0x9003: "created_timestamp",
+	0x920A: "focal_length",
+	0xA434: "lens",
+}
+
+// ReadExif 读取JPEG图片中常用的EXIF信息，如相机型号、光圈、快门、ISO、焦距及拍摄时间
+func ReadExif(r io.Reader) (map[string]string, error) {
+	var marker [2]byte
+	if _, err := io.ReadFull(r, marker[:]); err != nil {
+		return nil, err
+	}
+	if marker[0] != 0xFF || marker[1] != 0xD8 {
+		return nil, ErrNoExif
+	}
+
+	//依次读取JPEG的段，找到以Exif开头的APP1段，遇到图像数据时停止
+	for {
+		var header [4]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return nil, ErrNoExif
+		}
+		if header[0] != 0xFF || header[1] == 0xDA || header[1] == 0xD9 {
+			return nil, ErrNoExif
+		}
+		length := int(binary.BigEndian.Uint16(header[2:])) - 2
+		if length < 0 {
+			return nil, ErrNoExif
+		}
+		segment := make([]byte, length)
+		if _, err := io.ReadFull(r, segment); err != nil {
+			return nil, err
+		}
+		if header[1] == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return parseExif(segment[6:])
+		}
+	}
+}
+
+func parseExif(tiff []byte) (map[string]string, error) {
+	if len(tiff) < 8 {
+		return nil, ErrNoExif
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, ErrNoExif
+	}
+
+	exif := map[string]string{}
+	var subIFD uint32
+	readIFD := func(offset uint32) {
+		if int(offset)+2 > len(tiff) {
+			return
+		}
+		count := int(order.Uint16(tiff[offset:]))
+		for i := 0; i < count; i++ {
+			entry := int(offset) + 2 + i*12
+			if entry+12 > len(tiff) {
+				return
+			}
+			tag := order.Uint16(tiff[entry:])
+			if tag == 0x8769 {
+				subIFD = order.Uint32(tiff[entry+8:])
+				continue
+			}
+			if name, okay := exifTags[tag]; okay {
+				if value := exifValue(tiff, order, tiff[entry:entry+12]); len(value) > 0 {
+					exif[name] = value
+				}
+			}
+		}
+	}
+
+	readIFD(order.Uint32(tiff[4:]))
+	if subIFD > 0 {
+		readIFD(subIFD)
+	}
+	if len(exif) == 0 {
+		return nil, ErrNoExif
+	}
+	return exif, nil
+}
+
+// exifValue 按字段类型读取EXIF字段的值，支持ASCII、SHORT、LONG及RATIONAL
+func exifValue(tiff []byte, order binary.ByteOrder, entry []byte) string {
+	kind := order.Uint16(entry[2:])
+	count := int(order.Uint32(entry[4:]))
+	var size int
+	switch kind {
+	case 2:
+		size = count
+	case 3:
+		size = 2
+	case 4:
+		size = 4
+	case 5:
+		size = 8
+	default:
+		return ""
+	}
+
+	data := entry[8:12]
+	if size > 4 {
+		offset := int(order.Uint32(entry[8:]))
+		if offset < 0 || offset+size > len(tiff) {
+			return ""
+		}
+		data = tiff[offset : offset+size]
+	} else if size < 0 {
+		return ""
+	}
+
+	switch kind {
+	case 2:
+		return strings.TrimSpace(strings.TrimRight(string(data[:size]), "\x00"))
+	case 3:
+		return fmt.Sprint(order.Uint16(data))
+	case 4:
+		return fmt.Sprint(order.Uint32(data))
+	default:
+		num, den := order.Uint32(data), order.Uint32(data[4:])
+		if den == 0 {
+			return ""
+		}
+		if num < den && num > 0 && den%num == 0 {
+			return fmt.Sprintf("1/%d", den/num)
+		}
+		return fmt.Sprint(Round(float64(num)/float64(den), 2))
+	}
+}
@@ -38,7 +38,7 @@ func grayscaleImg(src image.Image) []byte {
 	for x := 0; x < w; x++ {
 		for y := 0; y < h; y++ {
 			r, g, b, _ := src.At(x, y).RGBA()
-			gray[x+y*8] = byte((r*30 + g*59 + b*11) / 100)
+			gray[x+y*8] = byte((r*30 + g*59 + b*11) / 100 >> 8)
 		}
 	}
 	return gray
@@ -71,6 +71,9 @@ func CompareDiff(fg1, fg2 string) int {
 	diff := 0
 	fbyte := []byte(fg1)
 	fbyte2 := []byte(fg2)
+	if len(fbyte) != len(fbyte2) {
+		return len(fbyte) + len(fbyte2)
+	}
 	for i, v := range fbyte {
 		if fbyte2[i] != v {
 			diff++
@@ -85,6 +88,7 @@ func GetImagePha(path string) (string, error) {
 	if infile, err := os.Open(path); err != nil {
 		return "", err
 	} else {
+		defer infile.Close()
 
 		// Decode picture.
 		if srcImg, _, err := image.Decode(infile); err != nil {
@@ -36,6 +36,7 @@ import (
 	"mime/multipart"
 	"net/http"
 	"net/http/cookiejar"
+	"net/url"
 	"os"
 	"os/exec"
 	fpath "path"
@@ -79,6 +80,21 @@ func FileSize(file string) (int64, error) {
 	return f.Size(), nil
 }
 
+//SizeFormat 将字节数格式化为B、KB、MB或GB
+func SizeFormat(size int64) string {
+	units := []string{"B", "KB", "MB", "GB"}
+	value := float64(size)
+	i := 0
+	for value >= 1024 && i < len(units)-1 {
+		value /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%d %s", size, units[i])
+	}
+	return fmt.Sprintf("%.1f %s", value, units[i])
+}
+
 //Unlink 删除文件
 func Unlink(file string) error {
 	return os.Remove(file)
@@ -1081,6 +1097,41 @@ func AesCFBDecrypt(ciphertext string, privateKey string, publicKey string) (stri
 	return string(contentCopy), err
 }
 
+//AesGCMEncrypt AES加密 GCM模式，随机nonce置于密文之前
+//key 为 16、24 或 32 bytes
+func AesGCMEncrypt(plaintext, key []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = io.ReadFull(crand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+//AesGCMDecrypt AES解密 GCM模式，密文被篡改或密钥不符时返回错误
+func AesGCMDecrypt(ciphertext, key []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, sealed := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, sealed, nil)
+}
+
 //RsaEncrypt RSA加密
 func RsaEncrypt(origData []byte, publicKey []byte) ([]byte, error) {
 	block, _ := pem.Decode(publicKey)
@@ -1453,6 +1504,15 @@ func CheckEmail(email string) (b bool) {
 	return true
 }
 
+//SafeURL 仅保留http及https协议的绝对地址，其他协议（如javascript:）的地址返回空字符串
+func SafeURL(rawurl string) string {
+	u, err := url.Parse(strings.TrimSpace(rawurl))
+	if err != nil || len(u.Host) == 0 || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	return u.String()
+}
+
 /*
 #gravity可用值有九个,分别是:
 
@@ -1593,6 +1653,7 @@ func Watermark(watermarkFile string, inputFile string, outputFile string, output
 		if err != nil {
 			return err
 		}
+		defer w.Close()
 
 		if format == "png" {
 			err := png.Encode(w, dst)
@@ -1868,6 +1929,11 @@ func staticProcess(g image.Image, format string, w io.Writer, width, height, qua
 		gift.Resize(width, height, gift.LanczosResampling),
 		//gift.CropToSize(width, height, gift.CenterAnchor),
 	)
+	return staticFilterProcess(g, format, w, filter, quality)
+}
+
+//staticFilterProcess 以滤镜处理静态图像并按format编码，GIF只输出单帧
+func staticFilterProcess(g image.Image, format string, w io.Writer, filter *gift.GIFT, quality int) error {
 
 	//tmp := image.NewNRGBA(g.Bounds())
 	//gift.New().DrawAt(tmp, g, g.Bounds().Min, gift.OverOperator)
@@ -1878,32 +1944,18 @@ func staticProcess(g image.Image, format string, w io.Writer, width, height, qua
 	dst := image.NewRGBA(filter.Bounds(g.Bounds()))
 	filter.Draw(dst, g)
 
-	var err error
-	if format == "png" {
-		err = png.Encode(w, dst)
-		if err != nil {
-			return err
-		}
-	} else {
-		err = jpeg.Encode(w, dst, &jpeg.Options{Quality: quality})
-		if err != nil {
-			return err
-		}
+	switch format {
+	case "png":
+		return png.Encode(w, dst)
+	case "jpeg":
+		return jpeg.Encode(w, dst, &jpeg.Options{Quality: quality})
+	case "gif":
+		return gif.Encode(w, dst, nil)
 	}
-
-	return nil
+	return fmt.Errorf("Not support format:%s", format)
 }
 
-func animateProcess(g *gif.GIF, w io.Writer, width, height int) error {
-
-	filter := gift.New(
-		// high-quality resampling with pixel mixing
-		//gift.ResizeToFit(width, height, gift.LanczosResampling),
-		//gift.ResizeToFill(width, height, gift.LanczosResampling, gift.CenterAnchor),
-		//gift.Resize(width, height, gift.LinearResampling),
-		gift.Resize(width, height, gift.LanczosResampling),
-		//gift.CropToSize(width, height, gift.CenterAnchor),
-	)
+func animateProcess(g *gif.GIF, w io.Writer, filter *gift.GIFT) error {
 
 	var ng = make([]*image.Paletted, 0)
 	tmp := image.NewNRGBA(g.Image[0].Bounds())
@@ -1928,30 +1980,89 @@ func animateProcess(g *gif.GIF, w io.Writer, width, height int) error {
 	return nil
 }
 
+//ImageMaxPixels 解码图片的最大像素数，防止文件很小但尺寸极大的图片在解码时耗尽内存
+var ImageMaxPixels = 50000000
+
+//ErrImageTooLarge 图片尺寸超过ImageMaxPixels
+var ErrImageTooLarge = errors.New("图片尺寸过大")
+
+//CheckImageSize 在解码之前读取图片的尺寸，超过ImageMaxPixels时返回ErrImageTooLarge
+func CheckImageSize(r io.Reader) (format string, err error) {
+	config, format, err := image.DecodeConfig(r)
+	if err != nil {
+		return "", fmt.Errorf("image.DecodeConfig：%v", err)
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width > ImageMaxPixels/config.Height {
+		return format, ErrImageTooLarge
+	}
+	return format, nil
+}
+
 //GraphicsProcess 图像处理过程
 func GraphicsProcess(r io.Reader, w io.Writer, width, height, quality int) error {
+	filter := gift.New(
+		// high-quality resampling with pixel mixing
+		//gift.ResizeToFit(width, height, gift.LanczosResampling),
+		//gift.ResizeToFill(width, height, gift.LanczosResampling, gift.CenterAnchor),
+		//gift.Resize(width, height, gift.LinearResampling),
+		gift.Resize(width, height, gift.LanczosResampling),
+		//gift.CropToSize(width, height, gift.CenterAnchor),
+	)
+	return GraphicsFilterProcess(r, w, filter, "", quality)
+}
+
+//GraphicsFilterProcess 以指定滤镜处理图像并以format格式输出，format为空时保持原格式，GIF动画输出为GIF时逐帧处理；解码前先检查图片尺寸
+func GraphicsFilterProcess(r io.Reader, w io.Writer, filter *gift.GIFT, format string, quality int) error {
 
 	b, err := ioutil.ReadAll(r)
 	if err != nil {
 		return fmt.Errorf("ioutil.ReadAll：%v", err)
 	}
+	if _, err := CheckImageSize(bytes.NewReader(b)); err != nil {
+		return err
+	}
 
-	g, format, err := image.Decode(bytes.NewReader(b))
+	g, source, err := image.Decode(bytes.NewReader(b))
 	if err != nil {
 		return fmt.Errorf("image.Decode(bytes.NewReader(b))：%v", err)
 	}
+	if len(format) == 0 {
+		format = source
+	}
 
-	if (format == "png") || (format == "jpeg") {
-		return staticProcess(g, format, w, width, height, quality)
-	} else if format == "gif" {
+	if source == "gif" && format == "gif" {
 		g, err := gif.DecodeAll(bytes.NewReader(b))
 		if err != nil {
 			return fmt.Errorf("gif.DecodeAll(bytes.NewReader(b))：%v", err)
 		}
-		return animateProcess(g, w, width, height)
-	} else {
-		return errors.New("bad format")
+		return animateProcess(g, w, filter)
 	}
+	return staticFilterProcess(g, format, w, filter, quality)
+}
+
+//ResizeImage 将图片缩放为指定宽高，crop为真时按中心裁剪填满该尺寸，GIF动画逐帧处理
+func ResizeImage(r io.Reader, w io.Writer, width, height, quality int, crop bool) error {
+	resize := gift.Resize(width, height, gift.LanczosResampling)
+	if crop {
+		resize = gift.ResizeToFill(width, height, gift.LanczosResampling, gift.CenterAnchor)
+	}
+	return GraphicsFilterProcess(r, w, gift.New(resize), "", quality)
+}
+
+//TransformImage 按模式处理图片并以指定格式输出，format为空时保持原格式，GIF动画输出为GIF时逐帧处理
+//mode可为fit（等比缩放至不超过宽高）、crop（居中裁剪填满宽高）或resize（缩放为准确宽高，宽或高为0时等比缩放），宽高均为0时仅转换格式
+func TransformImage(r io.Reader, w io.Writer, width, height int, mode, format string, quality int) error {
+	filter := gift.New()
+	switch {
+	case width == 0 && height == 0:
+	case mode == "crop" && width > 0 && height > 0:
+		filter.Add(gift.ResizeToFill(width, height, gift.LanczosResampling, gift.CenterAnchor))
+	case mode == "fit" && width > 0 && height > 0:
+		filter.Add(gift.ResizeToFit(width, height, gift.LanczosResampling))
+	default:
+		filter.Add(gift.Resize(width, height, gift.LanczosResampling))
+	}
+	return GraphicsFilterProcess(r, w, filter, format, quality)
 }
 
 //GetBannerThumbnail 从内容获取banner
@@ -2552,6 +2663,11 @@ func Markdown2Byte(md string) []byte {
 	return bluemonday.UGCPolicy().SanitizeBytes(unsafe)
 }
 
+//MarkdownUnsafe md内容转为未经过滤的html，须自行选择Policy过滤后输出
+func MarkdownUnsafe(md string) string {
+	return string(blackfriday.MarkdownCommon([]byte(md)))
+}
+
 //Markdown2Text md内容转为text
 func Markdown2Text(md string) string {
 	re, _ := regexp.Compile("\\<[\\S\\s]+?\\>")
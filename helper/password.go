@@ -5,11 +5,92 @@ import (
 	"crypto/md5"
 	"crypto/sha1"
 	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/hex"
 	//"fmt"
+	"strings"
 	"time"
+
+	"golang.org/x/crypto/bcrypt"
 )
 
+// PasswordHashCost 生成密码哈希时使用的bcrypt计算强度，低于此强度的哈希在登录成功后重新生成
+var PasswordHashCost = 12
+
+const phpassItoa64 = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// HashPassword 以bcrypt生成密码哈希，密码超过72字节时返回错误
+func HashPassword(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), PasswordHashCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+// VerifyPassword 校验密码，支持bcrypt、旧版48位哈希及WordPress的phpass哈希，
+// rehash为true时表示哈希算法或强度已过时，应在登录成功后以HashPassword重新生成
+func VerifyPassword(hashed, password string) (ok, rehash bool) {
+	switch {
+	case strings.HasPrefix(hashed, "$2a$") || strings.HasPrefix(hashed, "$2b$") || strings.HasPrefix(hashed, "$2y$"):
+		if bcrypt.CompareHashAndPassword([]byte(hashed), []byte(password)) != nil {
+			return false, false
+		}
+		cost, err := bcrypt.Cost([]byte(hashed))
+		return true, err != nil || cost < PasswordHashCost
+	case strings.HasPrefix(hashed, "$P$") || strings.HasPrefix(hashed, "$H$"):
+		return phpassCheck(hashed, password), true
+	case len(hashed) == 48:
+		return ValidateHash(hashed, password), true
+	}
+	return false, false
+}
+
+// phpassCheck 校验WordPress使用的phpass可移植哈希
+func phpassCheck(hashed, password string) bool {
+	if len(hashed) != 34 {
+		return false
+	}
+	countLog2 := strings.IndexByte(phpassItoa64, hashed[3])
+	if countLog2 < 7 || countLog2 > 30 {
+		return false
+	}
+	salt := hashed[4:12]
+	sum := md5.Sum([]byte(salt + password))
+	for count := 1 << uint(countLog2); count > 0; count-- {
+		sum = md5.Sum(append(sum[:], password...))
+	}
+	return subtle.ConstantTimeCompare([]byte(hashed[:12]+phpassEncode64(sum[:])), []byte(hashed)) == 1
+}
+
+func phpassEncode64(input []byte) string {
+	var out []byte
+	for i := 0; i < len(input); {
+		value := int(input[i])
+		i++
+		out = append(out, phpassItoa64[value&0x3f])
+		if i < len(input) {
+			value |= int(input[i]) << 8
+		}
+		out = append(out, phpassItoa64[(value>>6)&0x3f])
+		if i >= len(input) {
+			break
+		}
+		i++
+		if i < len(input) {
+			value |= int(input[i]) << 16
+		}
+		out = append(out, phpassItoa64[(value>>12)&0x3f])
+		if i >= len(input) {
+			break
+		}
+		i++
+		out = append(out, phpassItoa64[(value>>18)&0x3f])
+	}
+	return string(out)
+}
+
+// Deprecated: 密码请使用 HashPassword 生成哈希，EncryptHash 仅用于校验旧版哈希及非密码数据的签名
 func EncryptHash(password string, salt []byte) string {
 	if salt == nil {
 		m := md5.New()
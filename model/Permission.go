@@ -50,5 +50,5 @@ func DeletePermission(permission *Permission) {
 
 func DeleteRolePermissionByPermissionId(permission_id int) {
 	Enforcer.DeletePermission(fmt.Sprintf("%v", permission_id))
-	Database.Exec("delete from role_permissions where permission_id = ?", permission_id)
+	Database.Exec("delete from "+DatabaseTablePrefix+"role_permissions where permission_id = ?", permission_id)
 }
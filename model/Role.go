@@ -3,6 +3,7 @@ package model
 import (
 	"fmt"
 	"strconv"
+	"strings"
 )
 
 type Role struct {
@@ -12,6 +13,19 @@ type Role struct {
 	Permissions []*Permission `gorm:"many2many:role_permissions;"`
 }
 
+// roleSubject 返回角色在权限模块中的名称，加上前缀以免与同样以ID命名的用户混淆
+func roleSubject(id interface{}) string {
+	return fmt.Sprintf("role:%v", id)
+}
+
+// parseRoleSubject 由权限模块中的角色名称解析出角色ID
+func parseRoleSubject(subject string) (uint64, error) {
+	if !strings.HasPrefix(subject, "role:") {
+		return 0, fmt.Errorf("invalid role subject %q", subject)
+	}
+	return strconv.ParseUint(strings.TrimPrefix(subject, "role:"), 10, 64)
+}
+
 func FindRoleById(id int) Role {
 	var role Role
 	Database.Where("id = ?", id).First(&role)
@@ -33,19 +47,25 @@ func UpdateRole(role *Role) {
 	Database.Update(role, "name")
 }
 
+// DeleteRole 删除角色，该角色用户的个人访问令牌随之撤销
 func DeleteRole(role *Role) {
-	Enforcer.DeleteRole(fmt.Sprintf("%v", role.ID))
+	var ids []uint64
+	Database.Table(DatabaseTablePrefix+"user_roles").Where("role_id = ?", role.ID).Pluck("user_id", &ids)
+	for _, id := range ids {
+		RevokeUserAccessTokens(id)
+	}
+	Enforcer.DeleteRole(roleSubject(role.ID))
 	Database.Delete(role)
 }
 
 func DeleteRolePermissionByRoleId(role_id int) {
-	Enforcer.DeletePermissionsForUser(strconv.Itoa(role_id))
-	Database.Exec("delete from role_permissions where role_id = ?", role_id)
+	Enforcer.DeletePermissionsForUser(roleSubject(role_id))
+	Database.Exec("delete from "+DatabaseTablePrefix+"role_permissions where role_id = ?", role_id)
 }
 
 func SaveRolePermission(role_id int, permission_id int) {
-	Enforcer.AddPermissionForUser(fmt.Sprintf("%v", role_id), fmt.Sprintf("%v", permission_id))
-	Database.Exec("insert into role_permissions (role_id, permission_id) values (?, ?)", role_id, permission_id)
+	Enforcer.AddPermissionForUser(roleSubject(role_id), fmt.Sprintf("%v", permission_id))
+	Database.Exec("insert into "+DatabaseTablePrefix+"role_permissions (role_id, permission_id) values (?, ?)", role_id, permission_id)
 }
 
 type Result struct {
@@ -56,6 +76,6 @@ type Result struct {
 
 func FindRolePermissionByRoleId(role_id int) []Result {
 	var res []Result
-	Database.Raw("select id, role_id, permission_id from role_permissions where role_id = ?", role_id).Scan(&res)
+	Database.Raw("select id, role_id, permission_id from "+DatabaseTablePrefix+"role_permissions where role_id = ?", role_id).Scan(&res)
 	return res
 }
@@ -0,0 +1,7 @@
+package model
+
+type UserRole struct {
+	ID     uint64 `gorm:"primary_key"`
+	UserID uint64
+	RoleID uint64
+}
@@ -0,0 +1,196 @@
+package model
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/insionng/zenpress/module/auth"
+)
+
+const (
+	// AccessTokenPrefix 个人访问令牌的前缀，便于在代码及日志中识别
+	AccessTokenPrefix = "zp_"
+	// AccessTokenHintLength 列表中用于辨认令牌而显示的前缀长度
+	AccessTokenHintLength = 8
+	// AccessTokenNameMaxLength 令牌名称的最大长度
+	AccessTokenNameMaxLength = 100
+	// AccessTokenMaxExpireDays 令牌有效期的最大天数
+	AccessTokenMaxExpireDays = 365
+	// AccessTokenTouchInterval 最后使用时间的更新间隔，避免每次请求都写数据库
+	AccessTokenTouchInterval = time.Minute
+)
+
+// 个人访问令牌的错误
+var (
+	ErrInvalidAccessTokenName  = errors.New("请填写令牌名称，最多100个字符")
+	ErrInvalidAccessTokenScope = errors.New("请至少选择一个有效的权限范围")
+	ErrInvalidAccessTokenTTL   = errors.New("有效期须为0至365天，0表示永不过期")
+	ErrAccessTokenNotFound     = errors.New("访问令牌不存在")
+	ErrInvalidAccessToken      = errors.New("访问令牌无效、已过期或已撤销")
+	ErrAccessTokenScope        = errors.New("访问令牌的权限范围不足")
+	ErrAccessTokenSessionOnly  = errors.New("账号安全设置须登录后操作，不能使用访问令牌")
+	ErrAccessTokenTwoFactor    = errors.New("你所属的角色要求两步验证，请先启用两步验证")
+)
+
+// AccessToken 个人访问令牌表。用户为脚本及第三方应用签发的令牌，只保存哈希。
+type AccessToken struct {
+	ID         uint64 `gorm:"primary_key"`
+	UserID     uint64 `gorm:"index"`
+	Name       string `gorm:"not null default '' VARCHAR(100)"`
+	TokenHash  string `gorm:"unique_index"`
+	TokenHint  string `gorm:"not null default '' VARCHAR(20)"`
+	Scopes     string `gorm:"not null default '' VARCHAR(255)"`
+	LastUsedIP string `gorm:"not null default '' VARCHAR(100)"`
+	CreatedAt  time.Time
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
+
+// ScopeList 获得令牌的权限范围
+func (t AccessToken) ScopeList() []string {
+	return strings.Fields(t.Scopes)
+}
+
+// HasScope 判断令牌是否具有指定的权限范围
+func (t AccessToken) HasScope(scope string) bool {
+	return auth.HasScope(t.ScopeList(), scope)
+}
+
+// Expired 判断令牌是否已过期
+func (t AccessToken) Expired() bool {
+	return t.ExpiresAt != nil && !time.Now().Before(*t.ExpiresAt)
+}
+
+// Revoked 判断令牌是否已撤销
+func (t AccessToken) Revoked() bool {
+	return t.RevokedAt != nil
+}
+
+// Active 判断令牌是否可用
+func (t AccessToken) Active() bool {
+	return !t.Expired() && !t.Revoked()
+}
+
+// normalizeAccessTokenScopes 以空格或逗号分隔权限范围，去重排序后以空格连接，包含无效范围时返回空字符串
+func normalizeAccessTokenScopes(scopes string) string {
+	seen := map[string]bool{}
+	for _, scope := range strings.FieldsFunc(strings.ToLower(scopes), func(r rune) bool { return r == ' ' || r == ',' }) {
+		valid := false
+		for _, s := range auth.Scopes {
+			valid = valid || s == scope
+		}
+		if !valid {
+			return ""
+		}
+		seen[scope] = true
+	}
+	list := make([]string, 0, len(seen))
+	for scope := range seen {
+		list = append(list, scope)
+	}
+	sort.Strings(list)
+	return strings.Join(list, " ")
+}
+
+// CreateAccessToken 为用户签发个人访问令牌，scopes以空格或逗号分隔，days为有效天数，0表示永不过期，所属角色要求两步验证时须已启用；
+// 数据库中只保存令牌的哈希，明文只在此时返回
+func CreateAccessToken(userID uint64, name, scopes string, days int) (AccessToken, string, error) {
+	name = strings.TrimSpace(name)
+	if len(name) == 0 || len([]rune(name)) > AccessTokenNameMaxLength {
+		return AccessToken{}, "", ErrInvalidAccessTokenName
+	}
+	if scopes = normalizeAccessTokenScopes(scopes); len(scopes) == 0 {
+		return AccessToken{}, "", ErrInvalidAccessTokenScope
+	}
+	if days < 0 || days > AccessTokenMaxExpireDays {
+		return AccessToken{}, "", ErrInvalidAccessTokenTTL
+	}
+	if UserRequiresTwoFactor(userID) && !TwoFactorEnabled(userID) {
+		return AccessToken{}, "", ErrAccessTokenTwoFactor
+	}
+
+	secret := AccessTokenPrefix + auth.NewToken()
+	token := AccessToken{
+		UserID:    userID,
+		Name:      name,
+		TokenHash: activationKeyHash(secret),
+		TokenHint: secret[:len(AccessTokenPrefix)+AccessTokenHintLength],
+		Scopes:    scopes,
+	}
+	if days > 0 {
+		expires := time.Now().AddDate(0, 0, days)
+		token.ExpiresAt = &expires
+	}
+	if err := Database.Create(&token).Error; err != nil {
+		return AccessToken{}, "", err
+	}
+	return token, secret, nil
+}
+
+// FindAccessTokensByUserID 获得用户的全部访问令牌，新签发的在前
+func FindAccessTokensByUserID(userID uint64) []AccessToken {
+	var tokens []AccessToken
+	Database.Where("user_id = ?", userID).Order("id desc").Find(&tokens)
+	return tokens
+}
+
+// RevokeAccessToken 撤销用户的访问令牌，只能撤销自己的令牌
+func RevokeAccessToken(userID, tokenID uint64) error {
+	db := Database.Model(&AccessToken{}).Where("id = ? and user_id = ? and revoked_at is null", tokenID, userID).Update("revoked_at", time.Now())
+	if db.Error != nil {
+		return db.Error
+	}
+	if db.RowsAffected == 0 {
+		return ErrAccessTokenNotFound
+	}
+	return nil
+}
+
+// RevokeUserAccessTokens 撤销用户的全部访问令牌
+func RevokeUserAccessTokens(userID uint64) {
+	Database.Model(&AccessToken{}).Where("user_id = ? and revoked_at is null", userID).Update("revoked_at", time.Now())
+}
+
+// FindAccessToken 以令牌明文查找可用的访问令牌及其用户，令牌无效、已过期、已撤销或用户已停用时返回false
+func FindAccessToken(secret string) (bool, AccessToken, User) {
+	var token AccessToken
+	if !strings.HasPrefix(secret, AccessTokenPrefix) {
+		return false, AccessToken{}, User{}
+	}
+	if Database.Where("token_hash = ?", activationKeyHash(secret)).First(&token).Error != nil || !token.Active() {
+		return false, AccessToken{}, User{}
+	}
+	ok, user := GetSignedUser(token.UserID)
+	if !ok {
+		return false, AccessToken{}, User{}
+	}
+	return true, token, user
+}
+
+// AuthenticateAccessToken 以Bearer令牌认证请求，检查令牌是否具有请求方法及路径所需的权限范围，并记录最后使用的时间及IP；
+// 账号安全设置只能以会话访问，所属角色要求两步验证而尚未启用时令牌不可用
+func AuthenticateAccessToken(secret, method, path, ip string) (User, AccessToken, error) {
+	ok, token, user := FindAccessToken(secret)
+	if !ok {
+		return User{}, AccessToken{}, ErrInvalidAccessToken
+	}
+	if auth.SessionOnly(path) {
+		return User{}, AccessToken{}, ErrAccessTokenSessionOnly
+	}
+	if UserRequiresTwoFactor(user.ID) && !TwoFactorEnabled(user.ID) {
+		return User{}, AccessToken{}, ErrAccessTokenTwoFactor
+	}
+	for _, scope := range auth.RequiredScopes(method, path) {
+		if !token.HasScope(scope) {
+			return User{}, AccessToken{}, ErrAccessTokenScope
+		}
+	}
+	if now := time.Now(); token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= AccessTokenTouchInterval || token.LastUsedIP != ip {
+		Database.Model(&AccessToken{}).Where("id = ?", token.ID).Updates(map[string]interface{}{"last_used_at": now, "last_used_ip": ip})
+		token.LastUsedAt, token.LastUsedIP = &now, ip
+	}
+	return user, token, nil
+}
@@ -0,0 +1,147 @@
+package model_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/insionng/zenpress/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateAccessToken(t *testing.T) {
+	assert := assert.New(t)
+	user := &model.User{UserLogin: fmt.Sprintf("pat%d", time.Now().UnixNano())}
+	model.SaveUser(user)
+	defer model.Database.Delete(user)
+	defer model.Database.Delete(model.AccessToken{}, "user_id = ?", user.ID)
+
+	_, _, err := model.CreateAccessToken(user.ID, " ", "read", 0)
+	assert.Equal(model.ErrInvalidAccessTokenName, err)
+	_, _, err = model.CreateAccessToken(user.ID, "deploy", "read,sudo", 0)
+	assert.Equal(model.ErrInvalidAccessTokenScope, err)
+	_, _, err = model.CreateAccessToken(user.ID, "deploy", "", 0)
+	assert.Equal(model.ErrInvalidAccessTokenScope, err)
+	_, _, err = model.CreateAccessToken(user.ID, "deploy", "read", model.AccessTokenMaxExpireDays+1)
+	assert.Equal(model.ErrInvalidAccessTokenTTL, err)
+
+	token, secret, err := model.CreateAccessToken(user.ID, " deploy ", "write, read write", 30)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal("deploy", token.Name)
+	assert.Equal("read write", token.Scopes)
+	assert.True(strings.HasPrefix(secret, model.AccessTokenPrefix))
+	assert.True(strings.HasPrefix(secret, token.TokenHint))
+	assert.NotContains(token.TokenHash, secret[len(model.AccessTokenPrefix):], "only the hash of the token should be stored")
+	if assert.NotNil(token.ExpiresAt) {
+		assert.WithinDuration(time.Now().AddDate(0, 0, 30), *token.ExpiresAt, time.Minute)
+	}
+
+	other, otherSecret, _ := model.CreateAccessToken(user.ID, "forever", "read", 0)
+	assert.Nil(other.ExpiresAt)
+	assert.NotEqual(secret, otherSecret)
+	tokens := model.FindAccessTokensByUserID(user.ID)
+	if assert.Len(tokens, 2) {
+		assert.Equal(other.ID, tokens[0].ID, "newer tokens should be listed first")
+	}
+}
+
+func TestAuthenticateAccessToken(t *testing.T) {
+	assert := assert.New(t)
+	user := &model.User{UserLogin: fmt.Sprintf("bearer%d", time.Now().UnixNano())}
+	model.SaveUser(user)
+	defer model.Database.Delete(user)
+	defer model.Database.Delete(model.AccessToken{}, "user_id = ?", user.ID)
+
+	token, secret, _ := model.CreateAccessToken(user.ID, "reader", "read", 0)
+	found, _, err := model.AuthenticateAccessToken(secret, http.MethodGet, "/", "10.0.0.1")
+	if assert.NoError(err) {
+		assert.Equal(user.ID, found.ID)
+	}
+	_, _, err = model.AuthenticateAccessToken(secret, http.MethodPost, "/comment", "10.0.0.1")
+	assert.Equal(model.ErrAccessTokenScope, err)
+	_, _, err = model.AuthenticateAccessToken(secret, http.MethodGet, "/root/", "10.0.0.1")
+	assert.Equal(model.ErrAccessTokenScope, err, "the backend should require the admin scope")
+	_, _, err = model.AuthenticateAccessToken(secret+"x", http.MethodGet, "/", "10.0.0.1")
+	assert.Equal(model.ErrInvalidAccessToken, err)
+	_, _, err = model.AuthenticateAccessToken("", http.MethodGet, "/", "10.0.0.1")
+	assert.Equal(model.ErrInvalidAccessToken, err)
+	ok, tokenUser := model.FindUserByToken(secret)
+	assert.True(ok)
+	assert.Equal(user.ID, tokenUser.ID)
+
+	var used model.AccessToken
+	model.Database.First(&used, token.ID)
+	assert.NotNil(used.LastUsedAt)
+	assert.Equal("10.0.0.1", used.LastUsedIP)
+
+	_, admin, _ := model.CreateAccessToken(user.ID, "admin", "write admin", 0)
+	_, _, err = model.AuthenticateAccessToken(admin, http.MethodPost, "/root/article", "10.0.0.2")
+	assert.NoError(err)
+	_, _, err = model.AuthenticateAccessToken(admin, http.MethodGet, "/root/", "10.0.0.2")
+	assert.NoError(err, "write should include read")
+	_, _, err = model.AuthenticateAccessToken(admin, http.MethodGet, "/root/user/profile", "10.0.0.2")
+	assert.Equal(model.ErrAccessTokenSessionOnly, err, "tokens should not manage the account")
+	_, _, err = model.AuthenticateAccessToken(admin, http.MethodPost, "/root/user/two-factor", "10.0.0.2")
+	assert.Equal(model.ErrAccessTokenSessionOnly, err)
+
+	assert.Equal(model.ErrAccessTokenNotFound, model.RevokeAccessToken(user.ID+1, token.ID), "users should not revoke others' tokens")
+	assert.NoError(model.RevokeAccessToken(user.ID, token.ID))
+	assert.Equal(model.ErrAccessTokenNotFound, model.RevokeAccessToken(user.ID, token.ID))
+	_, _, err = model.AuthenticateAccessToken(secret, http.MethodGet, "/", "10.0.0.1")
+	assert.Equal(model.ErrInvalidAccessToken, err, "revoked tokens should be rejected")
+
+	_, expiring, _ := model.CreateAccessToken(user.ID, "expiring", "read", 1)
+	model.Database.Model(&model.AccessToken{}).Where("name = ? and user_id = ?", "expiring", user.ID).Update("expires_at", time.Now().Add(-time.Second))
+	_, _, err = model.AuthenticateAccessToken(expiring, http.MethodGet, "/", "10.0.0.1")
+	assert.Equal(model.ErrInvalidAccessToken, err, "expired tokens should be rejected")
+
+	model.Database.Model(user).Update("deleted", 1)
+	_, _, err = model.AuthenticateAccessToken(admin, http.MethodGet, "/", "10.0.0.2")
+	assert.Equal(model.ErrInvalidAccessToken, err, "tokens of disabled users should be rejected")
+	model.RevokeUserAccessTokens(user.ID)
+	for _, token := range model.FindAccessTokensByUserID(user.ID) {
+		assert.True(token.Revoked())
+		assert.False(token.Active())
+	}
+}
+
+func TestAccessTokenRevocation(t *testing.T) {
+	assert := assert.New(t)
+	loadEnforcer(t)
+	user := &model.User{UserLogin: fmt.Sprintf("revoke%d", time.Now().UnixNano())}
+	model.SaveUser(user)
+	defer model.Database.Delete(user)
+	defer model.Database.Delete(model.AccessToken{}, "user_id = ?", user.ID)
+	defer model.DeleteUsermetaByKey(user.ID, model.SessionEpochMetaKey)
+	role := &model.Role{Name: fmt.Sprintf("ops%d", time.Now().UnixNano())}
+	model.SaveRole(role)
+	defer model.Database.Delete(role)
+	model.SaveUserRole(int(user.ID), int(role.ID))
+
+	_, secret, _ := model.CreateAccessToken(user.ID, "deploy", "write admin", 0)
+	model.SetRoleTwoFactor(role.ID, true)
+	_, _, err := model.AuthenticateAccessToken(secret, http.MethodGet, "/root/", "10.0.0.1")
+	assert.Equal(model.ErrAccessTokenTwoFactor, err, "tokens should not bypass the two-factor requirement")
+	_, _, err = model.CreateAccessToken(user.ID, "other", "read", 0)
+	assert.Equal(model.ErrAccessTokenTwoFactor, err)
+	model.SetRoleTwoFactor(role.ID, false)
+	_, _, err = model.AuthenticateAccessToken(secret, http.MethodGet, "/root/", "10.0.0.1")
+	assert.NoError(err)
+
+	model.DeleteUserRolesByUserId(int(user.ID))
+	_, _, err = model.AuthenticateAccessToken(secret, http.MethodGet, "/", "10.0.0.1")
+	assert.Equal(model.ErrInvalidAccessToken, err, "removing roles should revoke tokens")
+
+	_, secret, _ = model.CreateAccessToken(user.ID, "deploy", "read", 0)
+	epoch := model.UserSessionEpoch(user.ID)
+	assert.NoError(model.SetUserDisabled(user.ID, true))
+	assert.NotEqual(epoch, model.UserSessionEpoch(user.ID), "disabling should sign out sessions")
+	assert.NoError(model.SetUserDisabled(user.ID, false))
+	_, _, err = model.AuthenticateAccessToken(secret, http.MethodGet, "/", "10.0.0.1")
+	assert.Equal(model.ErrInvalidAccessToken, err, "disabling should revoke tokens")
+}
@@ -0,0 +1,59 @@
+package model
+
+import (
+	"fmt"
+)
+
+const (
+	// AdministratorRoleName 拥有全部后台权限的管理员角色名
+	AdministratorRoleName = "administrator"
+	// AdminPermissionName 访问后台的权限名
+	AdminPermissionName = "root"
+	// AdminPermissionURL 访问后台的权限匹配的路径
+	AdminPermissionURL = "^/root(/.*)?$"
+)
+
+// AdminPermission 获得访问后台的权限，不存在时创建
+func AdminPermission() (Permission, error) {
+	var permission Permission
+	if Database.Where("name = ?", AdminPermissionName).First(&permission).Error == nil {
+		return permission, nil
+	}
+	permission = Permission{Name: AdminPermissionName, URL: AdminPermissionURL, Description: "访问后台"}
+	return permission, Database.Create(&permission).Error
+}
+
+// EnsureAdministratorRole 获得管理员角色，不存在时创建并授予访问后台的权限。
+// 首次创建时，已有的未分配角色且不是自助注册的用户视为原有的管理员，一并加入该角色
+func EnsureAdministratorRole() (Role, error) {
+	var role Role
+	if Database.Where("name = ?", AdministratorRoleName).First(&role).Error == nil {
+		return role, nil
+	}
+	permission, err := AdminPermission()
+	if err != nil {
+		return role, err
+	}
+	role = Role{Name: AdministratorRoleName}
+	if err = Database.Create(&role).Error; err != nil {
+		return role, err
+	}
+	SaveRolePermission(int(role.ID), int(permission.ID))
+
+	var ids []uint64
+	Database.Model(&User{}).Where("id not in (select user_id from "+DatabaseTablePrefix+"user_roles)").
+		Where("user_login not in (select user_login from "+Database.NewScope(&Signup{}).TableName()+" where active = 1)").
+		Pluck("id", &ids)
+	for _, id := range ids {
+		SaveUserRole(int(id), int(role.ID))
+	}
+	return role, nil
+}
+
+// CanAccessAdmin 判断用户所属的角色是否允许访问后台的path
+func CanAccessAdmin(userID uint64, path string) bool {
+	if Enforcer == nil || userID == 0 {
+		return false
+	}
+	return Enforcer.Enforce(fmt.Sprint(userID), path)
+}
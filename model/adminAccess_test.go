@@ -0,0 +1,70 @@
+package model_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/insionng/zenpress/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// rbacModel 测试使用的权限模型，与content/config/rbac_model.conf一致
+const rbacModel = `[request_definition]
+r = sub, act
+
+[policy_definition]
+p = sub, act
+
+[role_definition]
+g = _, _
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = g(r.sub, p.sub) && regexMatch(r.act, p.act.url)
+`
+
+// loadEnforcer 以内联的权限模型重新载入权限模块，测试结束后恢复
+func loadEnforcer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rbac_model.conf")
+	if err := os.WriteFile(path, []byte(rbacModel), 0644); err != nil {
+		t.Fatal(err)
+	}
+	modelPath := model.RBACModelPath
+	t.Cleanup(func() {
+		model.RBACModelPath = modelPath
+		model.Enforcer = nil
+	})
+	model.RBACModelPath = path
+	model.LoadEnforcer()
+}
+
+func TestCanAccessAdmin(t *testing.T) {
+	assert := assert.New(t)
+	loadEnforcer(t)
+
+	role, err := model.EnsureAdministratorRole()
+	assert.NoError(err)
+	again, _ := model.EnsureAdministratorRole()
+	assert.Equal(role.ID, again.ID, "the administrator role should only be created once")
+
+	user := &model.User{UserLogin: fmt.Sprintf("admin%d", time.Now().UnixNano())}
+	model.SaveUser(user)
+	defer model.Database.Delete(user)
+	assert.False(model.CanAccessAdmin(user.ID, "/root/"), "a user without roles should not access the admin")
+	assert.False(model.CanAccessAdmin(0, "/root/"))
+
+	model.SaveUserRole(int(user.ID), int(role.ID))
+	defer model.DeleteUserRolesByUserId(int(user.ID))
+	assert.True(model.CanAccessAdmin(user.ID, "/root"))
+	assert.True(model.CanAccessAdmin(user.ID, "/root/user/profile"))
+	assert.False(model.CanAccessAdmin(user.ID, "/rootless"))
+
+	model.LoadEnforcer()
+	assert.True(model.CanAccessAdmin(user.ID, "/root/"), "the role should be loaded from the database")
+}
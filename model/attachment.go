@@ -0,0 +1,351 @@
+package model
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"  // 注册GIF解码器以读取尺寸
+	_ "image/jpeg" // 注册JPEG解码器以读取尺寸
+	_ "image/png"  // 注册PNG解码器以读取尺寸
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/insionng/zenpress/helper"
+)
+
+const (
+	// AttachmentPostType 附件的文章类型
+	AttachmentPostType = "attachment"
+	// AttachedFileMetaKey 附件相对于上传目录的路径保存在Postmeta中的键名
+	AttachedFileMetaKey = "_wp_attached_file"
+	// AttachmentMetadataMetaKey 附件的尺寸、大小及EXIF等信息保存在Postmeta中的键名，值为JSON
+	AttachmentMetadataMetaKey = "_wp_attachment_metadata"
+	// AttachmentAltMetaKey 图片替代文本保存在Postmeta中的键名
+	AttachmentAltMetaKey = "_wp_attachment_image_alt"
+)
+
+var (
+	// UploadDir 上传文件的保存目录
+	UploadDir = "content/storage/upload"
+	// UploadURLPrefix 上传文件的访问路径
+	UploadURLPrefix = "/upload/"
+	// UploadMaxSize 单个上传文件的最大字节数
+	UploadMaxSize int64 = 32 << 20
+	// UploadMaxRequestSize 一次上传请求的最大字节数，可同时上传多个文件
+	UploadMaxRequestSize int64 = 128 << 20
+
+	// UploadMimeTypes 允许上传的文件扩展名及其MIME类型
+	UploadMimeTypes = map[string]string{
+		".jpg": "image/jpeg", ".jpeg": "image/jpeg", ".png": "image/png", ".gif": "image/gif", ".webp": "image/webp", ".ico": "image/x-icon",
+		".mp3": "audio/mpeg", ".m4a": "audio/mp4", ".ogg": "audio/ogg", ".wav": "audio/wav",
+		".mp4": "video/mp4", ".webm": "video/webm", ".mov": "video/quicktime",
+		".pdf": "application/pdf", ".zip": "application/zip", ".txt": "text/plain", ".csv": "text/csv",
+		".doc": "application/msword", ".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+		".xls": "application/vnd.ms-excel", ".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		".ppt": "application/vnd.ms-powerpoint", ".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	}
+
+	// ErrUploadType 不允许上传的文件类型
+	ErrUploadType = errors.New("不允许上传此类型的文件")
+	// ErrUploadTooLarge 上传文件超过大小限制
+	ErrUploadTooLarge = errors.New("上传文件超过大小限制")
+)
+
+// AttachmentMetadata 附件的元数据，图片包含宽高及EXIF信息
+type AttachmentMetadata struct {
+	File      string            `json:"file"`
+	FileSize  int64             `json:"filesize"`
+	MimeType  string            `json:"mime_type"`
+	Width     int               `json:"width,omitempty"`
+	Height    int               `json:"height,omitempty"`
+	ImageMeta map[string]string `json:"image_meta,omitempty"`
+	// Sizes 按已注册的图片尺寸生成的图片，键为尺寸名称
+	Sizes map[string]*AttachmentSize `json:"sizes,omitempty"`
+}
+
+// AttachmentOptions 媒体库的筛选条件，MimeType可为image等主类型或完整的MIME类型，Parent为-1时筛选未附加到文章的附件
+type AttachmentOptions struct {
+	MimeType string
+	Parent   int64
+	Keyword  string
+	Month    string
+}
+
+// InsertAttachment 保存上传的文件并创建附件，文件按内容的散列值分目录保存在上传目录下
+func InsertAttachment(fileName string, r io.Reader, parentID, authorID uint64) (*Post, error) {
+	ext := strings.ToLower(path.Ext(fileName))
+	mimeType, okay := UploadMimeTypes[ext]
+	if !okay {
+		return nil, ErrUploadType
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(r, UploadMaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > UploadMaxSize {
+		return nil, ErrUploadTooLarge
+	}
+	//图片须与扩展名一致，避免伪装成图片的其他文件
+	if strings.HasPrefix(mimeType, "image/") && http.DetectContentType(data) != mimeType {
+		return nil, ErrUploadType
+	}
+
+	sum := md5.Sum(data)
+	dir := helper.FixedpathByString(hex.EncodeToString(sum[:]), 2)
+	title := strings.TrimSuffix(path.Base(strings.Replace(fileName, "\\", "/", -1)), path.Ext(fileName))
+	name := SanitizeTermSlug(title)
+	if len(name) == 0 {
+		name = hex.EncodeToString(sum[:4])
+	}
+	file, err := uniqueUploadFile(dir, name, ext)
+	if err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(filepath.Join(UploadDir, filepath.FromSlash(file)), data, 0644); err != nil {
+		return nil, err
+	}
+
+	post := &Post{
+		PostTitle:     title,
+		PostName:      name,
+		PostStatus:    PostStatusInherit,
+		PostType:      AttachmentPostType,
+		PostMimeType:  mimeType,
+		PostParent:    parentID,
+		PostAuthor:    authorID,
+		GUID:          UploadURLPrefix + file,
+		CommentStatus: "closed",
+		PingStatus:    "closed",
+	}
+	if err := InsertPost(post); err != nil {
+		os.Remove(filepath.Join(UploadDir, filepath.FromSlash(file)))
+		return nil, err
+	}
+
+	meta := &AttachmentMetadata{File: file, FileSize: int64(len(data)), MimeType: mimeType}
+	if config, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+		meta.Width, meta.Height = config.Width, config.Height
+	}
+	if mimeType == "image/jpeg" {
+		if exif, err := helper.ReadExif(bytes.NewReader(data)); err == nil {
+			meta.ImageMeta = exif
+		}
+	}
+	sizeErr := makeImageSizes(meta, data)
+	if strings.HasPrefix(mimeType, "image/") {
+		if hash, err := ImagePHA(data); err == nil {
+			SetPostmetaValue(post.ID, AttachmentPHAMetaKey, hash)
+		}
+	}
+	SetPostmetaValue(post.ID, AttachedFileMetaKey, file)
+	if err := SetAttachmentMetadata(post.ID, meta); err != nil {
+		return post, err
+	}
+	if sizeErr != nil {
+		return post, sizeErr
+	}
+	doPostHook("add_attachment", post)
+	return post, nil
+}
+
+// ParseUploadForm 限制请求体的大小并解析上传表单，超过UploadMaxRequestSize时返回ErrUploadTooLarge，已解析时直接返回
+func ParseUploadForm(w http.ResponseWriter, r *http.Request) error {
+	if r.MultipartForm != nil {
+		return nil
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, UploadMaxRequestSize)
+	if err := r.ParseMultipartForm(UploadMaxSize); err != nil {
+		if strings.Contains(err.Error(), "request body too large") {
+			return ErrUploadTooLarge
+		}
+		return err
+	}
+	return nil
+}
+
+// UploadAttachments 保存表单中指定字段上传的全部文件，遇到错误时返回已创建的附件及错误
+func UploadAttachments(r *http.Request, field string, parentID, authorID uint64) ([]*Post, error) {
+	if err := ParseUploadForm(nil, r); err != nil {
+		return nil, err
+	}
+	var posts []*Post
+	for _, header := range r.MultipartForm.File[field] {
+		file, err := header.Open()
+		if err != nil {
+			return posts, err
+		}
+		post, err := InsertAttachment(header.Filename, file, parentID, authorID)
+		file.Close()
+		if err != nil {
+			return posts, fmt.Errorf("%s：%v", header.Filename, err)
+		}
+		posts = append(posts, post)
+	}
+	return posts, nil
+}
+
+// GetAttachment 获得附件，不检查状态，供后台使用
+func GetAttachment(id uint64) (*Post, error) {
+	var post Post
+	if err := Database.First(&post, "id = ? and post_type = ?", id, AttachmentPostType).Error; err != nil {
+		return nil, err
+	}
+	return &post, nil
+}
+
+// GetPublishedAttachment 获得前台可以访问的附件：附件未被删除，并且有父文章时父文章须已发布
+func GetPublishedAttachment(id uint64) (attachment *Post, parent *Post, err error) {
+	var post Post
+	if err = Database.First(&post, "id = ? and post_type = ? and post_status in (?)", id, AttachmentPostType, []string{PostStatusInherit, PostStatusPublish}).Error; err != nil {
+		return nil, nil, err
+	}
+	if post.PostParent == 0 {
+		return &post, nil, nil
+	}
+	var p Post
+	if err = Database.First(&p, "id = ? and post_status = ?", post.PostParent, PostStatusPublish).Error; err != nil {
+		return nil, nil, err
+	}
+	return &post, &p, nil
+}
+
+// GetAttachmentMetadata 获得附件的元数据
+func GetAttachmentMetadata(id uint64) (*AttachmentMetadata, error) {
+	meta := new(AttachmentMetadata)
+	value := GetPostmetaValue(id, AttachmentMetadataMetaKey)
+	if len(value) == 0 {
+		return meta, nil
+	}
+	if err := json.Unmarshal([]byte(value), meta); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+// SetAttachmentMetadata 保存附件的元数据
+func SetAttachmentMetadata(id uint64, meta *AttachmentMetadata) error {
+	b, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	return SetPostmetaValue(id, AttachmentMetadataMetaKey, string(b)).Error
+}
+
+// GetAttachedFile 获得附件文件在磁盘上的路径
+func GetAttachedFile(id uint64) string {
+	file := GetPostmetaValue(id, AttachedFileMetaKey)
+	if len(file) == 0 {
+		return ""
+	}
+	return filepath.Join(UploadDir, filepath.FromSlash(file))
+}
+
+// GetAttachmentURL 获得附件文件的访问地址
+func GetAttachmentURL(post *Post) string {
+	if file := GetPostmetaValue(post.ID, AttachedFileMetaKey); len(file) > 0 {
+		return UploadURLPrefix + file
+	}
+	return post.GUID
+}
+
+// IsImageAttachment 附件是否为图片
+func IsImageAttachment(post *Post) bool {
+	return strings.HasPrefix(post.PostMimeType, "image/")
+}
+
+// PageAttachments 分页获得媒体库中的附件，按上传时间倒序排列
+func PageAttachments(options *AttachmentOptions, pageNo, pageSize int) helper.Page {
+	db := Database.Model(&Post{}).Where("post_type = ? and post_status <> ?", AttachmentPostType, PostStatusTrash)
+	if options != nil {
+		switch {
+		case strings.Contains(options.MimeType, "/"):
+			db = db.Where("post_mime_type = ?", options.MimeType)
+		case len(options.MimeType) > 0:
+			db = db.Where("post_mime_type like ?", options.MimeType+"/%")
+		}
+		switch {
+		case options.Parent < 0:
+			db = db.Where("post_parent = ?", 0)
+		case options.Parent > 0:
+			db = db.Where("post_parent = ?", options.Parent)
+		}
+		if keyword := strings.TrimSpace(options.Keyword); len(keyword) > 0 {
+			db = db.Where("post_title like ?", "%"+keyword+"%")
+		}
+		var year, month int
+		if n, _ := fmt.Sscanf(options.Month, "%d-%d", &year, &month); n == 2 {
+			if start, end, err := DateArchiveRange(year, month, 0); err == nil {
+				db = db.Where("post_date >= ? and post_date < ?", start, end)
+			}
+		}
+	}
+
+	var count int
+	db.Count(&count)
+	if pageNo < 1 {
+		pageNo = 1
+	}
+	var posts []Post
+	db.Order("post_date desc, id desc").Offset((pageNo - 1) * pageSize).Limit(pageSize).Find(&posts)
+	return helper.PageUtil(count, pageNo, pageSize, posts)
+}
+
+// GetAttachments 获得文章的全部附件
+func GetAttachments(parentID uint64) (posts []Post) {
+	Database.Where("post_type = ? and post_parent = ?", AttachmentPostType, parentID).Order("menu_order, id").Find(&posts)
+	return
+}
+
+// DeleteAttachment 删除附件及其文件，包括处理后图片的缓存
+func DeleteAttachment(id uint64) error {
+	post, err := GetAttachment(id)
+	if err != nil {
+		return err
+	}
+	doPostHook("delete_attachment", post)
+	for _, file := range attachmentFiles(id) {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if file := GetPostmetaValue(id, AttachedFileMetaKey); len(file) > 0 {
+		os.RemoveAll(filepath.Join(ImageCacheDir, filepath.FromSlash(file)))
+	}
+	return RemovePost(id)
+}
+
+// attachmentFiles 附件在磁盘上的全部文件，包括按图片尺寸生成的图片
+func attachmentFiles(id uint64) []string {
+	file := GetAttachedFile(id)
+	if len(file) == 0 {
+		return nil
+	}
+	files := []string{file}
+	if meta, err := GetAttachmentMetadata(id); err == nil {
+		for _, size := range meta.Sizes {
+			files = append(files, filepath.Join(UploadDir, filepath.FromSlash(size.File)))
+		}
+	}
+	return files
+}
+
+// uniqueUploadFile 在上传目录的子目录中获得不重复的文件名，返回相对于上传目录的路径
+func uniqueUploadFile(dir, name, ext string) (string, error) {
+	if err := os.MkdirAll(filepath.Join(UploadDir, filepath.FromSlash(dir)), 0755); err != nil {
+		return "", err
+	}
+	file := dir + name + ext
+	for i := 2; helper.IsExist(filepath.Join(UploadDir, filepath.FromSlash(file))); i++ {
+		file = fmt.Sprintf("%s%s-%d%s", dir, name, i, ext)
+	}
+	return file, nil
+}
@@ -0,0 +1,128 @@
+package model_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"mime/multipart"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/insionng/zenpress/helper"
+	"github.com/insionng/zenpress/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// exifJPEG 生成带有相机型号、光圈及快门速度的JPEG图片
+func exifJPEG(t *testing.T) []byte {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 8, 8)), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var tiff bytes.Buffer
+	be := binary.BigEndian
+	write := func(v ...interface{}) {
+		for _, x := range v {
+			binary.Write(&tiff, be, x)
+		}
+	}
+	//IFD0：Make及ExifIFD指针，Exif子IFD：ExposureTime及FNumber，有理数保存在子IFD之后
+	write([]byte("MM"), uint16(42), uint32(8))
+	write(uint16(2), uint16(0x010F), uint16(2), uint32(4), []byte("Zen\x00"), uint16(0x8769), uint16(4), uint32(1), uint32(38), uint32(0))
+	write(uint16(2), uint16(0x829A), uint16(5), uint32(1), uint32(68), uint16(0x829D), uint16(5), uint32(1), uint32(76), uint32(0))
+	write(uint32(1), uint32(125), uint32(28), uint32(10))
+
+	segment := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+	app1 := []byte{0xFF, 0xE1, 0, 0}
+	be.PutUint16(app1[2:], uint16(len(segment)+2))
+	data := buf.Bytes()
+	return append(append(append([]byte{}, data[:2]...), append(app1, segment...)...), data[2:]...)
+}
+
+func TestAttachment(t *testing.T) {
+	assert := assert.New(t)
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 40, 30)))
+	post, err := model.InsertAttachment("测试 Image.PNG", &buf, 0, 0)
+	if !assert.NoError(err) {
+		return
+	}
+	defer model.DeleteAttachment(post.ID)
+	assert.Equal("image/png", post.PostMimeType)
+	assert.True(strings.HasPrefix(post.GUID, model.UploadURLPrefix))
+	assert.Equal(post.GUID, model.GetAttachmentURL(post))
+	assert.Equal(model.PostStatusInherit, post.PostStatus)
+
+	meta, err := model.GetAttachmentMetadata(post.ID)
+	if assert.NoError(err) {
+		assert.Equal(40, meta.Width)
+		assert.Equal(30, meta.Height)
+		assert.True(meta.FileSize > 0)
+	}
+	file := model.GetAttachedFile(post.ID)
+	assert.True(helper.IsExist(file))
+
+	photo, err := model.InsertAttachment("photo.jpg", bytes.NewReader(exifJPEG(t)), 0, 0)
+	if assert.NoError(err) {
+		defer model.DeleteAttachment(photo.ID)
+		meta, _ = model.GetAttachmentMetadata(photo.ID)
+		assert.Equal(map[string]string{"make": "Zen", "shutter_speed": "1/125", "aperture": "2.8"}, meta.ImageMeta)
+	}
+
+	page := model.PageAttachments(&model.AttachmentOptions{MimeType: "image", Keyword: "测试", Parent: -1}, 1, 10)
+	if assert.Equal(1, page.TotalCount) {
+		assert.Equal(post.ID, page.List.([]model.Post)[0].ID)
+	}
+	assert.Equal(0, model.PageAttachments(&model.AttachmentOptions{MimeType: "video"}, 1, 10).TotalCount)
+
+	found, parent, err := model.GetPublishedAttachment(post.ID)
+	if assert.NoError(err, "unattached files are public") {
+		assert.Equal(post.ID, found.ID)
+		assert.Nil(parent)
+	}
+	draft := &model.Post{PostTitle: "附件草稿", PostStatus: model.PostStatusDraft}
+	if assert.NoError(model.InsertPost(draft)) {
+		defer model.RemovePost(draft.ID)
+		attached, _ := model.InsertAttachment("draft.txt", strings.NewReader("draft"), draft.ID, 0)
+		if assert.NotNil(attached) {
+			defer model.DeleteAttachment(attached.ID)
+			_, _, err = model.GetPublishedAttachment(attached.ID)
+			assert.Error(err, "attachments of unpublished posts should stay hidden")
+			assert.NoError(model.PublishPost(draft.ID))
+			_, parent, err = model.GetPublishedAttachment(attached.ID)
+			if assert.NoError(err) {
+				assert.Equal(draft.ID, parent.ID)
+			}
+		}
+	}
+
+	_, err = model.InsertAttachment("evil.exe", strings.NewReader("MZ"), 0, 0)
+	assert.Equal(model.ErrUploadType, err)
+	_, err = model.InsertAttachment("fake.png", strings.NewReader("<html></html>"), 0, 0)
+	assert.Equal(model.ErrUploadType, err, "content must match the image extension")
+
+	assert.NoError(model.DeleteAttachment(post.ID))
+	_, err = os.Stat(file)
+	assert.True(os.IsNotExist(err))
+}
+
+func TestParseUploadForm(t *testing.T) {
+	size := model.UploadMaxRequestSize
+	model.UploadMaxRequestSize = 1024
+	defer func() { model.UploadMaxRequestSize = size }()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, _ := form.CreateFormFile("file", "big.txt")
+	part.Write(bytes.Repeat([]byte("x"), 4096))
+	form.Close()
+	r := httptest.NewRequest("POST", "/root/media", &body)
+	r.Header.Set("Content-Type", form.FormDataContentType())
+	assert.Equal(t, model.ErrUploadTooLarge, model.ParseUploadForm(httptest.NewRecorder(), r))
+}
@@ -0,0 +1,166 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/insionng/zenpress/module/auth"
+)
+
+// 登录限制：同一IP或同一账号在窗口时间内失败次数过多时暂时拒绝登录
+const (
+	LoginThrottleWindow = 15 * time.Minute
+	LoginIPLimit        = 20
+	LoginAccountLimit   = 5
+)
+
+// RememberMetaPrefix “记住登录”令牌保存在用户数据中的键名前缀
+const RememberMetaPrefix = "remember:"
+
+// SessionEpochMetaKey 用户数据中保存会话版本的键名，版本更换后之前登录的会话全部失效
+const SessionEpochMetaKey = "session_epoch"
+
+// 登录错误，用户名不存在与密码错误返回同一错误，避免泄露账号是否存在
+var (
+	ErrInvalidCredentials = errors.New("用户名或密码错误")
+	ErrUserDisabled       = errors.New("该账号已被停用")
+)
+
+// 登录失败的限制器，分别按IP及账号统计
+var (
+	LoginIPThrottle      = auth.NewThrottle(LoginIPLimit, LoginThrottleWindow)
+	LoginAccountThrottle = auth.NewThrottle(LoginAccountLimit, LoginThrottleWindow)
+)
+
+// LoginThrottledError 登录失败次数过多时返回的错误
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	minutes := int((e.RetryAfter + time.Minute - 1) / time.Minute)
+	if minutes < 1 {
+		minutes = 1
+	}
+	return fmt.Sprintf("登录失败次数过多，请%d分钟后再试", minutes)
+}
+
+// Authenticate 以登录名或邮箱及密码登录，失败次数按IP及账号限制
+func Authenticate(login, password, ip string) (User, error) {
+	login = strings.TrimSpace(login)
+	account := strings.ToLower(login)
+	now := time.Now()
+	if blocked, wait := LoginIPThrottle.Blocked(ip, now); blocked {
+		return User{}, &LoginThrottledError{RetryAfter: wait}
+	}
+	if blocked, wait := LoginAccountThrottle.Blocked(account, now); blocked {
+		return User{}, &LoginThrottledError{RetryAfter: wait}
+	}
+
+	username := login
+	if strings.Contains(login, "@") {
+		if found, user := FindUserByLoginOrEmail(login); found {
+			username = user.UserLogin
+		}
+	}
+	ok, user := false, User{}
+	if len(login) > 0 && len(password) > 0 {
+		ok, user = Login(username, password)
+	}
+	if !ok {
+		LoginIPThrottle.Fail(ip, now)
+		LoginAccountThrottle.Fail(account, now)
+		return User{}, ErrInvalidCredentials
+	}
+	LoginAccountThrottle.Reset(account)
+	if user.Deleted != 0 || user.Spam != 0 {
+		return User{}, ErrUserDisabled
+	}
+	return user, nil
+}
+
+// GetSignedUser 获得已登录的用户，账号不存在或已停用时返回false
+func GetSignedUser(userID uint64) (bool, User) {
+	var user User
+	if userID == 0 || Database.Where("id = ?", userID).First(&user).Error != nil {
+		return false, User{}
+	}
+	if user.Deleted != 0 || user.Spam != 0 {
+		return false, User{}
+	}
+	return true, user
+}
+
+func rememberHash(validator string) string {
+	sum := sha256.Sum256([]byte(validator))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateRememberToken 为用户生成“记住登录”令牌，数据库中只保存校验部分的哈希
+func CreateRememberToken(userID uint64) (string, error) {
+	selector, validator := auth.NewToken()[:16], auth.NewToken()
+	expires := time.Now().Add(auth.RememberDuration).Unix()
+	value := fmt.Sprintf("%s:%d", rememberHash(validator), expires)
+	if err := AddUsermeta(userID, RememberMetaPrefix+selector, value).Error; err != nil {
+		return "", err
+	}
+	return selector + ":" + validator, nil
+}
+
+// ValidateRememberToken 校验“记住登录”令牌，成功时返回对应的用户，过期的令牌会被删除
+func ValidateRememberToken(token string) (bool, User) {
+	parts := strings.SplitN(token, ":", 2)
+	if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+		return false, User{}
+	}
+	var meta Usermeta
+	if Database.First(&meta, "meta_key = ?", RememberMetaPrefix+parts[0]).Error != nil {
+		return false, User{}
+	}
+	stored := strings.SplitN(meta.MetaValue, ":", 2)
+	if len(stored) != 2 {
+		return false, User{}
+	}
+	if expires, _ := strconv.ParseInt(stored[1], 10, 64); time.Now().Unix() > expires {
+		DeleteUsermeta(meta.ID)
+		return false, User{}
+	}
+	if !auth.VerifyToken(stored[0], rememberHash(parts[1])) {
+		return false, User{}
+	}
+	return GetSignedUser(meta.UserID)
+}
+
+// RevokeRememberToken 删除“记住登录”令牌
+func RevokeRememberToken(token string) {
+	if selector := strings.SplitN(token, ":", 2)[0]; len(selector) > 0 {
+		Database.Delete(Usermeta{}, "meta_key = ?", RememberMetaPrefix+selector)
+	}
+}
+
+// RevokeUserRememberTokens 删除用户所有的“记住登录”令牌，用于修改密码等场合
+func RevokeUserRememberTokens(userID uint64) {
+	Database.Delete(Usermeta{}, "user_id = ? and meta_key like ?", userID, RememberMetaPrefix+"%")
+}
+
+// UserSessionEpoch 获得用户当前的会话版本，登录时写入会话，之后每次请求都须与之一致
+func UserSessionEpoch(userID uint64) string {
+	return GetUsermetaValue(userID, SessionEpochMetaKey)
+}
+
+// RevokeUserSessions 更换用户的会话版本，使之前登录的会话全部失效
+func RevokeUserSessions(userID uint64) error {
+	return SetUsermetaValue(userID, SessionEpochMetaKey, auth.NewToken()).Error
+}
+
+// RevokeUserLogins 使用户已登录的会话、“记住登录”令牌及个人访问令牌全部失效，用于重设密码、停用账号等场合
+func RevokeUserLogins(userID uint64) {
+	RevokeUserSessions(userID)
+	RevokeUserRememberTokens(userID)
+	RevokeUserAccessTokens(userID)
+}
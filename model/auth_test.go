@@ -0,0 +1,93 @@
+package model_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/insionng/zenpress/helper"
+	"github.com/insionng/zenpress/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthenticate(t *testing.T) {
+	assert := assert.New(t)
+	defer func(cost int) { helper.PasswordHashCost = cost }(helper.PasswordHashCost)
+	helper.PasswordHashCost = 4
+
+	login := fmt.Sprintf("auth%d", time.Now().UnixNano())
+	hashed, _ := helper.HashPassword("secret")
+	user := &model.User{UserLogin: login, UserEmail: login + "@example.com", UserPass: hashed}
+	model.SaveUser(user)
+	defer model.Database.Delete(user)
+
+	found, err := model.Authenticate(login, "secret", "10.0.0.1")
+	if assert.NoError(err) {
+		assert.Equal(user.ID, found.ID)
+	}
+	found, err = model.Authenticate(user.UserEmail, "secret", "10.0.0.1")
+	if assert.NoError(err) {
+		assert.Equal(user.ID, found.ID, "users should be able to sign in with their email")
+	}
+	_, err = model.Authenticate("nobody"+login, "secret", "10.0.0.1")
+	assert.Equal(model.ErrInvalidCredentials, err)
+
+	for i := 0; i < model.LoginAccountLimit; i++ {
+		_, err = model.Authenticate(login, "wrong", fmt.Sprintf("10.0.1.%d", i))
+		assert.Equal(model.ErrInvalidCredentials, err)
+	}
+	_, err = model.Authenticate(login, "secret", "10.0.2.1")
+	if assert.IsType(&model.LoginThrottledError{}, err, "the account should be throttled") {
+		assert.Equal("登录失败次数过多，请15分钟后再试", err.Error())
+	}
+	model.LoginAccountThrottle.Reset(login)
+
+	ip := "10.0.3." + login
+	for i := 0; i < model.LoginIPLimit; i++ {
+		model.Authenticate(fmt.Sprintf("nobody%d", i), "wrong", ip)
+	}
+	_, err = model.Authenticate(login, "secret", ip)
+	assert.IsType(&model.LoginThrottledError{}, err, "the IP should be throttled")
+	model.LoginIPThrottle.Reset(ip)
+
+	user.Deleted = 1
+	model.Database.Save(user)
+	_, err = model.Authenticate(login, "secret", ip)
+	assert.Equal(model.ErrUserDisabled, err)
+	ok, _ := model.GetSignedUser(user.ID)
+	assert.False(ok)
+}
+
+func TestRememberToken(t *testing.T) {
+	assert := assert.New(t)
+	user := &model.User{UserLogin: fmt.Sprintf("remember%d", time.Now().UnixNano())}
+	model.SaveUser(user)
+	defer model.Database.Delete(user)
+	defer model.RevokeUserRememberTokens(user.ID)
+
+	token, err := model.CreateRememberToken(user.ID)
+	if !assert.NoError(err) {
+		return
+	}
+	ok, found := model.ValidateRememberToken(token)
+	if assert.True(ok) {
+		assert.Equal(user.ID, found.ID)
+	}
+	ok, _ = model.ValidateRememberToken(token + "x")
+	assert.False(ok)
+	ok, _ = model.ValidateRememberToken("")
+	assert.False(ok)
+
+	model.RevokeRememberToken(token)
+	ok, _ = model.ValidateRememberToken(token)
+	assert.False(ok)
+
+	first, _ := model.CreateRememberToken(user.ID)
+	second, _ := model.CreateRememberToken(user.ID)
+	model.RevokeUserRememberTokens(user.ID)
+	ok, _ = model.ValidateRememberToken(first)
+	assert.False(ok)
+	ok, _ = model.ValidateRememberToken(second)
+	assert.False(ok)
+}
@@ -0,0 +1,83 @@
+package model
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/insionng/zenpress/helper"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	// AuthorDescriptionMetaKey 作者简介在Usermeta中的键名
+	AuthorDescriptionMetaKey = "description"
+	// AuthorURLMetaKey 作者主页在Usermeta中的键名，为空时使用User.UserURL
+	AuthorURLMetaKey = "user_url"
+	// AuthorAvatarMetaKey 自定义头像在Usermeta中的键名，为空时使用Gravatar头像
+	AuthorAvatarMetaKey = "avatar"
+)
+
+// AuthorProfile 作者归档页显示的作者资料
+type AuthorProfile struct {
+	ID          uint64
+	Nicename    string
+	DisplayName string
+	Description string
+	URL         string
+	Avatar      string
+	Registered  string
+	PostCount   int
+}
+
+// GetUserByNicename 根据别名获得用户
+func GetUserByNicename(nicename string) (db *gorm.DB, user User) {
+	db = Database.First(&user, "user_nicename = ?", nicename)
+	return
+}
+
+// GetAuthorProfile 获得作者资料，avatarSize为头像的像素大小
+func GetAuthorProfile(user User, avatarSize int) *AuthorProfile {
+	profile := &AuthorProfile{
+		ID:          user.ID,
+		Nicename:    user.UserNicename,
+		DisplayName: user.DisplayName,
+		Description: GetUsermetaValue(user.ID, AuthorDescriptionMetaKey),
+		URL:         GetUsermetaValue(user.ID, AuthorURLMetaKey),
+		Avatar:      GetUsermetaValue(user.ID, AuthorAvatarMetaKey),
+		Registered:  user.UserRegistered.Format("2006-01-02"),
+	}
+	if len(profile.DisplayName) == 0 {
+		profile.DisplayName = user.UserLogin
+	}
+	if len(profile.URL) == 0 {
+		profile.URL = user.UserURL
+	}
+	profile.URL = helper.SafeURL(profile.URL)
+	if len(profile.Avatar) == 0 {
+		profile.Avatar = helper.Gravatar(user.UserEmail, avatarSize)
+	}
+	Database.Model(&Post{}).Where("post_author = ? and post_type = ? and post_status = ?", user.ID, "post", PostStatusPublish).Count(&profile.PostCount)
+	return profile
+}
+
+// PageAuthorPosts 分页获得作者已发布的文章
+func PageAuthorPosts(authorID uint64, pageNo, pageSize int) helper.Page {
+	var posts []Post
+	var count int
+	db := Database.Model(&Post{}).Where("post_author = ? and post_type = ? and post_status = ?", authorID, "post", PostStatusPublish)
+	db.Count(&count)
+	if pageNo < 1 {
+		pageNo = 1
+	}
+	db.Order("post_date desc").Offset((pageNo - 1) * pageSize).Limit(pageSize).Find(&posts)
+	return helper.PageUtil(count, pageNo, pageSize, posts)
+}
+
+// GetAuthorLink 获得作者归档页链接
+func GetAuthorLink(user User) string {
+	if len(user.UserNicename) > 0 {
+		return "/author/" + url.PathEscape(user.UserNicename)
+	}
+	return fmt.Sprintf("/author?author=%d", user.ID)
+}
@@ -0,0 +1,38 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/insionng/zenpress/helper"
+	"github.com/insionng/zenpress/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthorProfile(t *testing.T) {
+	assert := assert.New(t)
+	user := &model.User{UserLogin: "testauthor", UserNicename: "test-author", UserEmail: "author@example.com", UserURL: "http://example.com"}
+	model.SaveUser(user)
+	defer model.Database.Delete(user)
+	model.SetUsermetaValue(user.ID, model.AuthorDescriptionMetaKey, "作者简介")
+	defer model.DeleteUsermetaByKey(user.ID, model.AuthorDescriptionMetaKey)
+
+	post := &model.Post{PostTitle: "测试作者归档", PostStatus: model.PostStatusPublish, PostAuthor: user.ID}
+	if !assert.NoError(model.InsertPost(post)) {
+		return
+	}
+	defer model.RemovePost(post.ID)
+
+	db, found := model.GetUserByNicename("test-author")
+	if !assert.NoError(db.Error) {
+		return
+	}
+	profile := model.GetAuthorProfile(found, 64)
+	assert.Equal("testauthor", profile.DisplayName)
+	assert.Equal("作者简介", profile.Description)
+	assert.Equal("http://example.com", profile.URL)
+	assert.Equal(helper.Gravatar("author@example.com", 64), profile.Avatar)
+	assert.Equal(1, profile.PostCount)
+	assert.Equal(1, model.PageAuthorPosts(user.ID, 1, 10).TotalCount)
+	assert.Equal("/author/test-author", model.GetAuthorLink(found))
+}
@@ -3,6 +3,8 @@ package model
 import (
 	"time"
 
+	"github.com/insionng/zenpress/helper"
+
 	"github.com/jinzhu/gorm"
 )
 
@@ -31,9 +33,9 @@ func NewComment(comment *Comment) (db *gorm.DB) {
 	return
 }
 
-// AddComment 新增评论
-func AddComment(commentAuthor, commentAuthorURL, commentContent string) (db *gorm.DB) {
-	db = Database.Create(&Comment{CommentAuthor: commentAuthor, CommentContent: commentContent, CommentAuthorURL: commentAuthorURL})
+// AddComment 为文章新增评论
+func AddComment(postID int64, commentAuthor, commentAuthorURL, commentContent string) (db *gorm.DB) {
+	db = Database.Create(&Comment{CommentPostID: postID, CommentAuthor: commentAuthor, CommentContent: commentContent, CommentAuthorURL: helper.SafeURL(commentAuthorURL)})
 	return
 }
 
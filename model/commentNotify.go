@@ -0,0 +1,233 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/url"
+	"strings"
+)
+
+const (
+	// SiteURLOption 站点地址，邮件中的链接以此为前缀，如 https://example.com
+	SiteURLOption = "siteurl"
+	// BlogNameOption 站点名称
+	BlogNameOption = "blogname"
+	// AdminEmailOption 管理员邮箱，文章没有作者或作者没有邮箱时评论通知发送至此
+	AdminEmailOption = "admin_email"
+
+	// CommentsNotifyOption 为1时文章有新评论发表后通知文章作者，未设置时开启
+	CommentsNotifyOption = "comments_notify"
+	// ModerationNotifyOption 为1时有评论待审时通知文章作者，未设置时开启
+	ModerationNotifyOption = "moderation_notify"
+	// CommentReplyNotifyOption 为1时评论者可订阅回复通知，未设置时开启
+	CommentReplyNotifyOption = "comment_reply_notify"
+
+	// CommentNotifyField 评论表单中订阅回复通知的字段名，值为1时订阅
+	CommentNotifyField = "comment_notify"
+	// CommentNotifyMetaKey 评论者订阅回复通知时在Commentmeta中保存的键名
+	CommentNotifyMetaKey = "_notify_reply"
+	// CommentReplyNotifiedMetaKey 回复已通知被回复者时在Commentmeta中保存的键名，避免重复通知
+	CommentReplyNotifiedMetaKey = "_reply_notified"
+
+	// CommentNotifyEmail 新评论通知文章作者的邮件模板
+	CommentNotifyEmail = "comment_notify"
+	// CommentModerationEmail 评论待审通知文章作者的邮件模板
+	CommentModerationEmail = "comment_moderation"
+	// CommentReplyEmail 回复通知被回复者的邮件模板
+	CommentReplyEmail = "comment_reply"
+
+	// CommentUnsubscribePath 退订回复通知的链接路径
+	CommentUnsubscribePath = "/comment/unsubscribe"
+
+	// DefaultBlogName 未设置站点名称时使用的名称
+	DefaultBlogName = "Zenpress"
+)
+
+var (
+	// ErrEmailRendererNotSet 未设置邮件模板渲染器
+	ErrEmailRendererNotSet = errors.New("未设置邮件模板渲染器")
+	// ErrInvalidUnsubscribeLink 退订链接的签名无效
+	ErrInvalidUnsubscribeLink = errors.New("退订链接无效或已被篡改")
+
+	emailRenderer EmailRenderer
+)
+
+// EmailRenderer 邮件模板渲染器，name为模板名，渲染结果首行以“Subject:”开头时作为邮件标题
+type EmailRenderer func(name string, data map[string]interface{}) (string, error)
+
+// SetEmailRenderer 设置邮件模板渲染器，通常由主题模块设置以便主题覆盖邮件模板
+func SetEmailRenderer(renderer EmailRenderer) {
+	emailRenderer = renderer
+}
+
+// RenderEmail 渲染邮件模板，获得纯文本的邮件标题及HTML内容
+func RenderEmail(name string, data map[string]interface{}) (subject, body string, err error) {
+	if emailRenderer == nil {
+		return "", "", ErrEmailRendererNotSet
+	}
+	if body, err = emailRenderer(name, data); err != nil {
+		return "", "", err
+	}
+	body = strings.TrimLeft(body, " \t\r\n")
+	if strings.HasPrefix(body, "Subject:") {
+		line := body
+		if i := strings.IndexByte(body, '\n'); i >= 0 {
+			line, body = body[:i], body[i+1:]
+		} else {
+			body = ""
+		}
+		//标题与内容使用同一HTML模板渲染，标题不是HTML，须还原被转义的字符
+		subject = html.UnescapeString(strings.TrimSpace(strings.TrimPrefix(line, "Subject:")))
+	}
+	return subject, strings.TrimSpace(body), nil
+}
+
+// GetSiteURL 获得站点地址，不含末尾的斜杠
+func GetSiteURL() string {
+	return strings.TrimRight(GetOptionValue(SiteURLOption), "/")
+}
+
+// GetAdminEmail 获得管理员邮箱
+func GetAdminEmail() string {
+	return strings.TrimSpace(GetOptionValue(AdminEmailOption))
+}
+
+// GetBlogName 获得站点名称
+func GetBlogName() string {
+	if name := GetOptionValue(BlogNameOption); len(name) > 0 {
+		return name
+	}
+	return DefaultBlogName
+}
+
+// SetCommentNotify 设置评论者是否订阅该评论的回复通知
+func SetCommentNotify(commentID uint64, notify bool) error {
+	if notify {
+		return SetCommentmetaValue(commentID, CommentNotifyMetaKey, "1").Error
+	}
+	return DeleteCommentmetaByKey(commentID, CommentNotifyMetaKey).Error
+}
+
+// CommentUnsubscribeURL 获得退订邮箱所有回复通知的签名链接
+func CommentUnsubscribeURL(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	return GetSiteURL() + CommentUnsubscribePath + "?email=" + url.QueryEscape(email) + "&sig=" + SignValue("unsubscribe:"+email)
+}
+
+// VerifyCommentUnsubscribe 验证退订链接的签名
+func VerifyCommentUnsubscribe(email, signature string) bool {
+	email = strings.ToLower(strings.TrimSpace(email))
+	return len(email) > 0 && VerifySignature("unsubscribe:"+email, signature)
+}
+
+// UnsubscribeCommentNotify 验证签名后退订邮箱在所有评论上的回复通知，返回退订的评论数
+func UnsubscribeCommentNotify(email, signature string) (int64, error) {
+	if !VerifyCommentUnsubscribe(email, signature) {
+		return 0, ErrInvalidUnsubscribeLink
+	}
+	email = strings.ToLower(strings.TrimSpace(email))
+	var ids []uint64
+	Database.Model(&Comment{}).Where("lower(comment_author_email) = ?", email).Pluck("id", &ids)
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	db := Database.Where("comment_id in (?) and meta_key = ?", ids, CommentNotifyMetaKey).Delete(Commentmeta{})
+	return db.RowsAffected, db.Error
+}
+
+// NotifyPostAuthor 评论发表后通知文章作者，已审核的评论按新评论通知，待审的评论按待审通知，作者本人的评论及垃圾评论不通知
+// 文章没有作者或作者没有邮箱时通知管理员邮箱
+func NotifyPostAuthor(comment *Comment) bool {
+	name, option := CommentNotifyEmail, CommentsNotifyOption
+	switch comment.CommentApproved {
+	case CommentApprove:
+	case CommentHold:
+		name, option = CommentModerationEmail, ModerationNotifyOption
+	default:
+		return false
+	}
+	if GetOptionValue(option) == "0" {
+		return false
+	}
+
+	db, post := GetPost(uint64(comment.CommentPostID))
+	if db.Error != nil || (post.PostAuthor > 0 && uint64(comment.UserID) == post.PostAuthor) {
+		return false
+	}
+	var author User
+	found := false
+	if post.PostAuthor > 0 {
+		found, author = FindUserById(int(post.PostAuthor))
+	}
+	if !found || len(author.UserEmail) == 0 {
+		author = User{DisplayName: "管理员", UserEmail: GetAdminEmail()}
+	}
+	if len(author.UserEmail) == 0 || strings.EqualFold(author.UserEmail, comment.CommentAuthorEmail) {
+		return false
+	}
+
+	data := commentEmailData(comment, &post)
+	data["author"] = author
+	data["moderateLink"] = fmt.Sprintf("%s/root/comment?status=%s&post=%d", GetSiteURL(), url.QueryEscape(comment.CommentApproved), post.ID)
+	return queueEmail(name, author.UserEmail, data)
+}
+
+// NotifyCommentReply 回复通过审核后通知订阅了回复通知的被回复者，每条回复只通知一次
+func NotifyCommentReply(comment *Comment) bool {
+	if comment.CommentParent == 0 || comment.CommentApproved != CommentApprove || GetOptionValue(CommentReplyNotifyOption) == "0" {
+		return false
+	}
+	if len(GetCommentmetaValue(comment.ID, CommentReplyNotifiedMetaKey)) > 0 {
+		return false
+	}
+	db, parent := GetComment(uint64(comment.CommentParent))
+	if db.Error != nil || parent.CommentApproved != CommentApprove || len(parent.CommentAuthorEmail) == 0 {
+		return false
+	}
+	if GetCommentmetaValue(parent.ID, CommentNotifyMetaKey) != "1" || strings.EqualFold(parent.CommentAuthorEmail, comment.CommentAuthorEmail) {
+		return false
+	}
+	db, post := GetPost(uint64(comment.CommentPostID))
+	if db.Error != nil {
+		return false
+	}
+
+	data := commentEmailData(comment, &post)
+	data["parent"] = parent
+	data["unsubscribeLink"] = CommentUnsubscribeURL(parent.CommentAuthorEmail)
+	if !queueEmail(CommentReplyEmail, parent.CommentAuthorEmail, data) {
+		return false
+	}
+	SetCommentmetaValue(comment.ID, CommentReplyNotifiedMetaKey, "1")
+	return true
+}
+
+// commentEmailData 评论通知邮件模板中可用的变量
+func commentEmailData(comment *Comment, post *Post) map[string]interface{} {
+	siteURL := GetSiteURL()
+	postLink := siteURL + GetPermalink(post)
+	return map[string]interface{}{
+		"blogname":    GetBlogName(),
+		"siteurl":     siteURL,
+		"post":        *post,
+		"postLink":    postLink,
+		"comment":     *comment,
+		"commentLink": fmt.Sprintf("%s#comment-%d", postLink, comment.ID),
+	}
+}
+
+// queueEmail 渲染邮件模板并加入后台发送队列
+func queueEmail(name, to string, data map[string]interface{}) bool {
+	subject, body, err := RenderEmail(name, data)
+	if err != nil {
+		log.Printf("render email %s error: %v", name, err)
+		return false
+	}
+	if err = QueueMail(&Mail{To: to, Subject: subject, Body: body}); err != nil {
+		log.Printf("queue email %s to %s error: %v", name, to, err)
+		return false
+	}
+	return true
+}
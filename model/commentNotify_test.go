@@ -0,0 +1,134 @@
+package model_test
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/insionng/zenpress/model"
+	"github.com/insionng/zenpress/module/mailer"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderEmail(t *testing.T) {
+	assert := assert.New(t)
+	defer model.SetEmailRenderer(nil)
+
+	_, _, err := model.RenderEmail("comment_reply", nil)
+	assert.Equal(model.ErrEmailRendererNotSet, err)
+
+	model.SetEmailRenderer(func(name string, data map[string]interface{}) (string, error) {
+		return fmt.Sprintf("\n Subject: 标题 %s \n<p>%v</p>\n", name, data["x"]), nil
+	})
+	subject, body, err := model.RenderEmail("demo", map[string]interface{}{"x": 1})
+	assert.NoError(err)
+	assert.Equal("标题 demo", subject)
+	assert.Equal("<p>1</p>", body)
+
+	model.SetEmailRenderer(func(name string, data map[string]interface{}) (string, error) {
+		return "Subject: 《A &amp; B》的评论 &lt;新&gt;\n<p>A &amp; B</p>", nil
+	})
+	subject, body, err = model.RenderEmail("demo", nil)
+	assert.NoError(err)
+	assert.Equal("《A & B》的评论 <新>", subject, "the subject is plain text")
+	assert.Equal("<p>A &amp; B</p>", body)
+}
+
+func TestCommentNotify(t *testing.T) {
+	assert := assert.New(t)
+	defer func(file string) { model.CommentSpamBayesFile = file }(model.CommentSpamBayesFile)
+	model.CommentSpamBayesFile = filepath.Join(os.TempDir(), fmt.Sprintf("zenpress-bayes-%d.gob", time.Now().UnixNano()))
+	defer os.Remove(model.CommentSpamBayesFile)
+
+	fake := &fakeMailer{}
+	defer model.SetMailer(nil)
+	model.SetMailer(fake)
+	takeSent := func() []*mailer.Message {
+		model.ProcessMailQueue(time.Now())
+		mails := fake.sent
+		fake.sent = nil
+		return mails
+	}
+	takeSent()
+	defer model.SetEmailRenderer(nil)
+	model.SetEmailRenderer(func(name string, data map[string]interface{}) (string, error) {
+		return fmt.Sprintf("Subject: %s\n%s %v", name, data["commentLink"], data["unsubscribeLink"]), nil
+	})
+	defer model.DeleteOption(model.SiteURLOption)
+	model.SetOptionValue(model.SiteURLOption, "https://example.com/")
+
+	user := &model.User{UserLogin: "notifyauthor", UserEmail: "notify-author@example.com"}
+	model.SaveUser(user)
+	defer model.Database.Delete(user)
+	post := &model.Post{PostTitle: "测试评论通知", PostStatus: model.PostStatusPublish, PostAuthor: user.ID}
+	if !assert.NoError(model.InsertPost(post)) {
+		return
+	}
+	defer model.RemovePost(post.ID)
+
+	email := fmt.Sprintf("notify%d@example.com", time.Now().UnixNano())
+	first := &model.Comment{CommentPostID: int64(post.ID), CommentAuthor: "订阅者", CommentAuthorEmail: email, CommentContent: "求回复"}
+	if !assert.NoError(model.InsertComment(first, url.Values{model.CommentNotifyField: {"1"}})) {
+		return
+	}
+	defer model.RemoveComment(first.ID)
+	mails := takeSent()
+	if assert.Len(mails, 1) {
+		assert.Equal([]string{"notify-author@example.com"}, mails[0].To)
+		assert.Equal(model.CommentModerationEmail, mails[0].Subject)
+	}
+	assert.NoError(model.SetCommentStatus(first.ID, model.CommentApprove))
+	assert.Empty(takeSent(), "approving a top level comment should not send reply notifications")
+
+	reply := &model.Comment{CommentPostID: int64(post.ID), CommentParent: int64(first.ID), CommentAuthor: "回复者", CommentAuthorEmail: "replier@example.com", CommentContent: "回复你"}
+	if !assert.NoError(model.InsertComment(reply, nil)) {
+		return
+	}
+	defer model.RemoveComment(reply.ID)
+	assert.Len(takeSent(), 1, "held replies should only notify the post author")
+
+	assert.NoError(model.SetCommentStatus(reply.ID, model.CommentApprove))
+	mails = takeSent()
+	if assert.Len(mails, 1) {
+		assert.Equal([]string{email}, mails[0].To)
+		assert.Equal(model.CommentReplyEmail, mails[0].Subject)
+		assert.Contains(mails[0].HTML, fmt.Sprintf("https://example.com%s#comment-%d", model.GetPermalink(post), reply.ID))
+		assert.Contains(mails[0].HTML, model.CommentUnsubscribeURL(email))
+	}
+	model.SetCommentStatus(reply.ID, model.CommentHold)
+	model.SetCommentStatus(reply.ID, model.CommentApprove)
+	assert.Empty(takeSent(), "a reply should only be notified once")
+
+	link, err := url.Parse(model.CommentUnsubscribeURL(strings.ToUpper(email)))
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(model.CommentUnsubscribePath, link.Path)
+	_, err = model.UnsubscribeCommentNotify(email, "bad")
+	assert.Equal(model.ErrInvalidUnsubscribeLink, err)
+	n, err := model.UnsubscribeCommentNotify(link.Query().Get("email"), link.Query().Get("sig"))
+	assert.NoError(err)
+	assert.Equal(int64(1), n)
+	assert.Empty(model.GetCommentmetaValue(first.ID, model.CommentNotifyMetaKey))
+
+	//没有作者的文章通知管理员邮箱
+	orphan := &model.Post{PostTitle: "没有作者的文章", PostStatus: model.PostStatusPublish}
+	if !assert.NoError(model.InsertPost(orphan)) {
+		return
+	}
+	defer model.RemovePost(orphan.ID)
+	defer model.DeleteOption(model.AdminEmailOption)
+	model.SetOptionValue(model.AdminEmailOption, "admin@example.com")
+	held := &model.Comment{CommentPostID: int64(orphan.ID), CommentAuthor: "访客", CommentAuthorEmail: fmt.Sprintf("guest%d@example.com", time.Now().UnixNano()), CommentContent: "无人认领"}
+	if assert.NoError(model.InsertComment(held, nil)) {
+		defer model.RemoveComment(held.ID)
+		if mails := takeSent(); assert.Len(mails, 1) {
+			assert.Equal([]string{"admin@example.com"}, mails[0].To)
+		}
+	}
+}
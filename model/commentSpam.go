@@ -0,0 +1,233 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/insionng/zenpress/module/hook"
+)
+
+const (
+	// CommentCaptchaOption 为1时发表评论须填写验证码
+	CommentCaptchaOption = "comment_captcha"
+	// CommentMaxLinksOption 评论中允许的最大链接数，超出时判定为垃圾评论
+	CommentMaxLinksOption = "comment_max_links"
+	// CommentBlocklistOption 评论黑名单，每行一个关键词、邮箱或IP，作者、邮箱、网址、内容、IP或浏览器标识中包含任一项时判定为垃圾评论
+	CommentBlocklistOption = "comment_blocklist"
+	// CommentFloodIntervalOption 同一IP两次发表评论的最小间隔秒数
+	CommentFloodIntervalOption = "comment_flood_interval"
+	// CommentHourlyLimitOption 同一IP每小时最多发表的评论数，0为不限制
+	CommentHourlyLimitOption = "comment_hourly_limit"
+	// CommentSpamThresholdOption 各评分器的分数之和达到该值时判定为垃圾评论
+	CommentSpamThresholdOption = "comment_spam_threshold"
+
+	// CommentHoneypotField 蜜罐字段名，页面中对访客隐藏，填写了该字段的提交来自机器人
+	CommentHoneypotField = "comment_website"
+	// CommentSpamReasonsMetaKey 评论的垃圾评分明细保存在Commentmeta中的键名
+	CommentSpamReasonsMetaKey = "_spam_reasons"
+)
+
+var (
+	// DefaultCommentMaxLinks 未设置选项时评论中允许的最大链接数
+	DefaultCommentMaxLinks = 2
+	// DefaultCommentFloodInterval 未设置选项时同一IP两次发表评论的最小间隔秒数
+	DefaultCommentFloodInterval = 15
+	// DefaultCommentHourlyLimit 未设置选项时同一IP每小时最多发表的评论数
+	DefaultCommentHourlyLimit = 20
+	// DefaultCommentSpamThreshold 未设置选项时判定为垃圾评论的分数
+	DefaultCommentSpamThreshold = 0.9
+
+	// ErrCommentFlood 同一IP发表评论过于频繁
+	ErrCommentFlood = errors.New("发表评论过于频繁，请稍后再试")
+
+	commentLinkRegexp = regexp.MustCompile(`(?i)(https?://|www\.|<a\s)`)
+
+	commentSpamScorers     []commentSpamScorer
+	commentSpamScorersLock sync.RWMutex
+)
+
+// CommentSpamScorer 垃圾评论评分器，form为提交评论的表单，由后台添加评论时为nil
+// 返回的分数通常在0到1之间，各评分器的分数之和达到阈值时评论将被标记为垃圾评论，reason为命中的原因
+type CommentSpamScorer func(comment *Comment, form url.Values) (score float64, reason string)
+
+// CommentSpamReason 单个评分器给出的分数及原因
+type CommentSpamReason struct {
+	Scorer string  `json:"scorer"`
+	Score  float64 `json:"score"`
+	Reason string  `json:"reason"`
+}
+
+// CommentSpamResult 垃圾评论检查结果，作为comment_spam_result过滤钩子的JSON内容，插件可修改Score、Spam及Reasons
+type CommentSpamResult struct {
+	CommentPostID   int64               `json:"comment_post_id"`
+	CommentAuthor   string              `json:"comment_author"`
+	CommentAuthorIP string              `json:"comment_author_ip"`
+	CommentContent  string              `json:"comment_content"`
+	Score           float64             `json:"score"`
+	Spam            bool                `json:"spam"`
+	Reasons         []CommentSpamReason `json:"reasons"`
+}
+
+type commentSpamScorer struct {
+	name   string
+	scorer CommentSpamScorer
+}
+
+func init() {
+	RegisterCommentSpamScorer("honeypot", honeypotSpamScorer)
+	RegisterCommentSpamScorer("links", linksSpamScorer)
+	RegisterCommentSpamScorer("blocklist", blocklistSpamScorer)
+	RegisterCommentSpamScorer("bayes", bayesSpamScorer)
+}
+
+// RegisterCommentSpamScorer 注册垃圾评论评分器，插件可注册其他评分器，同名评分器将被替换
+func RegisterCommentSpamScorer(name string, scorer CommentSpamScorer) {
+	commentSpamScorersLock.Lock()
+	defer commentSpamScorersLock.Unlock()
+	for i, s := range commentSpamScorers {
+		if s.name == name {
+			commentSpamScorers[i].scorer = scorer
+			return
+		}
+	}
+	commentSpamScorers = append(commentSpamScorers, commentSpamScorer{name: name, scorer: scorer})
+}
+
+// UnregisterCommentSpamScorer 移除垃圾评论评分器
+func UnregisterCommentSpamScorer(name string) {
+	commentSpamScorersLock.Lock()
+	defer commentSpamScorersLock.Unlock()
+	for i, s := range commentSpamScorers {
+		if s.name == name {
+			commentSpamScorers = append(commentSpamScorers[:i], commentSpamScorers[i+1:]...)
+			return
+		}
+	}
+}
+
+// GetCommentSpamScorers 按注册顺序获得垃圾评论评分器的名称
+func GetCommentSpamScorers() []string {
+	commentSpamScorersLock.RLock()
+	defer commentSpamScorersLock.RUnlock()
+	names := make([]string, len(commentSpamScorers))
+	for i, s := range commentSpamScorers {
+		names[i] = s.name
+	}
+	return names
+}
+
+// GetCommentSpamThreshold 获得判定为垃圾评论的分数
+func GetCommentSpamThreshold() float64 {
+	if f, err := strconv.ParseFloat(GetOptionValue(CommentSpamThresholdOption), 64); err == nil && f > 0 {
+		return f
+	}
+	return DefaultCommentSpamThreshold
+}
+
+// CheckCommentFlood 检查同一IP发表评论的频率，超出最小间隔或每小时上限时返回ErrCommentFlood
+func CheckCommentFlood(ip string, now time.Time) error {
+	if len(ip) == 0 {
+		return nil
+	}
+	if interval := optionInt(CommentFloodIntervalOption, DefaultCommentFloodInterval); interval > 0 {
+		var count int
+		Database.Model(&Comment{}).Where("comment_author_ip = ? and comment_date_gmt > ?", ip, now.UTC().Add(-time.Duration(interval)*time.Second)).Count(&count)
+		if count > 0 {
+			return ErrCommentFlood
+		}
+	}
+	if limit := optionInt(CommentHourlyLimitOption, DefaultCommentHourlyLimit); limit > 0 {
+		var count int
+		Database.Model(&Comment{}).Where("comment_author_ip = ? and comment_date_gmt > ?", ip, now.UTC().Add(-time.Hour)).Count(&count)
+		if count >= limit {
+			return ErrCommentFlood
+		}
+	}
+	return nil
+}
+
+// CheckCommentSpam 依次执行已注册的评分器并累计分数，结果可由comment_spam_result过滤钩子修改
+func CheckCommentSpam(comment *Comment, form url.Values) *CommentSpamResult {
+	result := &CommentSpamResult{
+		CommentPostID:   comment.CommentPostID,
+		CommentAuthor:   comment.CommentAuthor,
+		CommentAuthorIP: comment.CommentAuthorIP,
+		CommentContent:  comment.CommentContent,
+		Reasons:         []CommentSpamReason{},
+	}
+
+	commentSpamScorersLock.RLock()
+	scorers := append([]commentSpamScorer(nil), commentSpamScorers...)
+	commentSpamScorersLock.RUnlock()
+	for _, s := range scorers {
+		if score, reason := s.scorer(comment, form); score != 0 {
+			result.Score += score
+			result.Reasons = append(result.Reasons, CommentSpamReason{Scorer: s.name, Score: score, Reason: reason})
+		}
+	}
+	result.Spam = result.Score >= GetCommentSpamThreshold()
+
+	if b, err := json.Marshal(result); err == nil {
+		if b = hook.ApplyFilterHook("comment_spam_result", b); len(b) > 0 {
+			json.Unmarshal(b, result)
+		}
+	}
+	return result
+}
+
+// GetCommentSpamReasons 获得评论发表时的垃圾评分明细
+func GetCommentSpamReasons(commentID uint64) []CommentSpamReason {
+	var reasons []CommentSpamReason
+	json.Unmarshal([]byte(GetCommentmetaValue(commentID, CommentSpamReasonsMetaKey)), &reasons)
+	return reasons
+}
+
+// honeypotSpamScorer 填写了蜜罐字段的提交判定为垃圾评论
+func honeypotSpamScorer(comment *Comment, form url.Values) (float64, string) {
+	if form != nil && len(strings.TrimSpace(form.Get(CommentHoneypotField))) > 0 {
+		return 1, "填写了隐藏字段"
+	}
+	return 0, ""
+}
+
+// linksSpamScorer 链接数超过设置值时判定为垃圾评论
+func linksSpamScorer(comment *Comment, form url.Values) (float64, string) {
+	max := optionInt(CommentMaxLinksOption, DefaultCommentMaxLinks)
+	if n := len(commentLinkRegexp.FindAllStringIndex(comment.CommentContent, -1)); n > max {
+		return 1, "包含" + strconv.Itoa(n) + "个链接"
+	}
+	return 0, ""
+}
+
+// blocklistSpamScorer 命中黑名单时判定为垃圾评论
+func blocklistSpamScorer(comment *Comment, form url.Values) (float64, string) {
+	fields := strings.ToLower(strings.Join([]string{
+		comment.CommentAuthor,
+		comment.CommentAuthorEmail,
+		comment.CommentAuthorURL,
+		comment.CommentContent,
+		comment.CommentAuthorIP,
+		comment.CommentAgent,
+	}, "\n"))
+	for _, word := range strings.Split(GetOptionValue(CommentBlocklistOption), "\n") {
+		if word = strings.ToLower(strings.TrimSpace(word)); len(word) > 0 && strings.Contains(fields, word) {
+			return 1, "命中黑名单：" + word
+		}
+	}
+	return 0, ""
+}
+
+// bayesSpamScorer 以朴素贝叶斯分类器计算垃圾评论的概率，训练样本不足时不评分
+func bayesSpamScorer(comment *Comment, form url.Values) (float64, string) {
+	p, okay := CommentSpamClassifier().Classify(comment)
+	if !okay || p <= 0.5 {
+		return 0, ""
+	}
+	return (p - 0.5) * 2, "贝叶斯分类器判定为垃圾评论的概率为" + strconv.FormatFloat(p, 'f', 2, 64)
+}
@@ -0,0 +1,257 @@
+package model
+
+import (
+	"encoding/gob"
+	"log"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	// CommentSpamTrainedMetaKey 评论已用于训练分类器的类别保存在Commentmeta中的键名，值为spam或ham
+	CommentSpamTrainedMetaKey = "_spam_trained"
+
+	bayesSpam = 0
+	bayesHam  = 1
+)
+
+var (
+	// CommentSpamBayesFile 朴素贝叶斯分类器的训练数据文件
+	CommentSpamBayesFile = "content/storage/spam/bayes.gob"
+	// CommentSpamMinTrained 垃圾评论及正常评论的训练样本均达到该数量后分类器才参与评分
+	CommentSpamMinTrained = 5
+
+	commentSpamClassifier     *CommentBayes
+	commentSpamClassifierLock sync.Mutex
+)
+
+// CommentBayes 以管理员标记的垃圾评论及正常评论训练的朴素贝叶斯分类器，常驻内存并以gob格式保存到磁盘
+type CommentBayes struct {
+	file   string
+	lock   sync.RWMutex
+	loaded bool
+	data   commentBayesData
+}
+
+// commentBayesData 分类器的持久化数据，下标0为垃圾评论，1为正常评论
+type commentBayesData struct {
+	Docs   [2]int
+	Totals [2]int
+	Tokens map[string][2]int
+}
+
+// CommentSpamClassifier 获得垃圾评论分类器，训练数据文件变更时重新加载
+func CommentSpamClassifier() *CommentBayes {
+	commentSpamClassifierLock.Lock()
+	defer commentSpamClassifierLock.Unlock()
+	if commentSpamClassifier == nil || commentSpamClassifier.file != CommentSpamBayesFile {
+		commentSpamClassifier = &CommentBayes{file: CommentSpamBayesFile}
+	}
+	return commentSpamClassifier
+}
+
+// TrainCommentSpam 以评论训练分类器，评论已按另一类别训练时先撤销原训练
+func TrainCommentSpam(comment *Comment, spam bool) error {
+	class := "ham"
+	if spam {
+		class = "spam"
+	}
+	trained := GetCommentmetaValue(comment.ID, CommentSpamTrainedMetaKey)
+	if trained == class {
+		return nil
+	}
+
+	bayes := CommentSpamClassifier()
+	if len(trained) > 0 {
+		if err := bayes.Untrain(comment, trained == "spam"); err != nil {
+			return err
+		}
+	}
+	if err := bayes.Train(comment, spam); err != nil {
+		return err
+	}
+	return SetCommentmetaValue(comment.ID, CommentSpamTrainedMetaKey, class).Error
+}
+
+// Train 将评论作为垃圾评论或正常评论加入训练数据
+func (b *CommentBayes) Train(comment *Comment, spam bool) error {
+	return b.update(comment, spam, 1)
+}
+
+// Untrain 从训练数据中撤销评论
+func (b *CommentBayes) Untrain(comment *Comment, spam bool) error {
+	return b.update(comment, spam, -1)
+}
+
+// Classify 获得评论为垃圾评论的概率，训练样本不足时okay为false
+func (b *CommentBayes) Classify(comment *Comment) (p float64, okay bool) {
+	if err := b.load(); err != nil {
+		log.Printf("load comment spam classifier error: %v", err)
+		return 0, false
+	}
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	docs := b.data.Docs
+	if docs[bayesSpam] < CommentSpamMinTrained || docs[bayesHam] < CommentSpamMinTrained {
+		return 0, false
+	}
+
+	vocabulary := float64(len(b.data.Tokens))
+	var logp [2]float64
+	for c := range logp {
+		logp[c] = math.Log(float64(docs[c]) / float64(docs[bayesSpam]+docs[bayesHam]))
+	}
+	for _, token := range commentSpamTokens(comment) {
+		counts := b.data.Tokens[token]
+		for c := range logp {
+			logp[c] += math.Log((float64(counts[c]) + 1) / (float64(b.data.Totals[c]) + vocabulary))
+		}
+	}
+	return 1 / (1 + math.Exp(logp[bayesHam]-logp[bayesSpam])), true
+}
+
+// Stats 获得已训练的垃圾评论及正常评论数量
+func (b *CommentBayes) Stats() (spam, ham int) {
+	if err := b.load(); err != nil {
+		return 0, 0
+	}
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	return b.data.Docs[bayesSpam], b.data.Docs[bayesHam]
+}
+
+// Reset 清空训练数据
+func (b *CommentBayes) Reset() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.data = commentBayesData{Tokens: map[string][2]int{}}
+	b.loaded = true
+	if err := Database.Delete(Commentmeta{}, "meta_key = ?", CommentSpamTrainedMetaKey).Error; err != nil {
+		return err
+	}
+	if err := os.Remove(b.file); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (b *CommentBayes) update(comment *Comment, spam bool, delta int) error {
+	if err := b.load(); err != nil {
+		return err
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	c := bayesHam
+	if spam {
+		c = bayesSpam
+	}
+	if b.data.Docs[c]+delta < 0 {
+		return nil
+	}
+
+	b.data.Docs[c] += delta
+	for _, token := range commentSpamTokens(comment) {
+		counts := b.data.Tokens[token]
+		if counts[c]+delta < 0 {
+			continue
+		}
+		counts[c] += delta
+		b.data.Totals[c] += delta
+		if counts[bayesSpam] == 0 && counts[bayesHam] == 0 {
+			delete(b.data.Tokens, token)
+		} else {
+			b.data.Tokens[token] = counts
+		}
+	}
+	return b.save()
+}
+
+func (b *CommentBayes) load() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.loaded {
+		return nil
+	}
+
+	b.data = commentBayesData{Tokens: map[string][2]int{}}
+	f, err := os.Open(b.file)
+	if os.IsNotExist(err) {
+		b.loaded = true
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := gob.NewDecoder(f).Decode(&b.data); err != nil || b.data.Tokens == nil {
+		//训练数据损坏时从空数据开始，可重新标记评论训练
+		b.data = commentBayesData{Tokens: map[string][2]int{}}
+	}
+	b.loaded = true
+	return nil
+}
+
+func (b *CommentBayes) save() error {
+	if err := os.MkdirAll(filepath.Dir(b.file), 0755); err != nil {
+		return err
+	}
+	tmp := b.file + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(f).Encode(&b.data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, b.file)
+}
+
+// commentSpamTokens 获得评论用于分类的特征，内容按检索词切分，作者、邮箱域名、网址域名及IP作为附加特征，同一特征只计一次
+func commentSpamTokens(comment *Comment) []string {
+	tokens := SearchTokenize(comment.CommentContent)
+	if author := strings.ToLower(strings.TrimSpace(comment.CommentAuthor)); len(author) > 0 {
+		tokens = append(tokens, "author:"+author)
+	}
+	if i := strings.LastIndex(comment.CommentAuthorEmail, "@"); i >= 0 {
+		tokens = append(tokens, "email:"+strings.ToLower(comment.CommentAuthorEmail[i+1:]))
+	}
+	if u, err := url.Parse(comment.CommentAuthorURL); err == nil && len(u.Host) > 0 {
+		tokens = append(tokens, "url:"+strings.ToLower(u.Host))
+	}
+	if len(comment.CommentAuthorIP) > 0 {
+		tokens = append(tokens, "ip:"+comment.CommentAuthorIP)
+	}
+	tokens = append(tokens, "links:"+commentLinkBucket(comment.CommentContent))
+
+	seen := make(map[string]bool, len(tokens))
+	unique := tokens[:0]
+	for _, token := range tokens {
+		if !seen[token] {
+			seen[token] = true
+			unique = append(unique, token)
+		}
+	}
+	return unique
+}
+
+// commentLinkBucket 将评论中的链接数归为0、1、2、多个四档
+func commentLinkBucket(content string) string {
+	switch n := len(commentLinkRegexp.FindAllStringIndex(content, -1)); {
+	case n == 0:
+		return "0"
+	case n == 1:
+		return "1"
+	case n == 2:
+		return "2"
+	}
+	return "many"
+}
@@ -0,0 +1,107 @@
+package model_test
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/insionng/zenpress/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckCommentSpam(t *testing.T) {
+	assert := assert.New(t)
+	defer model.DeleteOption(model.CommentBlocklistOption)
+
+	comment := &model.Comment{CommentAuthor: "访客", CommentContent: "写得很好，学习了"}
+	result := model.CheckCommentSpam(comment, url.Values{})
+	assert.False(result.Spam)
+	assert.Empty(result.Reasons)
+
+	result = model.CheckCommentSpam(comment, url.Values{model.CommentHoneypotField: {"http://bot.example.com"}})
+	if assert.True(result.Spam) && assert.Len(result.Reasons, 1) {
+		assert.Equal("honeypot", result.Reasons[0].Scorer)
+	}
+
+	links := &model.Comment{CommentAuthor: "访客", CommentContent: "http://a.example.com http://b.example.com www.c.example.com"}
+	assert.True(model.CheckCommentSpam(links, nil).Spam)
+
+	model.SetOptionValue(model.CommentBlocklistOption, "\n 廉价代购 \n")
+	assert.True(model.CheckCommentSpam(&model.Comment{CommentContent: "全网廉价代购"}, nil).Spam)
+
+	model.RegisterCommentSpamScorer("test", func(c *model.Comment, form url.Values) (float64, string) {
+		return 0.5, "测试评分"
+	})
+	assert.Contains(model.GetCommentSpamScorers(), "test")
+	result = model.CheckCommentSpam(comment, nil)
+	assert.Equal(0.5, result.Score)
+	assert.False(result.Spam)
+	model.UnregisterCommentSpamScorer("test")
+	assert.NotContains(model.GetCommentSpamScorers(), "test")
+}
+
+func TestCheckCommentFlood(t *testing.T) {
+	assert := assert.New(t)
+	post := &model.Post{PostTitle: "测试评论频率", PostStatus: model.PostStatusPublish}
+	if !assert.NoError(model.InsertPost(post)) {
+		return
+	}
+	defer model.RemovePost(post.ID)
+
+	ip := fmt.Sprintf("10.%d.%d.%d", time.Now().Nanosecond()%250, time.Now().Second(), time.Now().Minute())
+	assert.NoError(model.CheckCommentFlood(ip, time.Now()))
+	comment := &model.Comment{
+		CommentPostID:      int64(post.ID),
+		CommentAuthor:      "频繁评论",
+		CommentAuthorEmail: "flood@example.com",
+		CommentAuthorIP:    ip,
+		CommentContent:     "第一条",
+	}
+	if !assert.NoError(model.InsertComment(comment, nil)) {
+		return
+	}
+	defer model.RemoveComment(comment.ID)
+	assert.Equal(model.ErrCommentFlood, model.CheckCommentFlood(ip, time.Now()))
+	assert.NoError(model.CheckCommentFlood(ip, time.Now().Add(time.Minute)))
+}
+
+func TestCommentBayes(t *testing.T) {
+	assert := assert.New(t)
+	defer func(file string) { model.CommentSpamBayesFile = file }(model.CommentSpamBayesFile)
+	model.CommentSpamBayesFile = filepath.Join(os.TempDir(), fmt.Sprintf("zenpress-bayes-%d.gob", time.Now().UnixNano()))
+	defer os.Remove(model.CommentSpamBayesFile)
+
+	bayes := model.CommentSpamClassifier()
+	_, okay := bayes.Classify(&model.Comment{CommentContent: "便宜发票"})
+	assert.False(okay, "classifier should not score before enough samples are trained")
+
+	for i := 0; i < model.CommentSpamMinTrained; i++ {
+		assert.NoError(bayes.Train(&model.Comment{CommentContent: fmt.Sprintf("便宜发票 代开 优惠 %d", i)}, true))
+		assert.NoError(bayes.Train(&model.Comment{CommentContent: fmt.Sprintf("文章 写得 很好 学习 %d", i)}, false))
+	}
+	spam, ham := bayes.Stats()
+	assert.Equal(model.CommentSpamMinTrained, spam)
+	assert.Equal(model.CommentSpamMinTrained, ham)
+
+	p, okay := bayes.Classify(&model.Comment{CommentContent: "代开 便宜发票"})
+	assert.True(okay)
+	assert.True(p > 0.5)
+	p, _ = bayes.Classify(&model.Comment{CommentContent: "文章 很好"})
+	assert.True(p < 0.5)
+
+	model.CommentSpamBayesFile = model.CommentSpamBayesFile + ".copy"
+	reloaded := model.CommentSpamClassifier()
+	spam, _ = reloaded.Stats()
+	assert.Equal(0, spam, "a different data file should start empty")
+	model.CommentSpamBayesFile = model.CommentSpamBayesFile[:len(model.CommentSpamBayesFile)-len(".copy")]
+	spam, _ = model.CommentSpamClassifier().Stats()
+	assert.Equal(model.CommentSpamMinTrained, spam, "training data should be persisted to disk")
+
+	assert.NoError(model.CommentSpamClassifier().Reset())
+	spam, ham = model.CommentSpamClassifier().Stats()
+	assert.Equal(0, spam+ham)
+}
@@ -0,0 +1,539 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/insionng/zenpress/helper"
+	"github.com/insionng/zenpress/module/hook"
+)
+
+const (
+	CommentApprove = "1"     //已审核
+	CommentHold    = "0"     //待审
+	CommentSpam    = "spam"  //垃圾评论
+	CommentTrash   = "trash" //回收站
+
+	CommentStatusOpen   = "open"   //文章允许评论
+	CommentStatusClosed = "closed" //文章关闭评论
+
+	// ThreadCommentsDepthOption 评论嵌套回复的最大层数保存在选项中的键名，1为不允许回复
+	ThreadCommentsDepthOption = "thread_comments_depth"
+	// CommentModerationOption 为1时所有新评论须人工审核，否则曾有评论通过审核的邮箱可直接发表
+	CommentModerationOption = "comment_moderation"
+)
+
+var (
+	// DefaultThreadCommentsDepth 未设置选项时评论嵌套回复的最大层数
+	DefaultThreadCommentsDepth = 5
+	// CommentMaxLength 评论内容的最大长度，按字符计算
+	CommentMaxLength = 65525
+
+	// ErrInvalidCommentStatus 评论审核状态无效
+	ErrInvalidCommentStatus = errors.New("评论审核状态无效")
+	// ErrCommentsClosed 文章不存在、未发布或已关闭评论
+	ErrCommentsClosed = errors.New("文章已关闭评论")
+	// ErrEmptyComment 评论内容为空
+	ErrEmptyComment = errors.New("评论内容不能为空")
+	// ErrCommentTooLong 评论内容过长
+	ErrCommentTooLong = errors.New("评论内容过长")
+	// ErrCommentAuthorRequired 未登录时须填写昵称及邮箱
+	ErrCommentAuthorRequired = errors.New("请填写昵称及有效的邮箱")
+	// ErrInvalidCommentParent 回复的评论不存在、未通过审核或不属于该文章
+	ErrInvalidCommentParent = errors.New("回复的评论无效")
+	// ErrCommentTooDeep 回复超过评论嵌套的最大层数
+	ErrCommentTooDeep = errors.New("回复超过评论嵌套的最大层数")
+	// ErrCommentNotSpam 评论不是垃圾评论
+	ErrCommentNotSpam = errors.New("评论不是垃圾评论")
+	// ErrInvalidCommentAuthorURL 评论者网址不是http或https地址
+	ErrInvalidCommentAuthorURL = errors.New("网址须以http://或https://开头")
+
+	commentStatuses = map[string]bool{
+		CommentApprove: true,
+		CommentHold:    true,
+		CommentSpam:    true,
+		CommentTrash:   true,
+	}
+)
+
+// ThreadedComment 按回复关系排列的评论，Depth从1开始
+type ThreadedComment struct {
+	Comment
+	Depth    int
+	CanReply bool
+	Votes    Votes
+}
+
+// CommentListItem 后台评论列表项
+type CommentListItem struct {
+	Comment
+	PostTitle   string
+	PostLink    string
+	SpamReasons []CommentSpamReason
+}
+
+// CommentCounts 各审核状态的评论数量
+type CommentCounts struct {
+	Approved int
+	Hold     int
+	Spam     int
+	Trash    int
+}
+
+// IsValidCommentStatus 是否为有效的评论审核状态
+func IsValidCommentStatus(status string) bool {
+	return commentStatuses[status]
+}
+
+// GetThreadCommentsDepth 获得评论嵌套回复的最大层数
+func GetThreadCommentsDepth() int {
+	if depth := optionInt(ThreadCommentsDepthOption, DefaultThreadCommentsDepth); depth > 0 {
+		return depth
+	}
+	return 1
+}
+
+// CommentsOpen 文章是否已发布且允许评论
+func CommentsOpen(post Post) bool {
+	return post.PostStatus == PostStatusPublish && post.CommentStatus != CommentStatusClosed
+}
+
+// InsertComment 发表评论，校验文章评论状态、回复层数及发表频率，按审核设置、垃圾评论检查及pre_comment_approved过滤钩子决定审核状态
+// form为提交评论的表单，供蜜罐、插件评分器及订阅回复通知使用，由后台添加评论时可为nil，发表后按设置通知文章作者及被回复者
+func InsertComment(comment *Comment, form url.Values) error {
+	db, post := GetPost(uint64(comment.CommentPostID))
+	if db.Error != nil || !CommentsOpen(post) {
+		return ErrCommentsClosed
+	}
+
+	comment.CommentContent = strings.TrimSpace(comment.CommentContent)
+	comment.CommentAuthor = strings.TrimSpace(comment.CommentAuthor)
+	comment.CommentAuthorEmail = strings.TrimSpace(comment.CommentAuthorEmail)
+	comment.CommentAuthorURL = strings.TrimSpace(comment.CommentAuthorURL)
+	if len(comment.CommentContent) == 0 {
+		return ErrEmptyComment
+	}
+	if len([]rune(comment.CommentContent)) > CommentMaxLength {
+		return ErrCommentTooLong
+	}
+	if comment.UserID == 0 && (len(comment.CommentAuthor) == 0 || !helper.CheckEmail(comment.CommentAuthorEmail)) {
+		return ErrCommentAuthorRequired
+	}
+	if len(comment.CommentAuthorURL) > 0 {
+		if comment.CommentAuthorURL = helper.SafeURL(comment.CommentAuthorURL); len(comment.CommentAuthorURL) == 0 {
+			return ErrInvalidCommentAuthorURL
+		}
+	}
+
+	if comment.CommentParent > 0 {
+		db, parent := GetComment(uint64(comment.CommentParent))
+		if db.Error != nil || parent.CommentPostID != comment.CommentPostID || parent.CommentApproved != CommentApprove {
+			return ErrInvalidCommentParent
+		}
+		if GetCommentDepth(parent.ID) >= GetThreadCommentsDepth() {
+			return ErrCommentTooDeep
+		}
+	}
+
+	now := time.Now()
+	if err := CheckCommentFlood(comment.CommentAuthorIP, now); err != nil {
+		return err
+	}
+	comment.CommentDate = now
+	comment.CommentDateGmt = now.UTC()
+	comment.CommentApproved = defaultCommentApproved(comment)
+	spam := CheckCommentSpam(comment, form)
+	if spam.Spam {
+		comment.CommentApproved = CommentSpam
+	}
+	if b, err := json.Marshal(comment); err == nil {
+		if b = hook.ApplyFilterHook("pre_comment_approved", b); len(b) > 0 {
+			var filtered Comment
+			if json.Unmarshal(b, &filtered) == nil && IsValidCommentStatus(filtered.CommentApproved) {
+				comment.CommentApproved = filtered.CommentApproved
+			}
+		}
+	}
+
+	if err := NewComment(comment).Error; err != nil {
+		return err
+	}
+	if len(spam.Reasons) > 0 {
+		b, _ := json.Marshal(spam.Reasons)
+		SetCommentmetaValue(comment.ID, CommentSpamReasonsMetaKey, string(b))
+	}
+	if form != nil && form.Get(CommentNotifyField) == "1" && GetOptionValue(CommentReplyNotifyOption) != "0" {
+		SetCommentNotify(comment.ID, true)
+	}
+	if comment.CommentApproved == CommentApprove {
+		if err := UpdatePostCommentCount(post.ID); err != nil {
+			return err
+		}
+	}
+	NotifyPostAuthor(comment)
+	NotifyCommentReply(comment)
+	doCommentHook("comment_post", comment)
+	return nil
+}
+
+// SetCommentStatus 修改评论审核状态并同步文章评论数，移至回收站或标记为垃圾评论时原状态保存于Commentmeta以便还原
+// 标记为垃圾评论时以该评论训练垃圾评论分类器，批准垃圾评论时作为正常评论训练，批准回复时通知订阅了回复通知的被回复者
+func SetCommentStatus(id uint64, status string) error {
+	return setCommentStatus(id, status, false)
+}
+
+// UnspamComment 将垃圾评论标记为正常评论，还原为原审核状态并作为正常评论训练垃圾评论分类器
+func UnspamComment(id uint64) error {
+	db, comment := GetComment(id)
+	if db.Error != nil {
+		return db.Error
+	}
+	if comment.CommentApproved != CommentSpam {
+		return ErrCommentNotSpam
+	}
+	return setCommentStatus(id, restoredCommentStatus(id), true)
+}
+
+// setCommentStatus 修改评论审核状态，unspam为真时将垃圾评论作为正常评论训练分类器
+func setCommentStatus(id uint64, status string, unspam bool) error {
+	if !IsValidCommentStatus(status) {
+		return ErrInvalidCommentStatus
+	}
+	db, comment := GetComment(id)
+	if db.Error != nil {
+		return db.Error
+	}
+	oldStatus := comment.CommentApproved
+	if oldStatus == status {
+		return nil
+	}
+
+	if status == CommentTrash || status == CommentSpam {
+		if oldStatus != CommentTrash && oldStatus != CommentSpam {
+			SetCommentmetaValue(id, "_wp_trash_meta_status", oldStatus)
+		}
+	} else {
+		DeleteCommentmetaByKey(id, "_wp_trash_meta_status")
+	}
+	if err := Database.Model(&Comment{}).Where("id = ?", id).UpdateColumn("comment_approved", status).Error; err != nil {
+		return err
+	}
+	comment.CommentApproved = status
+	if oldStatus == CommentApprove || status == CommentApprove {
+		if err := UpdatePostCommentCount(uint64(comment.CommentPostID)); err != nil {
+			return err
+		}
+	}
+	if status == CommentSpam || (oldStatus == CommentSpam && (status == CommentApprove || unspam)) {
+		if err := TrainCommentSpam(&comment, status == CommentSpam); err != nil {
+			log.Printf("train comment spam classifier error: %v", err)
+		}
+	}
+	if status == CommentApprove {
+		NotifyCommentReply(&comment)
+	}
+	doCommentHook("transition_comment_status", &comment)
+	return nil
+}
+
+// RestoreComment 将回收站或垃圾评论还原为原审核状态
+func RestoreComment(id uint64) error {
+	return SetCommentStatus(id, restoredCommentStatus(id))
+}
+
+// restoredCommentStatus 获得移至回收站或标记为垃圾评论前的审核状态，无记录时为待审
+func restoredCommentStatus(id uint64) string {
+	status := GetCommentmetaValue(id, "_wp_trash_meta_status")
+	if !IsValidCommentStatus(status) || status == CommentTrash || status == CommentSpam {
+		status = CommentHold
+	}
+	return status
+}
+
+// RemoveComment 永久删除评论及其数据，回复将移至被删除评论的上级
+func RemoveComment(id uint64) error {
+	db, comment := GetComment(id)
+	if db.Error != nil {
+		return db.Error
+	}
+	doCommentHook("delete_comment", &comment)
+
+	tx := Database.Begin()
+	if err := tx.Model(&Comment{}).Where("comment_parent = ?", id).UpdateColumn("comment_parent", comment.CommentParent).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Delete(Commentmeta{}, "comment_id = ?", id).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Delete(Comment{}, "id = ?", id).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+	if comment.CommentApproved == CommentApprove {
+		return UpdatePostCommentCount(uint64(comment.CommentPostID))
+	}
+	return nil
+}
+
+// BulkCommentAction 批量处理评论，action为approve、hold、spam、unspam、trash、restore或delete，返回处理成功的数量
+func BulkCommentAction(ids []uint64, action string) (int, error) {
+	var count int
+	for _, id := range ids {
+		var err error
+		switch action {
+		case "approve":
+			err = SetCommentStatus(id, CommentApprove)
+		case "hold":
+			err = SetCommentStatus(id, CommentHold)
+		case "spam":
+			err = SetCommentStatus(id, CommentSpam)
+		case "unspam":
+			err = UnspamComment(id)
+		case "trash":
+			err = SetCommentStatus(id, CommentTrash)
+		case "restore":
+			err = RestoreComment(id)
+		case "delete":
+			err = RemoveComment(id)
+		default:
+			return count, ErrInvalidCommentStatus
+		}
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// GetCommentDepth 获得评论所在的层数，顶层评论为1
+func GetCommentDepth(id uint64) int {
+	depth := 0
+	seen := map[uint64]bool{}
+	for id > 0 && !seen[id] {
+		seen[id] = true
+		db, comment := GetComment(id)
+		if db.Error != nil {
+			break
+		}
+		depth++
+		id = uint64(comment.CommentParent)
+	}
+	return depth
+}
+
+// GetThreadedComments 按回复关系获得文章已审核的评论，同级评论按设置的排序方式排列，超过最大层数的回复显示在最大层
+func GetThreadedComments(postID uint64) []ThreadedComment {
+	return GetSortedThreadedComments(postID, GetCommentOrder())
+}
+
+// GetSortedThreadedComments 按回复关系获得文章已审核的评论，同级评论按order排列，order无效时按时间先后排列
+func GetSortedThreadedComments(postID uint64, order string) []ThreadedComment {
+	var comments []Comment
+	Database.Where("comment_post_id = ? and comment_approved = ?", postID, CommentApprove).Order("comment_date, id").Find(&comments)
+
+	approved := make(map[int64]bool, len(comments))
+	ids := make([]uint64, len(comments))
+	for i, c := range comments {
+		approved[int64(c.ID)] = true
+		ids[i] = c.ID
+	}
+	votes := getCommentsVotes(ids)
+	children := map[int64][]Comment{}
+	for _, c := range comments {
+		parent := c.CommentParent
+		if !approved[parent] {
+			parent = 0
+		}
+		children[parent] = append(children[parent], c)
+	}
+	for _, siblings := range children {
+		sortComments(siblings, votes, order)
+	}
+
+	maxDepth := GetThreadCommentsDepth()
+	threaded := make([]ThreadedComment, 0, len(comments))
+	var walk func(parent int64, depth int)
+	walk = func(parent int64, depth int) {
+		for _, c := range children[parent] {
+			d := depth
+			if d > maxDepth {
+				d = maxDepth
+			}
+			threaded = append(threaded, ThreadedComment{Comment: c, Depth: d, CanReply: d < maxDepth, Votes: votes[c.ID]})
+			walk(int64(c.ID), depth+1)
+		}
+	}
+	walk(0, 1)
+	return threaded
+}
+
+// getCommentsVotes 批量获得评论的投票统计
+func getCommentsVotes(ids []uint64) map[uint64]Votes {
+	votes := make(map[uint64]Votes, len(ids))
+	if len(ids) == 0 {
+		return votes
+	}
+	var metas []Commentmeta
+	Database.Where("comment_id in (?) and meta_key in (?)", ids, []string{VoteUpsMetaKey, VoteDownsMetaKey}).Find(&metas)
+	counts := map[uint64][2]int64{}
+	for _, m := range metas {
+		n, _ := strconv.ParseInt(m.MetaValue, 10, 64)
+		c := counts[m.CommentID]
+		if m.MetaKey == VoteUpsMetaKey {
+			c[0] = n
+		} else {
+			c[1] = n
+		}
+		counts[m.CommentID] = c
+	}
+	for _, id := range ids {
+		c := counts[id]
+		votes[id] = NewVotes(c[0], c[1])
+	}
+	return votes
+}
+
+// sortComments 按排序方式排列同级评论，得分相同时按时间先后排列
+func sortComments(comments []Comment, votes map[uint64]Votes, order string) {
+	switch order {
+	case CommentOrderNewest:
+		sort.SliceStable(comments, func(i, j int) bool {
+			if !comments[i].CommentDate.Equal(comments[j].CommentDate) {
+				return comments[i].CommentDate.After(comments[j].CommentDate)
+			}
+			return comments[i].ID > comments[j].ID
+		})
+	case CommentOrderBest:
+		sort.SliceStable(comments, func(i, j int) bool {
+			a, b := votes[comments[i].ID], votes[comments[j].ID]
+			if a.Confidence != b.Confidence {
+				return a.Confidence > b.Confidence
+			}
+			return a.Score > b.Score
+		})
+	case CommentOrderControversial:
+		sort.SliceStable(comments, func(i, j int) bool {
+			a, b := votes[comments[i].ID], votes[comments[j].ID]
+			if a.Controversy != b.Controversy {
+				return a.Controversy > b.Controversy
+			}
+			return a.Ups+a.Downs > b.Ups+b.Downs
+		})
+	}
+}
+
+// GetCommentCounts 按审核状态统计评论数量
+func GetCommentCounts() CommentCounts {
+	var counts CommentCounts
+	rows, err := Database.Model(&Comment{}).Select("comment_approved, count(*)").Group("comment_approved").Rows()
+	if err != nil {
+		return counts
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var status string
+		var count int
+		if rows.Scan(&status, &count) != nil {
+			continue
+		}
+		switch status {
+		case CommentApprove:
+			counts.Approved = count
+		case CommentHold:
+			counts.Hold = count
+		case CommentSpam:
+			counts.Spam = count
+		case CommentTrash:
+			counts.Trash = count
+		}
+	}
+	return counts
+}
+
+// PageComments 分页获得指定审核状态的评论，status为空时为待审及已审核的评论，postID为0时不限文章
+func PageComments(status string, postID uint64, pageNo, pageSize int) helper.Page {
+	var comments []Comment
+	var count int
+	db := Database.Model(&Comment{})
+	if len(status) > 0 {
+		db = db.Where("comment_approved = ?", status)
+	} else {
+		db = db.Where("comment_approved in (?)", []string{CommentApprove, CommentHold})
+	}
+	if postID > 0 {
+		db = db.Where("comment_post_id = ?", postID)
+	}
+	db.Count(&count)
+	if pageNo < 1 {
+		pageNo = 1
+	}
+	db.Order("comment_date desc, id desc").Offset((pageNo - 1) * pageSize).Limit(pageSize).Find(&comments)
+
+	var ids []uint64
+	for _, c := range comments {
+		ids = append(ids, uint64(c.CommentPostID))
+	}
+	posts := map[uint64]*Post{}
+	if len(ids) > 0 {
+		var list []Post
+		Database.Where("id in (?)", ids).Find(&list)
+		for i := range list {
+			posts[list[i].ID] = &list[i]
+		}
+	}
+
+	items := make([]CommentListItem, len(comments))
+	for i, c := range comments {
+		items[i].Comment = c
+		if post, okay := posts[uint64(c.CommentPostID)]; okay {
+			items[i].PostTitle = post.PostTitle
+			items[i].PostLink = GetPermalink(post)
+		}
+		if c.CommentApproved == CommentSpam {
+			items[i].SpamReasons = GetCommentSpamReasons(c.ID)
+		}
+	}
+	return helper.PageUtil(count, pageNo, pageSize, items)
+}
+
+// ParseCommentIDs 将表单中提交的评论ID转为数值，忽略无效的值
+func ParseCommentIDs(form url.Values, key string) (ids []uint64) {
+	for _, v := range form[key] {
+		if id, err := strconv.ParseUint(strings.TrimSpace(v), 10, 64); err == nil && id > 0 {
+			ids = append(ids, id)
+		}
+	}
+	return
+}
+
+// defaultCommentApproved 按审核设置获得新评论的审核状态
+func defaultCommentApproved(comment *Comment) string {
+	if GetOptionValue(CommentModerationOption) == "1" {
+		return CommentHold
+	}
+	if len(comment.CommentAuthorEmail) > 0 {
+		var count int
+		Database.Model(&Comment{}).Where("comment_author_email = ? and comment_approved = ?", comment.CommentAuthorEmail, CommentApprove).Count(&count)
+		if count > 0 {
+			return CommentApprove
+		}
+	}
+	return CommentHold
+}
+
+func doCommentHook(key string, comment *Comment) {
+	b, _ := json.Marshal(comment)
+	hook.ApplyFilterHook(key, b)
+}
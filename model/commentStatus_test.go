@@ -0,0 +1,113 @@
+package model_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/insionng/zenpress/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInsertComment(t *testing.T) {
+	assert := assert.New(t)
+	post := &model.Post{PostTitle: "测试评论文章", PostStatus: model.PostStatusPublish}
+	if !assert.NoError(model.InsertPost(post)) {
+		return
+	}
+	defer model.RemovePost(post.ID)
+	defer model.DeleteOption(model.ThreadCommentsDepthOption)
+	defer func(file string) { model.CommentSpamBayesFile = file }(model.CommentSpamBayesFile)
+	model.CommentSpamBayesFile = filepath.Join(os.TempDir(), fmt.Sprintf("zenpress-bayes-%d.gob", time.Now().UnixNano()))
+	defer os.Remove(model.CommentSpamBayesFile)
+	model.SetOptionValue(model.ThreadCommentsDepthOption, "2")
+
+	email := fmt.Sprintf("comment%d@example.com", time.Now().UnixNano())
+	newComment := func(content string, parent uint64) *model.Comment {
+		return &model.Comment{
+			CommentPostID:      int64(post.ID),
+			CommentParent:      int64(parent),
+			CommentAuthor:      "评论者",
+			CommentAuthorEmail: email,
+			CommentContent:     content,
+		}
+	}
+
+	assert.Equal(model.ErrEmptyComment, model.InsertComment(newComment("  ", 0), nil))
+	anonymous := newComment("匿名评论", 0)
+	anonymous.CommentAuthorEmail = ""
+	assert.Equal(model.ErrCommentAuthorRequired, model.InsertComment(anonymous, nil))
+	script := newComment("脚本网址", 0)
+	script.CommentAuthorURL = "javascript:alert(1)"
+	assert.Equal(model.ErrInvalidCommentAuthorURL, model.InsertComment(script, nil))
+
+	first := newComment("第一条评论", 0)
+	if !assert.NoError(model.InsertComment(first, nil)) {
+		return
+	}
+	assert.Equal(model.CommentHold, first.CommentApproved, "new authors should be held for moderation")
+	assert.Equal(model.ErrInvalidCommentParent, model.InsertComment(newComment("回复待审评论", first.ID), nil))
+
+	assert.NoError(model.SetCommentStatus(first.ID, model.CommentApprove))
+	_, p := model.GetPost(post.ID)
+	assert.Equal(uint64(1), p.CommentCount)
+
+	reply := newComment("回复", first.ID)
+	if assert.NoError(model.InsertComment(reply, nil)) {
+		assert.Equal(model.CommentApprove, reply.CommentApproved, "previously approved authors should skip moderation")
+	}
+	assert.Equal(model.ErrCommentTooDeep, model.InsertComment(newComment("超过层数", reply.ID), nil))
+	second := newComment("第二条评论", 0)
+	assert.NoError(model.InsertComment(second, nil))
+
+	threaded := model.GetThreadedComments(post.ID)
+	if assert.Len(threaded, 3) {
+		assert.Equal(first.ID, threaded[0].ID)
+		assert.Equal(1, threaded[0].Depth)
+		assert.True(threaded[0].CanReply)
+		assert.Equal(reply.ID, threaded[1].ID)
+		assert.Equal(2, threaded[1].Depth)
+		assert.False(threaded[1].CanReply)
+		assert.Equal(second.ID, threaded[2].ID)
+	}
+
+	assert.NoError(model.SetCommentStatus(second.ID, model.CommentSpam))
+	_, p = model.GetPost(post.ID)
+	assert.Equal(uint64(2), p.CommentCount)
+	assert.NoError(model.RestoreComment(second.ID))
+	_, c := model.GetComment(second.ID)
+	assert.Equal(model.CommentApprove, c.CommentApproved, "restore should bring back the status before spam")
+
+	model.DeleteCommentmetaByKey(first.ID, model.CommentSpamTrainedMetaKey)
+	assert.NoError(model.SetCommentStatus(first.ID, model.CommentHold))
+	assert.NoError(model.SetCommentStatus(first.ID, model.CommentApprove))
+	assert.Empty(model.GetCommentmetaValue(first.ID, model.CommentSpamTrainedMetaKey), "approving a held comment should not train the classifier")
+	assert.NoError(model.SetCommentStatus(second.ID, model.CommentSpam))
+	assert.Equal("spam", model.GetCommentmetaValue(second.ID, model.CommentSpamTrainedMetaKey))
+	assert.Equal(model.ErrCommentNotSpam, model.UnspamComment(first.ID))
+	assert.NoError(model.UnspamComment(second.ID))
+	_, c = model.GetComment(second.ID)
+	assert.Equal(model.CommentApprove, c.CommentApproved)
+	assert.Equal("ham", model.GetCommentmetaValue(second.ID, model.CommentSpamTrainedMetaKey), "unspam should train the comment as ham")
+
+	n, err := model.BulkCommentAction([]uint64{first.ID, second.ID}, "trash")
+	assert.NoError(err)
+	assert.Equal(2, n)
+	_, p = model.GetPost(post.ID)
+	assert.Equal(uint64(1), p.CommentCount)
+
+	assert.NoError(model.RemoveComment(first.ID))
+	_, c = model.GetComment(reply.ID)
+	assert.Equal(int64(0), c.CommentParent, "replies should move up to the removed comment's parent")
+	model.RemoveComment(second.ID)
+	model.RemoveComment(reply.ID)
+	_, p = model.GetPost(post.ID)
+	assert.Equal(uint64(0), p.CommentCount)
+
+	post.CommentStatus = model.CommentStatusClosed
+	assert.NoError(model.SavePost(post))
+	assert.Equal(model.ErrCommentsClosed, model.InsertComment(newComment("已关闭", 0), nil))
+}
@@ -27,12 +27,18 @@ func TestNewComment(t *testing.T) {
 }
 func TestAddComment(t *testing.T) {
 	assert := assert.New(t)
-	db := model.AddComment("testkey", "http://www.at3.net", "http://at3.net/image.png")
+	db := model.AddComment(1, "testkey", "http://www.at3.net", "http://at3.net/image.png")
 
 	if assert.NotNil(db) {
 		assert.Equal(nil, db.Error, "they should be equal")
 	}
 
+	var comment model.Comment
+	model.Database.Order("id desc").First(&comment, "comment_author = ?", "testkey")
+	assert.Equal(int64(1), comment.CommentPostID)
+	assert.Equal("http://www.at3.net", comment.CommentAuthorURL)
+	model.DeleteComment(comment.ID)
+
 }
 func TestGetComment(t *testing.T) {
 	assert := assert.New(t)
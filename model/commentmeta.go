@@ -40,3 +40,31 @@ func DeleteCommentmeta(id uint64) (db *gorm.DB) {
 	db = Database.Delete(Commentmeta{}, "id = ?", id)
 	return
 }
+
+// GetCommentmetaByKey 获得指定评论的指定数据
+func GetCommentmetaByKey(commentID uint64, metaKey string) (db *gorm.DB, commentmeta Commentmeta) {
+	db = Database.First(&commentmeta, "comment_id = ? and meta_key = ?", commentID, metaKey)
+	return
+}
+
+// GetCommentmetaValue 获得指定评论的数据值，不存在时返回空字符串
+func GetCommentmetaValue(commentID uint64, metaKey string) string {
+	if db, commentmeta := GetCommentmetaByKey(commentID, metaKey); db.Error == nil {
+		return commentmeta.MetaValue
+	}
+	return ""
+}
+
+// SetCommentmetaValue 设置指定评论的数据值，不存在时新增
+func SetCommentmetaValue(commentID uint64, metaKey, metaValue string) (db *gorm.DB) {
+	if db, commentmeta := GetCommentmetaByKey(commentID, metaKey); db.Error == nil {
+		return Database.Model(&commentmeta).Update("meta_value", metaValue)
+	}
+	return AddCommentmeta(commentID, metaKey, metaValue)
+}
+
+// DeleteCommentmetaByKey 删除指定评论的指定数据
+func DeleteCommentmetaByKey(commentID uint64, metaKey string) (db *gorm.DB) {
+	db = Database.Delete(Commentmeta{}, "comment_id = ? and meta_key = ?", commentID, metaKey)
+	return
+}
@@ -0,0 +1,117 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/insionng/zenpress/helper"
+)
+
+// MonthArchive 按月统计的文章归档
+type MonthArchive struct {
+	Year  int
+	Month int
+	Count int
+}
+
+// YearArchive 按年统计的文章归档，Months按月份倒序排列
+type YearArchive struct {
+	Year   int
+	Count  int
+	Months []MonthArchive
+}
+
+// DateArchiveRange 获得日期归档的起止时间，month或day为0时表示整年或整月，日期无效时返回错误
+func DateArchiveRange(year, month, day int) (start, end time.Time, err error) {
+	switch {
+	case year < 1 || month < 0 || month > 12 || day < 0 || day > 31 || (month == 0 && day > 0):
+		return start, end, fmt.Errorf("无效的日期归档：%d-%d-%d", year, month, day)
+	case month == 0:
+		start = time.Date(year, 1, 1, 0, 0, 0, 0, time.Local)
+		end = start.AddDate(1, 0, 0)
+	case day == 0:
+		start = time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.Local)
+		end = start.AddDate(0, 1, 0)
+	default:
+		start = time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.Local)
+		if start.Day() != day {
+			return start, end, fmt.Errorf("无效的日期归档：%d-%d-%d", year, month, day)
+		}
+		end = start.AddDate(0, 0, 1)
+	}
+	return
+}
+
+// PageDatePosts 分页获得指定年、月、日发布的文章
+func PageDatePosts(year, month, day, pageNo, pageSize int) (helper.Page, error) {
+	start, end, err := DateArchiveRange(year, month, day)
+	if err != nil {
+		return helper.Page{}, err
+	}
+
+	var posts []Post
+	var count int
+	db := Database.Model(&Post{}).Where("post_type = ? and post_status = ? and post_date >= ? and post_date < ?", "post", PostStatusPublish, start, end)
+	db.Count(&count)
+	if pageNo < 1 {
+		pageNo = 1
+	}
+	db.Order("post_date desc").Offset((pageNo - 1) * pageSize).Limit(pageSize).Find(&posts)
+	return helper.PageUtil(count, pageNo, pageSize, posts), nil
+}
+
+// GetMonthlyArchives 获得已发布文章按月的数量，由一条聚合查询得出
+func GetMonthlyArchives() (archives []MonthArchive) {
+	var year, month string
+	switch Database.Dialect().GetName() {
+	case "mysql":
+		year, month = "YEAR(post_date)", "MONTH(post_date)"
+	case "postgres":
+		year, month = "CAST(EXTRACT(YEAR FROM post_date) AS INTEGER)", "CAST(EXTRACT(MONTH FROM post_date) AS INTEGER)"
+	default:
+		//sqlite以文本保存时间，直接截取本地时间的年月，避免strftime按时区偏移换算为UTC
+		year, month = "CAST(substr(post_date, 1, 4) AS INTEGER)", "CAST(substr(post_date, 6, 2) AS INTEGER)"
+	}
+
+	rows, err := Database.Model(&Post{}).
+		Select(fmt.Sprintf("%s AS archive_year, %s AS archive_month, COUNT(*) AS archive_count", year, month)).
+		Where("post_type = ? and post_status = ?", "post", PostStatusPublish).
+		Group(fmt.Sprintf("%s, %s", year, month)).
+		Order("archive_year desc, archive_month desc").
+		Rows()
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var archive MonthArchive
+		if rows.Scan(&archive.Year, &archive.Month, &archive.Count) == nil {
+			archives = append(archives, archive)
+		}
+	}
+	return
+}
+
+// GetYearlyArchives 获得已发布文章按年及按月的数量，用于侧边栏的归档列表
+func GetYearlyArchives() (archives []YearArchive) {
+	for _, month := range GetMonthlyArchives() {
+		if n := len(archives); n == 0 || archives[n-1].Year != month.Year {
+			archives = append(archives, YearArchive{Year: month.Year})
+		}
+		archive := &archives[len(archives)-1]
+		archive.Count += month.Count
+		archive.Months = append(archive.Months, month)
+	}
+	return
+}
+
+// GetDateArchiveLink 获得日期归档的链接，month或day为0时为年或月归档
+func GetDateArchiveLink(year, month, day int) string {
+	switch {
+	case month == 0:
+		return fmt.Sprintf("/%04d/", year)
+	case day == 0:
+		return fmt.Sprintf("/%04d/%02d/", year, month)
+	}
+	return fmt.Sprintf("/%04d/%02d/%02d/", year, month, day)
+}
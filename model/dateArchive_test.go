@@ -0,0 +1,49 @@
+package model_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/insionng/zenpress/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDateArchives(t *testing.T) {
+	assert := assert.New(t)
+	for _, date := range []time.Time{
+		time.Date(1999, 6, 1, 0, 0, 0, 0, time.Local),
+		time.Date(1999, 6, 30, 23, 59, 59, 0, time.Local),
+		time.Date(1999, 7, 1, 8, 0, 0, 0, time.Local),
+	} {
+		post := &model.Post{PostTitle: "测试日期归档", PostStatus: model.PostStatusPublish, PostDate: date}
+		if !assert.NoError(model.InsertPost(post)) {
+			return
+		}
+		defer model.RemovePost(post.ID)
+	}
+
+	page, err := model.PageDatePosts(1999, 6, 0, 1, 10)
+	if assert.NoError(err) {
+		assert.Equal(2, page.TotalCount)
+	}
+	page, _ = model.PageDatePosts(1999, 0, 0, 1, 10)
+	assert.Equal(3, page.TotalCount)
+	page, _ = model.PageDatePosts(1999, 6, 30, 1, 10)
+	assert.Equal(1, page.TotalCount)
+	_, err = model.PageDatePosts(1999, 2, 30, 1, 10)
+	assert.Error(err)
+
+	var year *model.YearArchive
+	archives := model.GetYearlyArchives()
+	for i := range archives {
+		if archives[i].Year == 1999 {
+			year = &archives[i]
+		}
+	}
+	if assert.NotNil(year) {
+		assert.Equal(3, year.Count)
+		assert.Equal([]model.MonthArchive{{Year: 1999, Month: 7, Count: 1}, {Year: 1999, Month: 6, Count: 2}}, year.Months)
+	}
+	assert.Equal("/1999/06/", model.GetDateArchiveLink(1999, 6, 0))
+}
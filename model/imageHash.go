@@ -0,0 +1,319 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"image"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/insionng/zenpress/helper"
+)
+
+const (
+	// AttachmentPHAMetaKey 图片感知哈希保存在Postmeta中的键名，值为64位的0、1字符串
+	AttachmentPHAMetaKey = "_attachment_pha"
+	// DuplicateImageDistanceOption 判定为相似图片的最大汉明距离保存在选项中的键名
+	DuplicateImageDistanceOption = "duplicate_image_distance"
+)
+
+var (
+	// DefaultDuplicateImageDistance 未设置选项时判定为相似图片的最大汉明距离
+	DefaultDuplicateImageDistance = 5
+
+	// ErrUploadFileAttached 文件已关联附件
+	ErrUploadFileAttached = errors.New("文件已关联附件，请在媒体库中删除")
+)
+
+// DuplicateAttachment 与指定图片相似的附件
+type DuplicateAttachment struct {
+	Post     Post
+	Distance int
+}
+
+// DuplicateImage 上传目录中的图片，未关联附件的文件Post为nil
+type DuplicateImage struct {
+	File     string
+	URL      string
+	Size     int64
+	Hash     string
+	Post     *Post
+	Distance int
+}
+
+// DuplicateGroup 一组相似图片，Distance为与第一张图片的汉明距离
+type DuplicateGroup struct {
+	Images []*DuplicateImage
+}
+
+// GetDuplicateImageDistance 获得判定为相似图片的最大汉明距离
+func GetDuplicateImageDistance() int {
+	return optionInt(DuplicateImageDistanceOption, DefaultDuplicateImageDistance)
+}
+
+// ImagePHA 计算图片的感知哈希
+func ImagePHA(data []byte) (string, error) {
+	if _, err := helper.CheckImageSize(bytes.NewReader(data)); err != nil {
+		return "", err
+	}
+	m, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	return helper.PHA(m), nil
+}
+
+// FindDuplicateAttachments 查找与图片附件相似的其他附件，按汉明距离排序
+func FindDuplicateAttachments(id uint64) []DuplicateAttachment {
+	hash := GetPostmetaValue(id, AttachmentPHAMetaKey)
+	if len(hash) == 0 {
+		return nil
+	}
+
+	var metas []Postmeta
+	Database.Where("meta_key = ? and post_id <> ?", AttachmentPHAMetaKey, id).Find(&metas)
+	distance := GetDuplicateImageDistance()
+	var duplicates []DuplicateAttachment
+	for _, meta := range metas {
+		if d := helper.CompareDiff(hash, meta.MetaValue); d <= distance {
+			if post, err := GetAttachment(meta.PostID); err == nil {
+				duplicates = append(duplicates, DuplicateAttachment{Post: *post, Distance: d})
+			}
+		}
+	}
+	sort.SliceStable(duplicates, func(i, j int) bool { return duplicates[i].Distance < duplicates[j].Distance })
+	return duplicates
+}
+
+// DuplicateImageScan 后台扫描重复图片的状态及最近一次扫描的结果
+type DuplicateImageScan struct {
+	Running    bool
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Groups     []DuplicateGroup
+	Error      string
+}
+
+// duplicateImageBatchSize 扫描时每次查询Postmeta的附件数量
+const duplicateImageBatchSize = 500
+
+var (
+	duplicateImageScan     DuplicateImageScan
+	duplicateImageScanLock sync.Mutex
+)
+
+// StartDuplicateImageScan 在后台扫描重复图片，已有扫描在进行时返回false
+func StartDuplicateImageScan() bool {
+	duplicateImageScanLock.Lock()
+	if duplicateImageScan.Running {
+		duplicateImageScanLock.Unlock()
+		return false
+	}
+	duplicateImageScan = DuplicateImageScan{Running: true, StartedAt: time.Now()}
+	duplicateImageScanLock.Unlock()
+
+	go func() {
+		groups, err := ScanDuplicateImages()
+		duplicateImageScanLock.Lock()
+		defer duplicateImageScanLock.Unlock()
+		duplicateImageScan.Running = false
+		duplicateImageScan.FinishedAt = time.Now()
+		duplicateImageScan.Groups = groups
+		if err != nil {
+			duplicateImageScan.Error = err.Error()
+			log.Println("scan duplicate images error:", err)
+		}
+	}()
+	return true
+}
+
+// GetDuplicateImageScan 获得后台扫描重复图片的状态及最近一次扫描的结果
+func GetDuplicateImageScan() DuplicateImageScan {
+	duplicateImageScanLock.Lock()
+	defer duplicateImageScanLock.Unlock()
+	return duplicateImageScan
+}
+
+// ScanDuplicateImages 扫描上传目录中的全部图片并将相似图片分组，按图片尺寸生成的图片不参与比较，缺少感知哈希的附件将补充保存
+func ScanDuplicateImages() ([]DuplicateGroup, error) {
+	if !helper.IsExist(UploadDir) {
+		return nil, nil
+	}
+
+	var posts []Post
+	Database.Where("post_type = ? and post_mime_type like ?", AttachmentPostType, "image/%").Find(&posts)
+	byID := make(map[uint64]*Post, len(posts))
+	ids := make([]uint64, 0, len(posts))
+	for i := range posts {
+		byID[posts[i].ID] = &posts[i]
+		ids = append(ids, posts[i].ID)
+	}
+
+	attached := map[string]*Post{}
+	derived := map[string]bool{}
+	hashes := map[uint64]string{}
+	keys := []string{AttachedFileMetaKey, AttachmentMetadataMetaKey, AttachmentPHAMetaKey}
+	for start := 0; start < len(ids); start += duplicateImageBatchSize {
+		end := start + duplicateImageBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		var metas []Postmeta
+		Database.Where("post_id in (?) and meta_key in (?)", ids[start:end], keys).Find(&metas)
+		for _, meta := range metas {
+			switch meta.MetaKey {
+			case AttachedFileMetaKey:
+				attached[meta.MetaValue] = byID[meta.PostID]
+			case AttachmentMetadataMetaKey:
+				var metadata AttachmentMetadata
+				if json.Unmarshal([]byte(meta.MetaValue), &metadata) == nil {
+					for _, size := range metadata.Sizes {
+						derived[size.File] = true
+					}
+				}
+			case AttachmentPHAMetaKey:
+				hashes[meta.PostID] = meta.MetaValue
+			}
+		}
+	}
+
+	var images []*DuplicateImage
+	err := filepath.Walk(UploadDir, func(name string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(UploadDir, name)
+		if err != nil {
+			return err
+		}
+		file := filepath.ToSlash(rel)
+		if derived[file] || !strings.HasPrefix(UploadMimeTypes[strings.ToLower(filepath.Ext(file))], "image/") {
+			return nil
+		}
+
+		img := &DuplicateImage{File: file, URL: UploadURLPrefix + file, Size: info.Size(), Post: attached[file]}
+		if img.Post != nil {
+			img.Hash = hashes[img.Post.ID]
+		}
+		if len(img.Hash) == 0 {
+			data, err := ioutil.ReadFile(name)
+			if err != nil {
+				return err
+			}
+			if img.Hash, err = ImagePHA(data); err != nil {
+				return nil
+			}
+			if img.Post != nil {
+				SetPostmetaValue(img.Post.ID, AttachmentPHAMetaKey, img.Hash)
+			}
+		}
+		images = append(images, img)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return groupDuplicateImages(images, GetDuplicateImageDistance()), nil
+}
+
+// RemoveUploadFile 删除上传目录中未关联附件的文件，已关联附件的文件及附件按尺寸生成的文件须通过删除附件来删除
+func RemoveUploadFile(file string) error {
+	file = path.Clean("/" + file)[1:]
+	if len(file) == 0 {
+		return ErrUploadFileAttached
+	}
+	var count int
+	Database.Model(&Postmeta{}).Where("meta_key = ? and meta_value = ?", AttachedFileMetaKey, file).Count(&count)
+	if count > 0 {
+		return ErrUploadFileAttached
+	}
+	//按尺寸生成的文件记录在附件元数据中，文件名中的通配符只会多匹配，不会误删
+	name, _ := json.Marshal(file)
+	Database.Model(&Postmeta{}).Where("meta_key = ? and meta_value like ?", AttachmentMetadataMetaKey, "%\"file\":"+string(name)+"%").Count(&count)
+	if count > 0 {
+		return ErrUploadFileAttached
+	}
+	return os.Remove(filepath.Join(UploadDir, filepath.FromSlash(file)))
+}
+
+// groupDuplicateImages 将汉明距离不超过distance的图片归为一组，相似关系可传递
+func groupDuplicateImages(images []*DuplicateImage, distance int) []DuplicateGroup {
+	parent := make([]int, len(images))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	union := func(i, j int) {
+		if ri, rj := find(i), find(j); ri != rj && helper.CompareDiff(images[i].Hash, images[j].Hash) <= distance {
+			parent[rj] = ri
+		}
+	}
+
+	//按鸽巢原理把哈希分成distance+1段，汉明距离不超过distance的两个哈希至少有一段相同，只比较有相同段的图片
+	segments := distance + 1
+	buckets := map[string][]int{}
+	var unsplit []int
+	for i, img := range images {
+		if segments > len(img.Hash) {
+			unsplit = append(unsplit, i)
+			continue
+		}
+		for k := 0; k < segments; k++ {
+			key := strconv.Itoa(len(img.Hash)) + ":" + strconv.Itoa(k) + ":" + img.Hash[k*len(img.Hash)/segments:(k+1)*len(img.Hash)/segments]
+			buckets[key] = append(buckets[key], i)
+		}
+	}
+	for _, bucket := range buckets {
+		for a := range bucket {
+			for b := a + 1; b < len(bucket); b++ {
+				union(bucket[a], bucket[b])
+			}
+		}
+	}
+	//哈希位数不足以分段时只能逐一比较
+	for _, i := range unsplit {
+		for j := range images {
+			if j != i {
+				union(i, j)
+			}
+		}
+	}
+
+	members := map[int][]*DuplicateImage{}
+	var roots []int
+	for i, img := range images {
+		root := find(i)
+		if _, okay := members[root]; !okay {
+			roots = append(roots, root)
+		}
+		members[root] = append(members[root], img)
+	}
+
+	var groups []DuplicateGroup
+	for _, root := range roots {
+		group := members[root]
+		if len(group) < 2 {
+			continue
+		}
+		for _, img := range group {
+			img.Distance = helper.CompareDiff(group[0].Hash, img.Hash)
+		}
+		groups = append(groups, DuplicateGroup{Images: group})
+	}
+	return groups
+}
@@ -0,0 +1,111 @@
+package model_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/insionng/zenpress/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// gradientImage 生成水平渐变的灰度图片，reverse为真时方向相反
+func gradientImage(reverse bool) image.Image {
+	m := image.NewGray(image.Rect(0, 0, 64, 64))
+	for x := 0; x < 64; x++ {
+		v := uint8(x * 4)
+		if reverse {
+			v = 255 - v
+		}
+		for y := 0; y < 64; y++ {
+			m.SetGray(x, y, color.Gray{Y: v})
+		}
+	}
+	return m
+}
+
+func TestDuplicateImages(t *testing.T) {
+	assert := assert.New(t)
+	var original, copied, different bytes.Buffer
+	png.Encode(&original, gradientImage(false))
+	jpeg.Encode(&copied, gradientImage(false), &jpeg.Options{Quality: 60})
+	png.Encode(&different, gradientImage(true))
+	orphan := append([]byte{}, original.Bytes()...)
+
+	var posts []*model.Post
+	for _, upload := range []struct {
+		name string
+		buf  *bytes.Buffer
+	}{{"gradient.png", &original}, {"gradient.jpg", &copied}, {"reverse.png", &different}} {
+		post, err := model.InsertAttachment(upload.name, upload.buf, 0, 0)
+		if !assert.NoError(err) {
+			return
+		}
+		defer model.DeleteAttachment(post.ID)
+		assert.Len(model.GetPostmetaValue(post.ID, model.AttachmentPHAMetaKey), 64)
+		posts = append(posts, post)
+	}
+	png1, jpg, reverse := posts[0], posts[1], posts[2]
+
+	duplicates := model.FindDuplicateAttachments(jpg.ID)
+	if assert.Len(duplicates, 1) {
+		assert.Equal(png1.ID, duplicates[0].Post.ID)
+		assert.True(duplicates[0].Distance <= model.DefaultDuplicateImageDistance)
+	}
+	assert.Empty(model.FindDuplicateAttachments(reverse.ID))
+
+	dir := filepath.Join(model.UploadDir, "orphan-test")
+	os.MkdirAll(dir, 0755)
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "copy.png"), orphan, 0644)
+
+	groups, err := model.ScanDuplicateImages()
+	if assert.NoError(err) && assert.Len(groups, 1) {
+		images := groups[0].Images
+		assert.Len(images, 3)
+		var orphans int
+		for _, img := range images {
+			assert.NotEqual(model.GetAttachmentURL(reverse), img.URL)
+			if img.Post == nil {
+				orphans++
+				assert.Equal("orphan-test/copy.png", img.File)
+			}
+		}
+		assert.Equal(1, orphans)
+	}
+
+	assert.Equal(model.ErrUploadFileAttached, model.RemoveUploadFile(model.GetPostmetaValue(png1.ID, model.AttachedFileMetaKey)))
+	assert.NoError(model.RemoveUploadFile("orphan-test/copy.png"))
+	assert.False(fileExists(filepath.Join(dir, "copy.png")))
+
+	//附件按尺寸生成的文件不能单独删除
+	ioutil.WriteFile(filepath.Join(dir, "derived.png"), orphan, 0644)
+	if meta, err := model.GetAttachmentMetadata(png1.ID); assert.NoError(err) {
+		meta.Sizes = map[string]*model.AttachmentSize{"test": {File: "orphan-test/derived.png"}}
+		assert.NoError(model.SetAttachmentMetadata(png1.ID, meta))
+	}
+	assert.Equal(model.ErrUploadFileAttached, model.RemoveUploadFile("orphan-test/derived.png"))
+	assert.True(fileExists(filepath.Join(dir, "derived.png")))
+
+	if assert.True(model.StartDuplicateImageScan()) {
+		for model.GetDuplicateImageScan().Running {
+			time.Sleep(10 * time.Millisecond)
+		}
+		scan := model.GetDuplicateImageScan()
+		assert.Empty(scan.Error)
+		assert.Len(scan.Groups, 1)
+	}
+}
+
+func fileExists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
@@ -0,0 +1,354 @@
+package model
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/insionng/zenpress/helper"
+)
+
+// ImageSizeQuality 生成JPEG尺寸图片时的质量
+var ImageSizeQuality = 90
+
+var (
+	// builtinImageSizes 内置图片尺寸，宽高及裁剪方式可通过选项 {name}_size_w、{name}_size_h 及 {name}_crop 修改
+	builtinImageSizes = []*ImageSize{
+		{Name: "thumbnail", Width: 150, Height: 150, Crop: true},
+		{Name: "medium", Width: 300, Height: 300},
+		{Name: "large", Width: 1024, Height: 1024},
+	}
+
+	imageSizes     = map[string]*ImageSize{}
+	imageSizesLock sync.RWMutex
+)
+
+// ImageSize 上传图片时生成的尺寸，Crop为真时裁剪为准确的宽高，否则等比缩放至不超过宽高，宽或高为0表示不限制
+type ImageSize struct {
+	Name   string `json:"name"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Crop   bool   `json:"crop"`
+}
+
+// AttachmentSize 按图片尺寸生成的文件，File为相对于上传目录的路径
+type AttachmentSize struct {
+	File     string `json:"file"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+	MimeType string `json:"mime_type"`
+}
+
+// RegisterImageSize 注册图片尺寸，主题可在theme.json的image_sizes中声明，重复注册同名尺寸将覆盖原有设置
+func RegisterImageSize(name string, width, height int, crop bool) error {
+	if !postTypeNameRegexp.MatchString(name) || name == "full" {
+		return fmt.Errorf("图片尺寸名称[%s]无效", name)
+	}
+	if isBuiltinImageSize(name) {
+		return fmt.Errorf("内置图片尺寸[%s]请通过选项修改", name)
+	}
+	if width < 0 || height < 0 || width == 0 && height == 0 {
+		return fmt.Errorf("图片尺寸[%s]的宽高无效", name)
+	}
+
+	imageSizesLock.Lock()
+	defer imageSizesLock.Unlock()
+	imageSizes[name] = &ImageSize{Name: name, Width: width, Height: height, Crop: crop}
+	resetImageTransformSizes()
+	return nil
+}
+
+// UnregisterImageSize 注销图片尺寸，已生成的图片不会被删除
+func UnregisterImageSize(name string) {
+	imageSizesLock.Lock()
+	defer imageSizesLock.Unlock()
+	delete(imageSizes, name)
+	resetImageTransformSizes()
+}
+
+// SetBuiltinImageSize 修改内置图片尺寸的宽高及裁剪方式，保存在选项中
+func SetBuiltinImageSize(name string, width, height int, crop bool) error {
+	if !isBuiltinImageSize(name) {
+		return fmt.Errorf("图片尺寸[%s]不是内置尺寸", name)
+	}
+	if width < 0 || height < 0 {
+		return fmt.Errorf("图片尺寸[%s]的宽高无效", name)
+	}
+	for key, value := range map[string]string{
+		name + "_size_w": strconv.Itoa(width),
+		name + "_size_h": strconv.Itoa(height),
+		name + "_crop":   strconv.FormatBool(crop),
+	} {
+		if err := SetOptionValue(key, value).Error; err != nil {
+			return err
+		}
+	}
+	resetImageTransformSizes()
+	return nil
+}
+
+// GetImageSize 获得图片尺寸，内置尺寸的宽高以选项中的设置为准
+func GetImageSize(name string) (*ImageSize, bool) {
+	for _, size := range builtinImageSizes {
+		if size.Name == name {
+			crop, err := strconv.ParseBool(GetOptionValue(name + "_crop"))
+			if err != nil {
+				crop = size.Crop
+			}
+			return &ImageSize{
+				Name:   name,
+				Width:  optionInt(name+"_size_w", size.Width),
+				Height: optionInt(name+"_size_h", size.Height),
+				Crop:   crop,
+			}, true
+		}
+	}
+
+	imageSizesLock.RLock()
+	defer imageSizesLock.RUnlock()
+	size, okay := imageSizes[name]
+	if !okay {
+		return nil, false
+	}
+	copied := *size
+	return &copied, true
+}
+
+// GetImageSizes 获得全部图片尺寸，内置尺寸在前，其余按名称排序
+func GetImageSizes() []*ImageSize {
+	sizes := make([]*ImageSize, 0, len(builtinImageSizes))
+	for _, size := range builtinImageSizes {
+		s, _ := GetImageSize(size.Name)
+		sizes = append(sizes, s)
+	}
+
+	imageSizesLock.RLock()
+	var names []string
+	for name := range imageSizes {
+		names = append(names, name)
+	}
+	imageSizesLock.RUnlock()
+	sort.Strings(names)
+	for _, name := range names {
+		if s, okay := GetImageSize(name); okay {
+			sizes = append(sizes, s)
+		}
+	}
+	return sizes
+}
+
+// ImageSizeDimensions 计算原图按尺寸处理后的宽高，原图不大于该尺寸时无需生成，返回false
+func ImageSizeDimensions(width, height int, size *ImageSize) (int, int, bool) {
+	if width <= 0 || height <= 0 || size.Width <= 0 && size.Height <= 0 {
+		return 0, 0, false
+	}
+
+	var w, h int
+	if size.Crop && size.Width > 0 && size.Height > 0 {
+		w, h = size.Width, size.Height
+		if w > width {
+			w = width
+		}
+		if h > height {
+			h = height
+		}
+	} else {
+		scale := math.Inf(1)
+		if size.Width > 0 {
+			scale = float64(size.Width) / float64(width)
+		}
+		if size.Height > 0 {
+			scale = math.Min(scale, float64(size.Height)/float64(height))
+		}
+		if scale >= 1 {
+			return 0, 0, false
+		}
+		w = int(math.Max(1, math.Round(float64(width)*scale)))
+		h = int(math.Max(1, math.Round(float64(height)*scale)))
+	}
+	if w == width && h == height {
+		return 0, 0, false
+	}
+	return w, h, true
+}
+
+// RegenerateImageSizes 删除图片附件已生成的尺寸并按当前注册的尺寸重新生成
+func RegenerateImageSizes(id uint64) (*AttachmentMetadata, error) {
+	meta, err := GetAttachmentMetadata(id)
+	if err != nil {
+		return nil, err
+	}
+	for _, size := range meta.Sizes {
+		if err := os.Remove(filepath.Join(UploadDir, filepath.FromSlash(size.File))); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	meta.Sizes = nil
+
+	data, err := ioutil.ReadFile(GetAttachedFile(id))
+	if err != nil {
+		return nil, err
+	}
+	if err := makeImageSizes(meta, data); err != nil {
+		return nil, err
+	}
+	return meta, SetAttachmentMetadata(id, meta)
+}
+
+// GetAttachmentImageSrc 获得图片附件指定尺寸的地址及宽高，尺寸不存在或为full时返回原图
+func GetAttachmentImageSrc(id uint64, size string) (string, int, int) {
+	meta, err := GetAttachmentMetadata(id)
+	if err != nil || len(meta.File) == 0 {
+		return "", 0, 0
+	}
+	if s, okay := meta.Sizes[size]; okay {
+		return UploadURLPrefix + s.File, s.Width, s.Height
+	}
+	return UploadURLPrefix + meta.File, meta.Width, meta.Height
+}
+
+// GetAttachmentImageSrcset 获得与指定尺寸宽高比一致的全部图片，用作img标签的srcset属性，少于两张时返回空字符串
+func GetAttachmentImageSrcset(id uint64, size string) string {
+	meta, err := GetAttachmentMetadata(id)
+	if err != nil || meta.Width == 0 || meta.Height == 0 {
+		return ""
+	}
+	_, width, height := GetAttachmentImageSrc(id, size)
+
+	candidates := map[int]string{meta.Width: UploadURLPrefix + meta.File}
+	for _, s := range meta.Sizes {
+		if _, okay := candidates[s.Width]; !okay && imageRatioMatches(width, height, s.Width, s.Height) {
+			candidates[s.Width] = UploadURLPrefix + s.File
+		}
+	}
+	if !imageRatioMatches(width, height, meta.Width, meta.Height) {
+		delete(candidates, meta.Width)
+	}
+	if len(candidates) < 2 {
+		return ""
+	}
+
+	var widths []int
+	for w := range candidates {
+		widths = append(widths, w)
+	}
+	sort.Ints(widths)
+	srcset := make([]string, len(widths))
+	for i, w := range widths {
+		srcset[i] = fmt.Sprintf("%s %dw", candidates[w], w)
+	}
+	return strings.Join(srcset, ", ")
+}
+
+// GetAttachmentImageSizes 获得img标签的sizes属性，页面宽度不足时占满视口，否则按图片宽度显示
+func GetAttachmentImageSizes(id uint64, size string) string {
+	_, width, _ := GetAttachmentImageSrc(id, size)
+	if width == 0 {
+		return ""
+	}
+	return fmt.Sprintf("(max-width: %dpx) 100vw, %dpx", width, width)
+}
+
+// GetAttachmentImage 获得图片附件指定尺寸的img标签，包含srcset及sizes属性，非图片附件返回空字符串
+func GetAttachmentImage(id uint64, size string) string {
+	post, err := GetAttachment(id)
+	if err != nil || !IsImageAttachment(post) {
+		return ""
+	}
+	src, width, height := GetAttachmentImageSrc(id, size)
+	if len(src) == 0 {
+		return ""
+	}
+
+	alt := GetPostmetaValue(id, AttachmentAltMetaKey)
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<img src="%s"`, html.EscapeString(src))
+	if width > 0 && height > 0 {
+		fmt.Fprintf(&buf, ` width="%d" height="%d"`, width, height)
+	}
+	fmt.Fprintf(&buf, ` class="attachment-%s size-%s" alt="%s"`, html.EscapeString(size), html.EscapeString(size), html.EscapeString(alt))
+	if srcset := GetAttachmentImageSrcset(id, size); len(srcset) > 0 {
+		fmt.Fprintf(&buf, ` srcset="%s" sizes="%s"`, html.EscapeString(srcset), GetAttachmentImageSizes(id, size))
+	}
+	buf.WriteString(">")
+	return buf.String()
+}
+
+// makeImageSizes 按已注册的图片尺寸生成缩放后的图片，文件名为原文件名加上宽高后缀
+func makeImageSizes(meta *AttachmentMetadata, data []byte) error {
+	switch meta.MimeType {
+	case "image/jpeg", "image/png", "image/gif":
+	default:
+		return nil
+	}
+
+	ext := path.Ext(meta.File)
+	base := strings.TrimSuffix(meta.File, ext)
+	made := map[string]*AttachmentSize{}
+	for _, size := range GetImageSizes() {
+		w, h, okay := ImageSizeDimensions(meta.Width, meta.Height, size)
+		if !okay {
+			continue
+		}
+		file := fmt.Sprintf("%s-%dx%d%s", base, w, h, ext)
+		if s, done := made[file]; done {
+			meta.setSize(size.Name, s)
+			continue
+		}
+
+		var buf bytes.Buffer
+		if err := helper.ResizeImage(bytes.NewReader(data), &buf, w, h, ImageSizeQuality, size.Crop); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filepath.Join(UploadDir, filepath.FromSlash(file)), buf.Bytes(), 0644); err != nil {
+			return err
+		}
+		made[file] = &AttachmentSize{File: file, Width: w, Height: h, MimeType: meta.MimeType}
+		meta.setSize(size.Name, made[file])
+	}
+	return nil
+}
+
+func (meta *AttachmentMetadata) setSize(name string, size *AttachmentSize) {
+	if meta.Sizes == nil {
+		meta.Sizes = map[string]*AttachmentSize{}
+	}
+	meta.Sizes[name] = size
+}
+
+// imageRatioMatches 两组宽高的比例是否一致，允许缩放取整带来的1像素误差
+func imageRatioMatches(width, height, otherWidth, otherHeight int) bool {
+	if width == 0 || height == 0 || otherWidth == 0 || otherHeight == 0 {
+		return false
+	}
+	if otherWidth > width {
+		width, height, otherWidth, otherHeight = otherWidth, otherHeight, width, height
+	}
+	expected := float64(height) * float64(otherWidth) / float64(width)
+	return math.Abs(expected-float64(otherHeight)) <= 1
+}
+
+func isBuiltinImageSize(name string) bool {
+	for _, size := range builtinImageSizes {
+		if size.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func optionInt(key string, defaultValue int) int {
+	if n, err := strconv.Atoi(GetOptionValue(key)); err == nil && n >= 0 {
+		return n
+	}
+	return defaultValue
+}
@@ -0,0 +1,106 @@
+package model_test
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/insionng/zenpress/helper"
+	"github.com/insionng/zenpress/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImageSizeDimensions(t *testing.T) {
+	assert := assert.New(t)
+	for _, c := range []struct {
+		width, height int
+		size          model.ImageSize
+		w, h          int
+		okay          bool
+	}{
+		{1200, 800, model.ImageSize{Width: 150, Height: 150, Crop: true}, 150, 150, true},
+		{1200, 800, model.ImageSize{Width: 300, Height: 300}, 300, 200, true},
+		{1200, 800, model.ImageSize{Width: 760}, 760, 507, true},
+		{100, 80, model.ImageSize{Width: 150, Height: 150, Crop: true}, 100, 80, false},
+		{200, 100, model.ImageSize{Width: 150, Height: 150, Crop: true}, 150, 100, true},
+		{1200, 800, model.ImageSize{Width: 2048, Height: 2048}, 0, 0, false},
+	} {
+		w, h, okay := model.ImageSizeDimensions(c.width, c.height, &c.size)
+		assert.Equal(c.okay, okay, "%+v", c)
+		if c.okay {
+			assert.Equal([]int{c.w, c.h}, []int{w, h}, "%+v", c)
+		}
+	}
+}
+
+func TestAttachmentImageSizes(t *testing.T) {
+	assert := assert.New(t)
+	assert.Error(model.RegisterImageSize("thumbnail", 10, 10, false), "builtin sizes are changed by options")
+	if !assert.NoError(model.RegisterImageSize("test-wide", 600, 0, false)) {
+		return
+	}
+	defer model.UnregisterImageSize("test-wide")
+
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 1200, 800)))
+	post, err := model.InsertAttachment("sizes.png", &buf, 0, 0)
+	if !assert.NoError(err) {
+		return
+	}
+	defer model.DeleteAttachment(post.ID)
+
+	meta, _ := model.GetAttachmentMetadata(post.ID)
+	if assert.Equal(4, len(meta.Sizes)) {
+		assert.Equal(150, meta.Sizes["thumbnail"].Height)
+		assert.Equal(200, meta.Sizes["medium"].Height)
+		assert.Equal(683, meta.Sizes["large"].Height)
+		assert.Equal(400, meta.Sizes["test-wide"].Height)
+	}
+	thumb := model.GetAttachedFile(post.ID)
+	thumb = strings.TrimSuffix(thumb, ".png") + "-150x150.png"
+	_, err = os.Stat(thumb)
+	assert.NoError(err)
+
+	src, w, h := model.GetAttachmentImageSrc(post.ID, "medium")
+	assert.True(strings.HasSuffix(src, "-300x200.png"))
+	assert.Equal([]int{300, 200}, []int{w, h})
+
+	srcset := model.GetAttachmentImageSrcset(post.ID, "medium")
+	assert.Equal(4, len(strings.Split(srcset, ", ")), "thumbnail is excluded for its different ratio")
+	assert.True(strings.HasSuffix(srcset, " 1200w"))
+	assert.Equal("", model.GetAttachmentImageSrcset(post.ID, "thumbnail"))
+
+	img := model.GetAttachmentImage(post.ID, "large")
+	assert.Contains(img, `width="1024" height="683"`)
+	assert.Contains(img, `sizes="(max-width: 1024px) 100vw, 1024px"`)
+
+	model.UnregisterImageSize("test-wide")
+	meta, err = model.RegenerateImageSizes(post.ID)
+	if assert.NoError(err) {
+		assert.Equal(3, len(meta.Sizes))
+	}
+
+	assert.NoError(model.DeleteAttachment(post.ID))
+	_, err = os.Stat(thumb)
+	assert.True(os.IsNotExist(err))
+}
+
+func TestImageSizesTooLarge(t *testing.T) {
+	pixels := helper.ImageMaxPixels
+	helper.ImageMaxPixels = 100 * 100
+	defer func() { helper.ImageMaxPixels = pixels }()
+
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewGray(image.Rect(0, 0, 400, 300)))
+	post, err := model.InsertAttachment("bomb.png", bytes.NewReader(buf.Bytes()), 0, 0)
+	if post != nil {
+		defer model.DeleteAttachment(post.ID)
+	}
+	assert.Equal(t, helper.ErrImageTooLarge, err, "images should be measured before they are decoded")
+	_, err = model.ImagePHA(buf.Bytes())
+	assert.Equal(t, helper.ErrImageTooLarge, err)
+}
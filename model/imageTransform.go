@@ -0,0 +1,274 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/insionng/zenpress/helper"
+)
+
+const (
+	// ImageTransformSizesOption 无需签名即可访问的处理尺寸，多个尺寸以逗号分隔，如 300x200,600x0，已注册的图片尺寸始终允许
+	ImageTransformSizesOption = "image_transform_sizes"
+	// ImageTransformURLPrefix 图片处理的访问路径，完整路径为 /img/<宽>x<高>/<模式>/<文件>
+	ImageTransformURLPrefix = "/img/"
+)
+
+var (
+	// ImageCacheDir 处理后图片的缓存目录
+	ImageCacheDir = "content/storage/cache/img"
+	// ImageWatermarkFile watermark模式使用的水印图片，不存在时仅等比缩放
+	ImageWatermarkFile = "content/storage/watermark.png"
+	// ImageTransformMaxSize 处理后图片的最大宽高
+	ImageTransformMaxSize = 4096
+	// ImageTransformModes 支持的处理模式
+	ImageTransformModes = []string{"fit", "crop", "resize", "watermark"}
+	// ImageTransformFormats 可转换的输出格式，在源文件路径后追加扩展名即可转换格式，如 photo.png.jpg
+	ImageTransformFormats = map[string]string{".jpg": "jpeg", ".jpeg": "jpeg", ".png": "png", ".gif": "gif"}
+
+	// ErrImageTransformInvalid 图片处理参数无效或源文件不存在
+	ErrImageTransformInvalid = errors.New("图片处理参数无效")
+	// ErrImageTransformForbidden 处理尺寸不在允许列表中且签名无效
+	ErrImageTransformForbidden = errors.New("图片处理尺寸不在允许列表中且签名无效")
+
+	imageTransformSizeRegexp = regexp.MustCompile(`^(\d{1,5})x(\d{1,5})$`)
+
+	// imageTransformLocks 正在生成的缓存文件的锁，无人等待时删除
+	imageTransformLocks     = map[string]*imageTransformLock{}
+	imageTransformLocksLock sync.Mutex
+
+	// imageTransformSizes 无需签名的处理尺寸，修改设置或图片尺寸时清除
+	imageTransformSizes     map[string]bool
+	imageTransformSizesLock sync.RWMutex
+)
+
+// imageTransformLock 同一缓存文件只由一个请求生成，refs为持有及等待该锁的请求数
+type imageTransformLock struct {
+	sync.Mutex
+	refs int
+}
+
+// ImageTransform 图片处理请求，File为相对于上传目录的源文件路径，Format为输出文件的扩展名，为空时保持原格式
+type ImageTransform struct {
+	Width  int
+	Height int
+	Mode   string
+	File   string
+	Format string
+}
+
+// Path 获得处理请求在/img/之后的路径
+func (t *ImageTransform) Path() string {
+	return fmt.Sprintf("%dx%d/%s/%s%s", t.Width, t.Height, t.Mode, t.File, t.Format)
+}
+
+// Allowed 处理尺寸是否在允许列表中
+func (t *ImageTransform) Allowed() bool {
+	return allowedImageTransformSizes()[fmt.Sprintf("%dx%d", t.Width, t.Height)]
+}
+
+// SetImageTransformSizes 设置无需签名即可访问的处理尺寸，多个尺寸以逗号分隔
+func SetImageTransformSizes(sizes string) error {
+	var list []string
+	for _, s := range strings.Split(sizes, ",") {
+		if s = strings.TrimSpace(s); len(s) == 0 {
+			continue
+		}
+		if !imageTransformSizeRegexp.MatchString(s) {
+			return fmt.Errorf("处理尺寸[%s]无效，格式为 宽x高", s)
+		}
+		list = append(list, s)
+	}
+	defer resetImageTransformSizes()
+	return SetOptionValue(ImageTransformSizesOption, strings.Join(list, ",")).Error
+}
+
+// allowedImageTransformSizes 获得设置中及已注册的图片尺寸，缓存至设置或图片尺寸修改
+func allowedImageTransformSizes() map[string]bool {
+	imageTransformSizesLock.RLock()
+	allowed := imageTransformSizes
+	imageTransformSizesLock.RUnlock()
+	if allowed != nil {
+		return allowed
+	}
+
+	allowed = map[string]bool{}
+	for _, s := range strings.Split(GetOptionValue(ImageTransformSizesOption), ",") {
+		if s = strings.TrimSpace(s); len(s) > 0 {
+			allowed[s] = true
+		}
+	}
+	for _, s := range GetImageSizes() {
+		allowed[fmt.Sprintf("%dx%d", s.Width, s.Height)] = true
+	}
+	imageTransformSizesLock.Lock()
+	imageTransformSizes = allowed
+	imageTransformSizesLock.Unlock()
+	return allowed
+}
+
+// resetImageTransformSizes 清除无需签名的处理尺寸缓存
+func resetImageTransformSizes() {
+	imageTransformSizesLock.Lock()
+	imageTransformSizes = nil
+	imageTransformSizesLock.Unlock()
+}
+
+// URL 获得处理后图片的访问地址，尺寸不在允许列表中时附带签名
+func (t *ImageTransform) URL() string {
+	p := t.Path()
+	if t.Allowed() {
+		return ImageTransformURLPrefix + p
+	}
+	return ImageTransformURLPrefix + p + "?sig=" + SignValue(p)
+}
+
+// CacheFile 获得处理后图片的缓存文件，同一源文件的缓存保存在同一目录下
+func (t *ImageTransform) CacheFile() string {
+	ext := t.Format
+	if len(ext) == 0 {
+		ext = path.Ext(t.File)
+	}
+	return filepath.Join(ImageCacheDir, filepath.FromSlash(t.File), fmt.Sprintf("%dx%d-%s%s", t.Width, t.Height, t.Mode, strings.ToLower(ext)))
+}
+
+// ImageTransformURL 获得上传图片按尺寸及模式处理后的访问地址，file可为上传文件的访问地址或相对于上传目录的路径，format为输出格式的扩展名
+func ImageTransformURL(file string, width, height int, mode string, format ...string) string {
+	t := &ImageTransform{Width: width, Height: height, Mode: mode, File: strings.TrimPrefix(file, UploadURLPrefix)}
+	if len(format) > 0 && !strings.EqualFold(format[0], path.Ext(t.File)) {
+		t.Format = format[0]
+	}
+	return t.URL()
+}
+
+// ParseImageTransform 解析并校验图片处理请求，尺寸不在允许列表中时须提供有效的签名
+func ParseImageTransform(size, mode, file, signature string) (*ImageTransform, error) {
+	m := imageTransformSizeRegexp.FindStringSubmatch(size)
+	if m == nil {
+		return nil, ErrImageTransformInvalid
+	}
+	t := &ImageTransform{Mode: mode}
+	t.Width, _ = strconv.Atoi(m[1])
+	t.Height, _ = strconv.Atoi(m[2])
+	if t.Width > ImageTransformMaxSize || t.Height > ImageTransformMaxSize || !helper.IsContainsSets(ImageTransformModes, mode) {
+		return nil, ErrImageTransformInvalid
+	}
+
+	file = path.Clean("/" + file)[1:]
+	if !isTransformableImage(file) {
+		//源文件不存在时尝试将最后的扩展名作为输出格式
+		ext := path.Ext(file)
+		if _, okay := ImageTransformFormats[strings.ToLower(ext)]; !okay || !isTransformableImage(strings.TrimSuffix(file, ext)) {
+			return nil, ErrImageTransformInvalid
+		}
+		file, t.Format = strings.TrimSuffix(file, ext), ext
+	}
+	t.File = file
+	if mode == "watermark" && t.outputFormat() == "gif" {
+		return nil, ErrImageTransformInvalid
+	}
+
+	if !t.Allowed() && !VerifySignature(t.Path(), signature) {
+		return nil, ErrImageTransformForbidden
+	}
+	return t, nil
+}
+
+// MakeImageTransform 生成处理后的图片并返回缓存文件，缓存比源文件新时直接返回
+func MakeImageTransform(t *ImageTransform) (string, error) {
+	source := filepath.Join(UploadDir, filepath.FromSlash(t.File))
+	cache := t.CacheFile()
+
+	defer lockImageTransform(cache)()
+
+	src, err := os.Stat(source)
+	if err != nil {
+		return "", err
+	}
+	if dst, err := os.Stat(cache); err == nil && !dst.ModTime().Before(src.ModTime()) {
+		return cache, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cache), 0755); err != nil {
+		return "", err
+	}
+	r, err := os.Open(source)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	tmp := cache + ".tmp"
+	w, err := os.Create(tmp)
+	if err != nil {
+		return "", err
+	}
+	mode := t.Mode
+	if mode == "watermark" {
+		mode = "fit"
+	}
+	err = helper.TransformImage(r, w, t.Width, t.Height, mode, ImageTransformFormats[strings.ToLower(t.Format)], ImageSizeQuality)
+	w.Close()
+	if err == nil && t.Mode == "watermark" && helper.IsExist(ImageWatermarkFile) {
+		err = helper.Watermark(ImageWatermarkFile, tmp, tmp, "SouthEast")
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	return cache, os.Rename(tmp, cache)
+}
+
+// lockImageTransform 锁定缓存文件的生成，返回解锁函数，最后一个请求解锁时删除该锁
+func lockImageTransform(cache string) func() {
+	imageTransformLocksLock.Lock()
+	lock, okay := imageTransformLocks[cache]
+	if !okay {
+		lock = new(imageTransformLock)
+		imageTransformLocks[cache] = lock
+	}
+	lock.refs++
+	imageTransformLocksLock.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+		imageTransformLocksLock.Lock()
+		if lock.refs--; lock.refs == 0 {
+			delete(imageTransformLocks, cache)
+		}
+		imageTransformLocksLock.Unlock()
+	}
+}
+
+// ClearImageCache 清除全部处理后图片的缓存
+func ClearImageCache() error {
+	return os.RemoveAll(ImageCacheDir)
+}
+
+// outputFormat 处理后图片的格式
+func (t *ImageTransform) outputFormat() string {
+	if len(t.Format) > 0 {
+		return ImageTransformFormats[strings.ToLower(t.Format)]
+	}
+	return ImageTransformFormats[strings.ToLower(path.Ext(t.File))]
+}
+
+// isTransformableImage 上传目录中是否存在可处理的图片
+func isTransformableImage(file string) bool {
+	if len(file) == 0 {
+		return false
+	}
+	if _, okay := ImageTransformFormats[strings.ToLower(path.Ext(file))]; !okay {
+		return false
+	}
+	info, err := os.Stat(filepath.Join(UploadDir, filepath.FromSlash(file)))
+	return err == nil && !info.IsDir()
+}
@@ -0,0 +1,120 @@
+package model_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/insionng/zenpress/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// parseTransformURL 按图片处理地址解析处理请求
+func parseTransformURL(link string) (*model.ImageTransform, error) {
+	u, _ := url.Parse(link)
+	parts := strings.SplitN(strings.TrimPrefix(u.Path, model.ImageTransformURLPrefix), "/", 3)
+	return model.ParseImageTransform(parts[0], parts[1], parts[2], u.Query().Get("sig"))
+}
+
+func TestImageTransform(t *testing.T) {
+	assert := assert.New(t)
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 1200, 800)))
+	post, err := model.InsertAttachment("transform.png", &buf, 0, 0)
+	if !assert.NoError(err) {
+		return
+	}
+	defer model.DeleteAttachment(post.ID)
+	meta, _ := model.GetAttachmentMetadata(post.ID)
+
+	tr, err := model.ParseImageTransform("150x150", "crop", meta.File, "")
+	if assert.NoError(err, "registered sizes need no signature") {
+		file, err := model.MakeImageTransform(tr)
+		if assert.NoError(err) {
+			f, _ := os.Open(file)
+			config, _, _ := image.DecodeConfig(f)
+			f.Close()
+			assert.Equal([]int{150, 150}, []int{config.Width, config.Height})
+		}
+	}
+
+	_, err = model.ParseImageTransform("333x222", "fit", meta.File, "")
+	assert.Equal(model.ErrImageTransformForbidden, err)
+	_, err = model.ParseImageTransform("333x222", "fit", meta.File, "0123456789abcdef0123456789abcdef")
+	assert.Equal(model.ErrImageTransformForbidden, err)
+	link := model.ImageTransformURL(post.GUID, 333, 222, "fit", ".jpg")
+	assert.Contains(link, "?sig=")
+	tr, err = parseTransformURL(link)
+	if assert.NoError(err) {
+		assert.Equal(".jpg", tr.Format)
+		file, err := model.MakeImageTransform(tr)
+		if assert.NoError(err) {
+			f, _ := os.Open(file)
+			config, format, _ := image.DecodeConfig(f)
+			f.Close()
+			assert.Equal("jpeg", format)
+			assert.Equal([]int{333, 222}, []int{config.Width, config.Height})
+		}
+	}
+
+	assert.Error(model.SetImageTransformSizes("300x0, big"))
+	assert.NoError(model.SetImageTransformSizes("300x0, 640x480"))
+	defer model.SetImageTransformSizes("")
+	assert.Equal(model.ImageTransformURLPrefix+"300x0/resize/"+meta.File, model.ImageTransformURL(meta.File, 300, 0, "resize"))
+	_, err = model.ParseImageTransform("640x480", "crop", meta.File, "")
+	assert.NoError(err)
+
+	for _, c := range [][]string{
+		{"99999x1", "fit", meta.File},
+		{"300x0", "blur", meta.File},
+		{"300x0", "fit", "../../../etc/passwd"},
+		{"300x0", "fit", meta.File + ".bmp"},
+		{"300x0", "fit", "not/exist.png"},
+	} {
+		_, err = model.ParseImageTransform(c[0], c[1], c[2], "")
+		assert.Equal(model.ErrImageTransformInvalid, err, "%v", c)
+	}
+
+	palette := color.Palette{color.Black, color.White}
+	anim := &gif.GIF{Delay: []int{10, 10}}
+	for i := 0; i < 2; i++ {
+		frame := image.NewPaletted(image.Rect(0, 0, 80, 60), palette)
+		frame.SetColorIndex(i, i, 1)
+		anim.Image = append(anim.Image, frame)
+	}
+	buf.Reset()
+	gif.EncodeAll(&buf, anim)
+	animated, err := model.InsertAttachment("anim.gif", &buf, 0, 0)
+	if assert.NoError(err) {
+		defer model.DeleteAttachment(animated.ID)
+		tr, err = parseTransformURL(model.ImageTransformURL(animated.GUID, 40, 30, "crop"))
+		if assert.NoError(err) {
+			file, err := model.MakeImageTransform(tr)
+			if assert.NoError(err) {
+				f, _ := os.Open(file)
+				g, err := gif.DecodeAll(f)
+				f.Close()
+				if assert.NoError(err) {
+					assert.Equal(2, len(g.Image), "animation frames are kept")
+					assert.Equal(40, g.Image[0].Bounds().Dx())
+				}
+			}
+		}
+		_, err = model.ParseImageTransform("40x30", "watermark", strings.TrimPrefix(animated.GUID, model.UploadURLPrefix), "")
+		assert.Equal(model.ErrImageTransformInvalid, err, "gif cannot be watermarked")
+	}
+
+	cache := tr.CacheFile()
+	_, err = os.Stat(cache)
+	assert.NoError(err)
+	assert.NoError(model.DeleteAttachment(animated.ID))
+	_, err = os.Stat(cache)
+	assert.True(os.IsNotExist(err), "cache is removed with the attachment")
+}
@@ -0,0 +1,350 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/insionng/zenpress/helper"
+	"github.com/insionng/zenpress/module/mailer"
+)
+
+const (
+	// MailTransportOption 邮件发送方式，可选 smtp、sendmail、file，未设置时已配置SMTP服务器则为smtp，否则为file
+	MailTransportOption = "mail_transport"
+	// MailFromOption 发件人，如 站点 <noreply@example.com>，未设置时以站点名称及站点域名生成
+	MailFromOption = "mail_from"
+	// MailSMTPHostOption SMTP服务器地址
+	MailSMTPHostOption = "mail_smtp_host"
+	// MailSMTPPortOption SMTP服务器端口，未设置时按加密方式使用25、587或465
+	MailSMTPPortOption = "mail_smtp_port"
+	// MailSMTPUserOption SMTP登录用户名，为空时不登录
+	MailSMTPUserOption = "mail_smtp_user"
+	// MailSMTPPasswordOption SMTP登录密码
+	MailSMTPPasswordOption = "mail_smtp_password"
+	// MailSMTPEncryptionOption SMTP连接加密方式，可选 starttls、ssl，为空时不加密
+	MailSMTPEncryptionOption = "mail_smtp_encryption"
+	// MailSendmailPathOption sendmail程序路径
+	MailSendmailPathOption = "mail_sendmail_path"
+	// MailSpoolDirOption file方式保存邮件的目录
+	MailSpoolDirOption = "mail_spool_dir"
+
+	// MailTransportSMTP 通过SMTP服务器发送
+	MailTransportSMTP = "smtp"
+	// MailTransportSendmail 通过本机sendmail程序发送
+	MailTransportSendmail = "sendmail"
+	// MailTransportFile 保存为.eml文件而不实际发送，用于开发及测试环境
+	MailTransportFile = "file"
+
+	// DefaultMailSpoolDir file方式保存邮件的默认目录
+	DefaultMailSpoolDir = "content/storage/mail"
+
+	// MailStatusPending 等待发送或等待重试
+	MailStatusPending = "pending"
+	// MailStatusSending 已被某个进程领取正在发送，领取超时后可被重新领取
+	MailStatusSending = "sending"
+	// MailStatusSent 已发送
+	MailStatusSent = "sent"
+	// MailStatusFailed 重试次数用尽后发送失败
+	MailStatusFailed = "failed"
+
+	// MailMaxAttempts 每封邮件最多尝试发送的次数
+	MailMaxAttempts = 5
+	// MailBatchSize 每次处理队列时最多发送的邮件数
+	MailBatchSize = 50
+	// MailKeepSentDays 已发送的邮件在队列中保留的天数
+	MailKeepSentDays = 30
+)
+
+var (
+	// ErrMailNotFound 队列中没有该邮件
+	ErrMailNotFound = errors.New("队列中没有该邮件")
+	// ErrInvalidMailTransport 不支持的邮件发送方式
+	ErrInvalidMailTransport = errors.New("不支持的邮件发送方式")
+
+	// MailRetryDelays 第N次发送失败后等待重试的时间，次数超出时使用最后一项
+	MailRetryDelays = []time.Duration{time.Minute, 5 * time.Minute, 30 * time.Minute, 2 * time.Hour}
+	// MailClaimTimeout 领取邮件后未完成发送时，超过此时间可被重新领取
+	MailClaimTimeout = 10 * time.Minute
+
+	mailerOverride mailer.Mailer
+	mailQueueLock  sync.Mutex
+	mailQueueOnce  sync.Once
+	mailQueueKick  = make(chan struct{}, 1)
+)
+
+// Mail 待发送的邮件，Body为HTML，Text为空时由Body生成纯文本内容，Attachments为附件的文件路径
+type Mail struct {
+	To          string
+	Subject     string
+	Body        string
+	Text        string
+	Attachments []string
+}
+
+// MailQueue 持久化的外发邮件队列，发送失败时按 MailRetryDelays 重试
+type MailQueue struct {
+	ID            uint64    `gorm:"primary_key"`
+	MailTo        string    `gorm:"not null default '' VARCHAR(255)"`
+	Subject       string    `gorm:"not null default '' VARCHAR(255)"`
+	Body          string    `gorm:"not null LONGTEXT"`
+	Text          string    `gorm:"not null LONGTEXT"`
+	Attachments   string    `gorm:"not null"`
+	Status        string    `gorm:"not null default 'pending' index VARCHAR(20)"`
+	Attempts      int       `gorm:"not null default 0"`
+	LastError     string    `gorm:"not null"`
+	NextAttemptAt time.Time `gorm:"index"`
+	CreatedAt     time.Time
+	SentAt        *time.Time
+}
+
+// SetMailer 替换按选项创建的邮件发送方式，为nil时恢复按选项创建
+func SetMailer(m mailer.Mailer) {
+	mailerOverride = m
+}
+
+// GetMailTransport 获得邮件发送方式选项，未设置时已配置SMTP服务器则为smtp，否则为file
+func GetMailTransport() string {
+	if transport := GetOptionValue(MailTransportOption); len(transport) > 0 {
+		return transport
+	}
+	if len(GetOptionValue(MailSMTPHostOption)) > 0 {
+		return MailTransportSMTP
+	}
+	return MailTransportFile
+}
+
+// GetMailer 获得当前的邮件发送方式
+func GetMailer() (mailer.Mailer, error) {
+	if mailerOverride != nil {
+		return mailerOverride, nil
+	}
+	switch GetMailTransport() {
+	case MailTransportSMTP:
+		return &mailer.SMTPMailer{
+			Host:       GetOptionValue(MailSMTPHostOption),
+			Port:       optionInt(MailSMTPPortOption, 0),
+			Username:   GetOptionValue(MailSMTPUserOption),
+			Password:   GetOptionValue(MailSMTPPasswordOption),
+			Encryption: GetOptionValue(MailSMTPEncryptionOption),
+		}, nil
+	case MailTransportSendmail:
+		return &mailer.SendmailMailer{Path: GetOptionValue(MailSendmailPathOption)}, nil
+	case MailTransportFile:
+		dir := GetOptionValue(MailSpoolDirOption)
+		if len(dir) == 0 {
+			dir = DefaultMailSpoolDir
+		}
+		return &mailer.FileMailer{Dir: dir}, nil
+	}
+	return nil, ErrInvalidMailTransport
+}
+
+// GetMailFrom 获得发件人，未设置时为站点名称及站点域名下的noreply地址
+func GetMailFrom() string {
+	if from := GetOptionValue(MailFromOption); len(from) > 0 {
+		return from
+	}
+	host := "localhost"
+	if u, err := url.Parse(GetSiteURL()); err == nil && len(u.Hostname()) > 0 {
+		host = strings.TrimPrefix(u.Hostname(), "www.")
+	}
+	return GetBlogName() + " <noreply@" + host + ">"
+}
+
+// SendMail 立即发送邮件，不经过队列
+func SendMail(mail *Mail) error {
+	m, err := GetMailer()
+	if err != nil {
+		return err
+	}
+	msg := &mailer.Message{From: GetMailFrom(), To: []string{mail.To}, Subject: mail.Subject, HTML: mail.Body, Text: mail.Text}
+	for _, path := range mail.Attachments {
+		if err = msg.AttachFile(path); err != nil {
+			return err
+		}
+	}
+	return m.Send(msg)
+}
+
+// SendTestMail 立即发送一封测试邮件，用于检查邮件设置
+func SendTestMail(to string) error {
+	return SendMail(&Mail{
+		To:      to,
+		Subject: GetBlogName() + " 测试邮件",
+		Body:    "<p>这是一封来自 " + GetBlogName() + " 的测试邮件，收到此邮件说明邮件设置正确。</p>",
+	})
+}
+
+// QueueMail 将邮件加入持久化队列，由后台任务发送，发送失败时自动重试
+func QueueMail(mail *Mail) error {
+	attachments, _ := json.Marshal(mail.Attachments)
+	item := &MailQueue{
+		MailTo:        mail.To,
+		Subject:       mail.Subject,
+		Body:          mail.Body,
+		Text:          mail.Text,
+		Attachments:   string(attachments),
+		Status:        MailStatusPending,
+		NextAttemptAt: time.Now(),
+	}
+	if err := Database.Create(item).Error; err != nil {
+		return err
+	}
+	kickMailQueue()
+	return nil
+}
+
+// StartMailQueue 启动后台任务，每隔interval及有新邮件加入时处理队列，重复调用无效
+func StartMailQueue(interval time.Duration) {
+	mailQueueOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+				case <-mailQueueKick:
+				}
+				ProcessMailQueue(time.Now())
+			}
+		}()
+	})
+}
+
+// ProcessMailQueue 发送到期的待发送邮件，失败时安排重试或在次数用尽后标记为失败，并清理过期的已发送邮件
+// 多个进程共用队列时每封邮件须先领取成功才发送，避免重复发送
+func ProcessMailQueue(now time.Time) (sent, failed int) {
+	mailQueueLock.Lock()
+	defer mailQueueLock.Unlock()
+
+	var items []MailQueue
+	Database.Where("status in (?) and next_attempt_at <= ?", []string{MailStatusPending, MailStatusSending}, now).Order("next_attempt_at, id").Limit(MailBatchSize).Find(&items)
+	for i := range items {
+		item := &items[i]
+		if !claimMail(item, now) {
+			continue
+		}
+		mail := &Mail{To: item.MailTo, Subject: item.Subject, Body: item.Body, Text: item.Text}
+		json.Unmarshal([]byte(item.Attachments), &mail.Attachments)
+
+		item.Attempts++
+		if err := SendMail(mail); err != nil {
+			log.Printf("send mail #%d to %s error (attempt %d): %v", item.ID, item.MailTo, item.Attempts, err)
+			item.LastError = err.Error()
+			if item.Attempts >= MailMaxAttempts {
+				item.Status = MailStatusFailed
+				failed++
+			} else {
+				item.Status, item.NextAttemptAt = MailStatusPending, now.Add(mailRetryDelay(item.Attempts))
+			}
+		} else {
+			sentAt := now
+			item.Status, item.LastError, item.SentAt = MailStatusSent, "", &sentAt
+			sent++
+		}
+		Database.Save(item)
+	}
+
+	Database.Where("status = ? and sent_at < ?", MailStatusSent, now.AddDate(0, 0, -MailKeepSentDays)).Delete(MailQueue{})
+	return sent, failed
+}
+
+// claimMail 以条件更新领取到期的邮件，其他进程已领取时返回false
+func claimMail(item *MailQueue, now time.Time) bool {
+	lease := now.Add(MailClaimTimeout)
+	db := Database.Model(&MailQueue{}).Where("id = ? and status in (?) and next_attempt_at <= ?", item.ID, []string{MailStatusPending, MailStatusSending}, now).
+		UpdateColumns(map[string]interface{}{"status": MailStatusSending, "next_attempt_at": lease})
+	if db.Error != nil || db.RowsAffected != 1 {
+		return false
+	}
+	item.Status, item.NextAttemptAt = MailStatusSending, lease
+	return true
+}
+
+// kickMailQueue 通知后台任务立即处理队列
+func kickMailQueue() {
+	select {
+	case mailQueueKick <- struct{}{}:
+	default:
+	}
+}
+
+func mailRetryDelay(attempts int) time.Duration {
+	if attempts > len(MailRetryDelays) {
+		attempts = len(MailRetryDelays)
+	}
+	if attempts < 1 {
+		return 0
+	}
+	return MailRetryDelays[attempts-1]
+}
+
+// GetMail 获得队列中的邮件
+func GetMail(id uint64) (*MailQueue, error) {
+	var item MailQueue
+	if Database.First(&item, id).RecordNotFound() {
+		return nil, ErrMailNotFound
+	}
+	return &item, nil
+}
+
+// RetryMail 将发送失败或等待重试的邮件重新加入队列并立即发送，重新计算重试次数
+func RetryMail(id uint64) error {
+	item, err := GetMail(id)
+	if err != nil {
+		return err
+	}
+	if item.Status == MailStatusSent || item.Status == MailStatusSending {
+		return nil
+	}
+	item.Status, item.Attempts, item.NextAttemptAt = MailStatusPending, 0, time.Now()
+	if err = Database.Save(item).Error; err != nil {
+		return err
+	}
+	kickMailQueue()
+	return nil
+}
+
+// DeleteMail 从队列中删除邮件
+func DeleteMail(id uint64) error {
+	return Database.Where("id = ?", id).Delete(MailQueue{}).Error
+}
+
+// CountMailQueue 获得队列中各状态的邮件数
+func CountMailQueue() map[string]int {
+	counts := map[string]int{MailStatusPending: 0, MailStatusSending: 0, MailStatusSent: 0, MailStatusFailed: 0}
+	rows, err := Database.Model(&MailQueue{}).Select("status, count(*)").Group("status").Rows()
+	if err != nil {
+		return counts
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var status string
+		var count int
+		if rows.Scan(&status, &count) == nil {
+			counts[status] = count
+		}
+	}
+	return counts
+}
+
+// PageMailQueue 分页获得队列中的邮件，status为空时不限状态
+func PageMailQueue(status string, pageNo, pageSize int) helper.Page {
+	var items []MailQueue
+	var count int
+	db := Database.Model(&MailQueue{})
+	if len(status) > 0 {
+		db = db.Where("status = ?", status)
+	}
+	db.Count(&count)
+	if pageNo < 1 {
+		pageNo = 1
+	}
+	db.Order("id desc").Offset((pageNo - 1) * pageSize).Limit(pageSize).Find(&items)
+	return helper.PageUtil(count, pageNo, pageSize, items)
+}
@@ -0,0 +1,165 @@
+package model_test
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/insionng/zenpress/model"
+	"github.com/insionng/zenpress/module/mailer"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeMailer struct {
+	sent []*mailer.Message
+	err  error
+}
+
+func (f *fakeMailer) Send(msg *mailer.Message) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.sent = append(f.sent, msg)
+	return nil
+}
+
+func TestMailQueue(t *testing.T) {
+	assert := assert.New(t)
+	fake := &fakeMailer{}
+	defer model.SetMailer(nil)
+	model.SetMailer(fake)
+	now := time.Now()
+	model.ProcessMailQueue(now)
+	fake.sent = nil
+
+	attachment := filepath.Join(os.TempDir(), fmt.Sprintf("zenpress-mail-%d.txt", now.UnixNano()))
+	ioutil.WriteFile(attachment, []byte("附件"), 0644)
+	defer os.Remove(attachment)
+
+	assert.NoError(model.QueueMail(&model.Mail{To: "queue@example.com", Subject: "队列", Body: "<p>正文</p>", Attachments: []string{attachment}}))
+	sent, failed := model.ProcessMailQueue(time.Now())
+	assert.Equal(1, sent)
+	assert.Equal(0, failed)
+	if assert.Len(fake.sent, 1) {
+		msg := fake.sent[0]
+		assert.Equal([]string{"queue@example.com"}, msg.To)
+		assert.Equal("<p>正文</p>", msg.HTML)
+		assert.Equal(model.GetMailFrom(), msg.From)
+		if assert.Len(msg.Attachments, 1) {
+			assert.Equal(filepath.Base(attachment), msg.Attachments[0].Filename)
+			assert.Equal("附件", string(msg.Attachments[0].Data))
+		}
+	}
+	page := model.PageMailQueue(model.MailStatusSent, 1, 1)
+	items := page.List.([]model.MailQueue)
+	if assert.Len(items, 1) {
+		assert.Equal(1, items[0].Attempts)
+		assert.NotNil(items[0].SentAt)
+		assert.NoError(model.DeleteMail(items[0].ID))
+	}
+
+	fake.err = errors.New("connection refused")
+	assert.NoError(model.QueueMail(&model.Mail{To: "retry@example.com", Subject: "重试"}))
+	now = time.Now()
+	sent, failed = model.ProcessMailQueue(now)
+	assert.Equal(0, sent)
+	assert.Equal(0, failed)
+	items = model.PageMailQueue(model.MailStatusPending, 1, 1).List.([]model.MailQueue)
+	if !assert.Len(items, 1) {
+		return
+	}
+	id := items[0].ID
+	defer model.DeleteMail(id)
+	assert.Equal("connection refused", items[0].LastError)
+	sent, _ = model.ProcessMailQueue(now)
+	assert.Equal(0, sent, "a failed mail should wait before it is retried")
+
+	for i := 1; i < model.MailMaxAttempts; i++ {
+		now = now.Add(model.MailRetryDelays[len(model.MailRetryDelays)-1] + time.Minute)
+		_, failed = model.ProcessMailQueue(now)
+	}
+	assert.Equal(1, failed)
+	item, err := model.GetMail(id)
+	if assert.NoError(err) {
+		assert.Equal(model.MailStatusFailed, item.Status)
+		assert.Equal(model.MailMaxAttempts, item.Attempts)
+	}
+	assert.True(model.CountMailQueue()[model.MailStatusFailed] > 0)
+
+	fake.err = nil
+	assert.NoError(model.RetryMail(id))
+	sent, _ = model.ProcessMailQueue(time.Now())
+	assert.Equal(1, sent)
+	item, _ = model.GetMail(id)
+	assert.Equal(model.MailStatusSent, item.Status)
+
+	assert.NoError(model.DeleteMail(id))
+	_, err = model.GetMail(id)
+	assert.Equal(model.ErrMailNotFound, err)
+}
+
+func TestMailQueueClaim(t *testing.T) {
+	assert := assert.New(t)
+	fake := &fakeMailer{}
+	defer model.SetMailer(nil)
+	model.SetMailer(fake)
+	now := time.Now()
+	model.ProcessMailQueue(now)
+	fake.sent = nil
+
+	assert.NoError(model.QueueMail(&model.Mail{To: "claim@example.com", Subject: "领取"}))
+	items := model.PageMailQueue(model.MailStatusPending, 1, 1).List.([]model.MailQueue)
+	if !assert.Len(items, 1) {
+		return
+	}
+	id := items[0].ID
+	defer model.DeleteMail(id)
+
+	//模拟另一个进程已领取该邮件
+	model.Database.Model(&model.MailQueue{}).Where("id = ?", id).UpdateColumns(map[string]interface{}{"status": model.MailStatusSending, "next_attempt_at": now.Add(model.MailClaimTimeout)})
+	sent, _ := model.ProcessMailQueue(now)
+	assert.Equal(0, sent, "a claimed mail should not be sent twice")
+	assert.NoError(model.RetryMail(id))
+	item, _ := model.GetMail(id)
+	assert.Equal(model.MailStatusSending, item.Status)
+
+	sent, _ = model.ProcessMailQueue(now.Add(model.MailClaimTimeout + time.Minute))
+	assert.Equal(1, sent, "an expired claim should be taken over")
+	assert.Len(fake.sent, 1)
+	item, _ = model.GetMail(id)
+	assert.Equal(model.MailStatusSent, item.Status)
+}
+
+func TestGetMailer(t *testing.T) {
+	assert := assert.New(t)
+	defer model.DeleteOption(model.MailTransportOption)
+	defer model.DeleteOption(model.MailSpoolDirOption)
+	defer model.DeleteOption(model.MailSMTPHostOption)
+
+	dir := filepath.Join(os.TempDir(), fmt.Sprintf("zenpress-spool-%d", time.Now().UnixNano()))
+	defer os.RemoveAll(dir)
+	model.SetOptionValue(model.MailSpoolDirOption, dir)
+	m, err := model.GetMailer()
+	assert.NoError(err)
+	assert.Equal(&mailer.FileMailer{Dir: dir}, m)
+	assert.NoError(model.SendTestMail("spool@example.com"))
+	files, _ := m.(*mailer.FileMailer).Files()
+	assert.Len(files, 1)
+
+	model.SetOptionValue(model.MailSMTPHostOption, "smtp.example.com")
+	m, _ = model.GetMailer()
+	assert.Equal("smtp.example.com", m.(*mailer.SMTPMailer).Host)
+
+	model.SetOptionValue(model.MailTransportOption, model.MailTransportSendmail)
+	m, _ = model.GetMailer()
+	assert.IsType(&mailer.SendmailMailer{}, m)
+
+	model.SetOptionValue(model.MailTransportOption, "pigeon")
+	_, err = model.GetMailer()
+	assert.Equal(model.ErrInvalidMailTransport, err)
+}
@@ -93,16 +93,25 @@ func init() {
 		log.Fatal("app.models.init() errors:", _error.Error())
 	}
 
-	CreateTables(Database)
+	if _error = CreateTables(Database); _error != nil {
+		log.Fatal("app.models.init() errors:", _error.Error())
+	}
 	message()
 }
 
-func CreateTables(Database *gorm.DB) {
+// CreateTables 创建或升级数据表，关联表的联合主键须先以显式的建表语句创建
+func CreateTables(Database *gorm.DB) error {
+	if err := migrateTermRelationships(Database); err != nil {
+		return fmt.Errorf("migrate term relationships error: %v", err)
+	}
+	if err := createTermRelationshipsTable(Database); err != nil {
+		return fmt.Errorf("create term relationships error: %v", err)
+	}
+	db := Database
 	if DataType == "mysql" {
-		Database.Set("gorm:table_options", "ENGINE=InnoDB").AutoMigrate(&AppVersion{}, &App{}, &Commentmeta{}, &Comment{}, &Link{}, &Option{}, &Postmeta{}, &Post{}, &RegistrationLog{}, &Signup{}, &Site{}, &Sitemeta{}, &TermRelationship{}, &TermTaxonomy{}, &Termmeta{}, &Term{}, &Usermeta{}, &User{}, &Role{}, &Permission{}, &RolePermission{})
-	} else {
-		Database.AutoMigrate(&AppVersion{}, &App{}, &Commentmeta{}, &Comment{}, &Link{}, &Option{}, &Postmeta{}, &Post{}, &RegistrationLog{}, &Signup{}, &Site{}, &Sitemeta{}, &TermRelationship{}, &TermTaxonomy{}, &Termmeta{}, &Term{}, &Usermeta{}, &User{}, &Role{}, &Permission{}, &RolePermission{})
+		db = Database.Set("gorm:table_options", "ENGINE=InnoDB")
 	}
+	return db.AutoMigrate(&AppVersion{}, &App{}, &Commentmeta{}, &Comment{}, &Link{}, &Option{}, &Postmeta{}, &Post{}, &RegistrationLog{}, &Signup{}, &Site{}, &Sitemeta{}, &TermRelationship{}, &TermTaxonomy{}, &Termmeta{}, &Term{}, &Usermeta{}, &User{}, &MailQueue{}, &AccessToken{}, &Role{}, &Permission{}, &RolePermission{}, &UserRole{}).Error
 }
 
 func Ping() error {
@@ -0,0 +1,382 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	// NavMenuTaxonomy 菜单在TermTaxonomy中的分类类型
+	NavMenuTaxonomy = "nav_menu"
+	// NavMenuItemPostType 菜单项在Post中的文章类型
+	NavMenuItemPostType = "nav_menu_item"
+)
+
+// NavMenuItem 菜单项，数据存储于nav_menu_item类型的文章及其Postmeta中。
+type NavMenuItem struct {
+	ID       uint64
+	MenuID   uint64 //对应菜单的TermTaxonomyID
+	ParentID uint64 //父菜单项ID
+	Title    string
+	URL      string //custom类型时使用，其余类型根据对象生成
+	Type     string //custom、post_type、taxonomy
+	Object   string //custom、post、page、category、post_tag等
+	ObjectID uint64
+	Target   string
+	Classes  string
+	Order    int
+	Children []*NavMenuItem
+}
+
+// NavMenu 菜单，数据存储于nav_menu分类中。
+type NavMenu struct {
+	ID    uint64 //TermTaxonomyID
+	Name  string
+	Slug  string
+	Count int64
+}
+
+// CreateNavMenu 创建菜单
+func CreateNavMenu(name string) (*NavMenu, error) {
+	if len(name) == 0 {
+		return nil, errors.New("菜单名称为空")
+	}
+	if menu, err := GetNavMenuByName(name); err == nil {
+		return menu, fmt.Errorf("菜单[%s]已存在", name)
+	}
+
+	term, err := InsertTerm(name, NavMenuTaxonomy, &TermOptions{Slug: name})
+	if err != nil {
+		return nil, err
+	}
+	return &NavMenu{ID: term.TermTaxonomyID, Name: term.Name, Slug: term.Slug}, nil
+}
+
+// GetNavMenu 获得菜单
+func GetNavMenu(menuID uint64) (*NavMenu, error) {
+	var tt TermTaxonomy
+	if err := Database.First(&tt, "term_taxonomy_id = ? and taxonomy = ?", menuID, NavMenuTaxonomy).Error; err != nil {
+		return nil, err
+	}
+	_, term := GetTerm(tt.TermID)
+	return &NavMenu{ID: tt.TermTaxonomyID, Name: term.Name, Slug: term.Slug, Count: tt.Count}, nil
+}
+
+// GetNavMenuByName 根据名称获得菜单
+func GetNavMenuByName(name string) (*NavMenu, error) {
+	for _, menu := range GetNavMenus() {
+		if menu.Name == name {
+			return menu, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// GetNavMenus 获得所有菜单
+func GetNavMenus() (menus []*NavMenu) {
+	var tts []TermTaxonomy
+	Database.Where("taxonomy = ?", NavMenuTaxonomy).Find(&tts)
+	for _, tt := range tts {
+		if db, term := GetTerm(tt.TermID); db.Error == nil {
+			menus = append(menus, &NavMenu{ID: tt.TermTaxonomyID, Name: term.Name, Slug: term.Slug, Count: tt.Count})
+		}
+	}
+	return
+}
+
+// DeleteNavMenu 删除菜单及其所有菜单项
+func DeleteNavMenu(menuID uint64) error {
+	menu, err := GetNavMenu(menuID)
+	if err != nil {
+		return err
+	}
+	for _, item := range GetNavMenuItems(menuID) {
+		if err := DeleteNavMenuItem(item.ID); err != nil {
+			return err
+		}
+	}
+
+	if err := DeleteTaxonomyTerm(menu.ID); err != nil {
+		return err
+	}
+
+	locations := GetNavMenuLocations()
+	for location, id := range locations {
+		if id == menuID {
+			delete(locations, location)
+		}
+	}
+	return SetNavMenuLocations(locations)
+}
+
+// AddNavMenuItem 新增菜单项
+func AddNavMenuItem(menuID uint64, item *NavMenuItem) error {
+	if _, err := GetNavMenu(menuID); err != nil {
+		return err
+	}
+	if item.Type == "" {
+		item.Type = "custom"
+		item.Object = "custom"
+	}
+
+	post := &Post{
+		PostTitle:  item.Title,
+		PostStatus: "publish",
+		PostType:   NavMenuItemPostType,
+		MenuOrder:  item.Order,
+	}
+	if err := NewPost(post).Error; err != nil {
+		return err
+	}
+	item.ID = post.ID
+	item.MenuID = menuID
+
+	if err := Database.Create(&TermRelationship{ObjectID: post.ID, TermTaxonomyID: menuID}).Error; err != nil {
+		return err
+	}
+	updateNavMenuCount(menuID)
+	return saveNavMenuItemMeta(item)
+}
+
+// UpdateNavMenuItem 更新菜单项
+func UpdateNavMenuItem(item *NavMenuItem) error {
+	if err := Database.Model(&Post{}).Where("id = ? and post_type = ?", item.ID, NavMenuItemPostType).Updates(map[string]interface{}{
+		"post_title": item.Title,
+		"menu_order": item.Order,
+	}).Error; err != nil {
+		return err
+	}
+	return saveNavMenuItemMeta(item)
+}
+
+// DeleteNavMenuItem 删除菜单项，子菜单项提升至其父级
+func DeleteNavMenuItem(id uint64) error {
+	item, err := GetNavMenuItem(id)
+	if err != nil {
+		return err
+	}
+
+	var metas []Postmeta
+	Database.Where("meta_key = ? and meta_value = ?", "_menu_item_menu_item_parent", strconv.FormatUint(id, 10)).Find(&metas)
+	for _, meta := range metas {
+		SetPostmetaValue(meta.PostID, "_menu_item_menu_item_parent", strconv.FormatUint(item.ParentID, 10))
+	}
+
+	Database.Delete(Postmeta{}, "post_id = ?", id)
+	Database.Delete(TermRelationship{}, "object_id = ?", id)
+	if err := DeletePost(id).Error; err != nil {
+		return err
+	}
+	updateNavMenuCount(item.MenuID)
+	return nil
+}
+
+// GetNavMenuItem 获得菜单项
+func GetNavMenuItem(id uint64) (*NavMenuItem, error) {
+	var post Post
+	if err := Database.First(&post, "id = ? and post_type = ?", id, NavMenuItemPostType).Error; err != nil {
+		return nil, err
+	}
+	var rel TermRelationship
+	Database.First(&rel, "object_id = ?", id)
+	return loadNavMenuItem(&post, rel.TermTaxonomyID), nil
+}
+
+// GetNavMenuItems 获得菜单的所有菜单项，按排序返回平铺列表
+func GetNavMenuItems(menuID uint64) (items []*NavMenuItem) {
+	var posts []Post
+	Database.Table(Database.NewScope(&Post{}).TableName()+" p").
+		Select("p.*").
+		Joins("inner join "+Database.NewScope(&TermRelationship{}).TableName()+" tr on tr.object_id = p.id").
+		Where("tr.term_taxonomy_id = ? and p.post_type = ?", menuID, NavMenuItemPostType).
+		Order("p.menu_order asc, p.id asc").
+		Scan(&posts)
+
+	for i := range posts {
+		items = append(items, loadNavMenuItem(&posts[i], menuID))
+	}
+	return
+}
+
+// GetNavMenuTree 获得菜单的树形结构
+func GetNavMenuTree(menuID uint64) []*NavMenuItem {
+	return buildNavMenuTree(GetNavMenuItems(menuID))
+}
+
+// NavMenuOrder 菜单项的排序及层级，对应后台拖拽编辑器提交的结构
+type NavMenuOrder struct {
+	ID       uint64         `json:"id"`
+	Children []NavMenuOrder `json:"children,omitempty"`
+}
+
+// SaveNavMenuStructure 保存菜单项的排序及层级，structure为嵌套的JSON数组
+func SaveNavMenuStructure(menuID uint64, structure string) error {
+	var orders []NavMenuOrder
+	if err := json.Unmarshal([]byte(structure), &orders); err != nil {
+		return err
+	}
+
+	owned := map[uint64]bool{}
+	for _, item := range GetNavMenuItems(menuID) {
+		owned[item.ID] = true
+	}
+
+	order := 0
+	var walk func(parentID uint64, nodes []NavMenuOrder) error
+	walk = func(parentID uint64, nodes []NavMenuOrder) error {
+		for _, node := range nodes {
+			if !owned[node.ID] {
+				return fmt.Errorf("菜单项[%v]不属于该菜单", node.ID)
+			}
+			order++
+			if err := Database.Model(&Post{}).Where("id = ?", node.ID).Update("menu_order", order).Error; err != nil {
+				return err
+			}
+			if err := SetPostmetaValue(node.ID, "_menu_item_menu_item_parent", strconv.FormatUint(parentID, 10)).Error; err != nil {
+				return err
+			}
+			if err := walk(node.ID, node.Children); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return walk(0, orders)
+}
+
+// GetNavMenuLocations 获得菜单位置与菜单的对应关系
+func GetNavMenuLocations() map[string]uint64 {
+	locations := map[string]uint64{}
+	if value := GetOptionValue("nav_menu_locations"); len(value) > 0 {
+		json.Unmarshal([]byte(value), &locations)
+	}
+	return locations
+}
+
+// SetNavMenuLocations 保存菜单位置与菜单的对应关系
+func SetNavMenuLocations(locations map[string]uint64) error {
+	b, err := json.Marshal(locations)
+	if err != nil {
+		return err
+	}
+	return SetOptionValue("nav_menu_locations", string(b)).Error
+}
+
+// SetNavMenuLocation 将菜单指定到菜单位置，menuID为0时取消指定
+func SetNavMenuLocation(location string, menuID uint64) error {
+	locations := GetNavMenuLocations()
+	if menuID == 0 {
+		delete(locations, location)
+	} else {
+		locations[location] = menuID
+	}
+	return SetNavMenuLocations(locations)
+}
+
+// GetNavMenuTreeByLocation 获得菜单位置上的菜单树
+func GetNavMenuTreeByLocation(location string) []*NavMenuItem {
+	if menuID, okay := GetNavMenuLocations()[location]; okay {
+		return GetNavMenuTree(menuID)
+	}
+	return nil
+}
+
+func loadNavMenuItem(post *Post, menuID uint64) *NavMenuItem {
+	item := &NavMenuItem{
+		ID:     post.ID,
+		MenuID: menuID,
+		Title:  post.PostTitle,
+		Order:  post.MenuOrder,
+	}
+
+	var metas []Postmeta
+	Database.Where("post_id = ?", post.ID).Find(&metas)
+	for _, meta := range metas {
+		switch meta.MetaKey {
+		case "_menu_item_type":
+			item.Type = meta.MetaValue
+		case "_menu_item_object":
+			item.Object = meta.MetaValue
+		case "_menu_item_object_id":
+			item.ObjectID, _ = strconv.ParseUint(meta.MetaValue, 10, 64)
+		case "_menu_item_menu_item_parent":
+			item.ParentID, _ = strconv.ParseUint(meta.MetaValue, 10, 64)
+		case "_menu_item_url":
+			item.URL = meta.MetaValue
+		case "_menu_item_target":
+			item.Target = meta.MetaValue
+		case "_menu_item_classes":
+			item.Classes = meta.MetaValue
+		}
+	}
+
+	switch item.Type {
+	case "post_type":
+		if db, object := GetPost(item.ObjectID); db.Error == nil {
+			item.URL = GetPermalink(&object)
+			if item.Title == "" {
+				item.Title = object.PostTitle
+			}
+		}
+	case "taxonomy":
+		if db, term := GetTerm(item.ObjectID); db.Error == nil {
+			item.URL = GetTermLink(&term, item.Object)
+			if item.Title == "" {
+				item.Title = term.Name
+			}
+		}
+	}
+	return item
+}
+
+func saveNavMenuItemMeta(item *NavMenuItem) error {
+	metas := map[string]string{
+		"_menu_item_type":             item.Type,
+		"_menu_item_object":           item.Object,
+		"_menu_item_object_id":        strconv.FormatUint(item.ObjectID, 10),
+		"_menu_item_menu_item_parent": strconv.FormatUint(item.ParentID, 10),
+		"_menu_item_url":              item.URL,
+		"_menu_item_target":           item.Target,
+		"_menu_item_classes":          item.Classes,
+	}
+	for key, value := range metas {
+		if err := SetPostmetaValue(item.ID, key, value).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func updateNavMenuCount(menuID uint64) {
+	UpdateTermCount(menuID)
+}
+
+func buildNavMenuTree(items []*NavMenuItem) (tree []*NavMenuItem) {
+	index := map[uint64]*NavMenuItem{}
+	for _, item := range items {
+		item.Children = nil
+		index[item.ID] = item
+	}
+	for _, item := range items {
+		if parent, okay := index[item.ParentID]; okay && item.ParentID != item.ID {
+			parent.Children = append(parent.Children, item)
+		} else {
+			tree = append(tree, item)
+		}
+	}
+
+	var sortItems func([]*NavMenuItem)
+	sortItems = func(nodes []*NavMenuItem) {
+		sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].Order < nodes[j].Order })
+		for _, node := range nodes {
+			sortItems(node.Children)
+		}
+	}
+	sortItems(tree)
+	return
+}
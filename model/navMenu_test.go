@@ -0,0 +1,67 @@
+package model_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/insionng/zenpress/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateNavMenu(t *testing.T) {
+	assert := assert.New(t)
+	menu, err := model.CreateNavMenu("测试菜单")
+	if assert.NoError(err) {
+		assert.Equal("测试菜单", menu.Name)
+	}
+
+	_, err = model.CreateNavMenu("测试菜单")
+	assert.Error(err, "duplicate menu name should fail")
+
+	assert.NoError(model.DeleteNavMenu(menu.ID))
+}
+
+func TestNavMenuTree(t *testing.T) {
+	assert := assert.New(t)
+	menu, err := model.CreateNavMenu("测试菜单树")
+	if !assert.NoError(err) {
+		return
+	}
+	defer model.DeleteNavMenu(menu.ID)
+
+	home := &model.NavMenuItem{Title: "首页", URL: "/"}
+	news := &model.NavMenuItem{Title: "新闻", URL: "/category/news"}
+	assert.NoError(model.AddNavMenuItem(menu.ID, home))
+	assert.NoError(model.AddNavMenuItem(menu.ID, news))
+
+	structure := fmt.Sprintf(`[{"id":%d,"children":[{"id":%d}]}]`, home.ID, news.ID)
+	assert.NoError(model.SaveNavMenuStructure(menu.ID, structure))
+
+	tree := model.GetNavMenuTree(menu.ID)
+	if assert.Equal(1, len(tree)) && assert.Equal(1, len(tree[0].Children)) {
+		assert.Equal("首页", tree[0].Title)
+		assert.Equal("/category/news", tree[0].Children[0].URL)
+	}
+
+	assert.NoError(model.DeleteNavMenuItem(home.ID))
+	tree = model.GetNavMenuTree(menu.ID)
+	if assert.Equal(1, len(tree)) {
+		assert.Equal(news.ID, tree[0].ID, "children should move up to the deleted item's parent")
+	}
+}
+
+func TestNavMenuLocation(t *testing.T) {
+	assert := assert.New(t)
+	menu, err := model.CreateNavMenu("测试菜单位置")
+	if !assert.NoError(err) {
+		return
+	}
+	assert.NoError(model.AddNavMenuItem(menu.ID, &model.NavMenuItem{Title: "关于", URL: "/about"}))
+
+	assert.NoError(model.SetNavMenuLocation("primary", menu.ID))
+	assert.Equal(1, len(model.GetNavMenuTreeByLocation("primary")))
+
+	assert.NoError(model.DeleteNavMenu(menu.ID))
+	assert.Equal(0, len(model.GetNavMenuTreeByLocation("primary")))
+}
@@ -49,3 +49,22 @@ func DeleteOption(key string) (db *gorm.DB) {
 	db = Database.Delete(Option{}, "option_name = ?", key)
 	return
 }
+
+// GetOptionValue 获得选项值，不存在时返回默认值
+func GetOptionValue(key string, defaultValue ...string) string {
+	if db, option := GetOption(key); db.Error == nil {
+		return option.OptionValue
+	}
+	if len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+	return ""
+}
+
+// SetOptionValue 设置选项值，不存在时新增
+func SetOptionValue(key, value string) (db *gorm.DB) {
+	if db, option := GetOption(key); db.Error == nil {
+		return Database.Model(&Option{}).Where("option_id = ?", option.OptionID).Update("option_value", value)
+	}
+	return AddOption(key, value)
+}
@@ -0,0 +1,245 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	// PermalinkStructureOption 文章固定链接结构的选项名，为空时使用 /single?p={ID}
+	PermalinkStructureOption = "permalink_structure"
+	// OldSlugMetaKey 文章修改别名后，旧别名保存在Postmeta中的键名
+	OldSlugMetaKey = "_wp_old_slug"
+)
+
+var (
+	// ErrInvalidPermalink 固定链接结构无效
+	ErrInvalidPermalink = errors.New("固定链接结构无效，须以/开头并包含%post_id%或%postname%")
+
+	// PermalinkStructures 常用的固定链接结构
+	PermalinkStructures = []string{
+		"/%year%/%monthnum%/%day%/%postname%/",
+		"/%year%/%monthnum%/%postname%/",
+		"/archives/%post_id%",
+		"/%post_id%.html",
+		"/%postname%/",
+	}
+
+	// permalinkTags 固定链接中可用的标签及其在路由中的匹配规则
+	permalinkTags = map[string]string{
+		"year":     `\d{4}`,
+		"monthnum": `\d{2}`,
+		"day":      `\d{2}`,
+		"hour":     `\d{2}`,
+		"minute":   `\d{2}`,
+		"second":   `\d{2}`,
+		"post_id":  `\d+`,
+		"postname": `[^/]+`,
+		"category": `[^/]+`,
+		"author":   `[^/]+`,
+	}
+
+	permalinkTagRegexp = regexp.MustCompile(`%([a-z_]+)%`)
+
+	// activePermalinkStructure 路由生成时加载的固定链接结构，文章链接按此生成，以免设置修改后、路由重新生成前的链接无法访问
+	activePermalinkStructure string
+	permalinkStructureLoaded bool
+	permalinkStructureLock   sync.RWMutex
+)
+
+// GetPermalinkStructure 获得文章的固定链接结构
+func GetPermalinkStructure() string {
+	return GetOptionValue(PermalinkStructureOption)
+}
+
+// LoadPermalinkStructure 读取设置中的固定链接结构作为生效的结构，由生成路由的代码调用
+func LoadPermalinkStructure() string {
+	structure := GetPermalinkStructure()
+	permalinkStructureLock.Lock()
+	activePermalinkStructure, permalinkStructureLoaded = structure, true
+	permalinkStructureLock.Unlock()
+	return structure
+}
+
+// ActivePermalinkStructure 获得路由正在使用的固定链接结构，尚未加载时读取设置并缓存
+func ActivePermalinkStructure() string {
+	permalinkStructureLock.RLock()
+	structure, loaded := activePermalinkStructure, permalinkStructureLoaded
+	permalinkStructureLock.RUnlock()
+	if loaded {
+		return structure
+	}
+	return LoadPermalinkStructure()
+}
+
+// SetPermalinkStructure 设置文章的固定链接结构，空字符串表示使用默认链接，修改后需重启以重新生成路由，此前文章链接仍按原结构生成
+func SetPermalinkStructure(structure string) error {
+	structure = strings.TrimSpace(structure)
+	if err := ValidatePermalinkStructure(structure); err != nil {
+		return err
+	}
+	return SetOptionValue(PermalinkStructureOption, structure).Error
+}
+
+// ValidatePermalinkStructure 检查固定链接结构，标签须已定义，并且不能占用已有路由的路径
+func ValidatePermalinkStructure(structure string) error {
+	if len(structure) == 0 {
+		return nil
+	}
+	if !strings.HasPrefix(structure, "/") || strings.Contains(structure, "?") {
+		return ErrInvalidPermalink
+	}
+	if !strings.Contains(structure, "%post_id%") && !strings.Contains(structure, "%postname%") {
+		return ErrInvalidPermalink
+	}
+	for _, m := range permalinkTagRegexp.FindAllStringSubmatch(structure, -1) {
+		if _, okay := permalinkTags[m[1]]; !okay {
+			return fmt.Errorf("固定链接中的标签%%%s%%未定义", m[1])
+		}
+	}
+	//archives为常用结构/archives/%post_id%自身的路径，只对文章类型保留
+	if prefix := strings.SplitN(strings.Trim(structure, "/"), "/", 2)[0]; !strings.Contains(prefix, "%") && prefix != "archives" && reservedArchiveSlugs[prefix] {
+		return fmt.Errorf("固定链接的路径[%s]为保留路径", prefix)
+	}
+	return nil
+}
+
+// PermalinkRoute 将固定链接结构转为路由规则，如 /%year%/%postname%/ 转为 /<year:\d{4}>/<postname:[^/]+>，结尾的斜杠由调用方处理
+func PermalinkRoute(structure string) string {
+	route := permalinkTagRegexp.ReplaceAllStringFunc(strings.TrimSuffix(structure, "/"), func(tag string) string {
+		name := strings.Trim(tag, "%")
+		return fmt.Sprintf("<%s:%s>", name, permalinkTags[name])
+	})
+	return strings.Replace(route, ".", `\.`, -1)
+}
+
+// BuildPermalink 按固定链接结构生成文章链接
+func BuildPermalink(structure string, post *Post) string {
+	return permalinkTagRegexp.ReplaceAllStringFunc(structure, func(tag string) string {
+		switch strings.Trim(tag, "%") {
+		case "year":
+			return post.PostDate.Format("2006")
+		case "monthnum":
+			return post.PostDate.Format("01")
+		case "day":
+			return post.PostDate.Format("02")
+		case "hour":
+			return post.PostDate.Format("15")
+		case "minute":
+			return post.PostDate.Format("04")
+		case "second":
+			return post.PostDate.Format("05")
+		case "post_id":
+			return strconv.FormatUint(post.ID, 10)
+		case "postname":
+			if len(post.PostName) > 0 {
+				return url.PathEscape(post.PostName)
+			}
+			return strconv.FormatUint(post.ID, 10)
+		case "category":
+			if terms := GetObjectTerms(post.ID, "category"); len(terms) > 0 {
+				return url.PathEscape(terms[0].Slug)
+			}
+			return "uncategorized"
+		case "author":
+			var user User
+			if Database.First(&user, "id = ?", post.PostAuthor).Error == nil && len(user.UserNicename) > 0 {
+				return url.PathEscape(user.UserNicename)
+			}
+			return strconv.FormatUint(post.PostAuthor, 10)
+		}
+		return tag
+	})
+}
+
+// ResolvePermalink 根据固定链接中的%post_id%或%postname%获得已发布的文章，别名找不到时按旧别名查找
+func ResolvePermalink(postID, name string) (db *gorm.DB, post Post) {
+	if id, err := strconv.ParseUint(postID, 10, 64); err == nil {
+		db = Database.Where("id = ? and post_type = ? and post_status = ?", id, "post", PostStatusPublish).First(&post)
+		return
+	}
+
+	if db, post = GetPublishedPostByName("post", name); db.Error == nil {
+		return
+	}
+	return GetPostByOldSlug("post", name)
+}
+
+// GetPostByOldSlug 根据修改前的别名获得已发布的文章
+func GetPostByOldSlug(postType, slug string) (db *gorm.DB, post Post) {
+	db = Database.Table(Database.NewScope(&Post{}).TableName()+" p").
+		Select("p.*").
+		Joins("inner join "+Database.NewScope(&Postmeta{}).TableName()+" pm on pm.post_id = p.id").
+		Where("pm.meta_key = ? and pm.meta_value = ? and p.post_type = ? and p.post_status = ?", OldSlugMetaKey, slug, postType, PostStatusPublish).
+		Order("p.post_date desc").
+		Limit(1).
+		Scan(&post)
+	if db.Error == nil && post.ID == 0 {
+		db.Error = gorm.ErrRecordNotFound
+	}
+	return
+}
+
+// CanonicalPermalink 比较请求路径与文章的固定链接，不一致时返回需跳转的链接
+func CanonicalPermalink(post Post, requestPath string) (string, bool) {
+	link := GetPermalink(&post)
+	u, err := url.Parse(link)
+	if err != nil || u.Path == requestPath {
+		return link, true
+	}
+	return link, false
+}
+
+// UniquePostName 获得同类型文章中唯一的别名，重复时附加数字后缀
+func UniquePostName(name, postType string, excludeID uint64) string {
+	exists := func(s string) bool {
+		var count int
+		Database.Model(&Post{}).Where("post_name = ? and post_type = ? and id <> ?", s, postType, excludeID).Count(&count)
+		return count > 0
+	}
+	if !exists(name) {
+		return name
+	}
+	for i := 2; ; i++ {
+		if s := fmt.Sprintf("%s-%d", name, i); !exists(s) {
+			return s
+		}
+	}
+}
+
+// preparePostName 整理公开文章类型的别名，发布时别名为空则由标题生成，纯数字别名会与文章ID混淆，附加后缀
+func preparePostName(post *Post) {
+	pt := GetPostType(post.PostType)
+	if pt == nil || !pt.Public {
+		return
+	}
+
+	name := SanitizeTermSlug(post.PostName)
+	if len(name) == 0 && (post.PostStatus == PostStatusPublish || post.PostStatus == PostStatusFuture) {
+		name = SanitizeTermSlug(post.PostTitle)
+	}
+	if len(name) == 0 {
+		post.PostName = ""
+		return
+	}
+	if _, err := strconv.ParseUint(name, 10, 64); err == nil {
+		name = "post-" + name
+	}
+	post.PostName = UniquePostName(name, post.PostType, post.ID)
+}
+
+// saveOldSlug 已发布文章的别名修改后，保存旧别名以便旧链接继续可用
+func saveOldSlug(old, post *Post) {
+	if old.PostStatus != PostStatusPublish || len(old.PostName) == 0 || old.PostName == post.PostName {
+		return
+	}
+	Database.Delete(Postmeta{}, "post_id = ? and meta_key = ? and meta_value in (?)", post.ID, OldSlugMetaKey, []string{old.PostName, post.PostName})
+	AddPostmeta(post.ID, OldSlugMetaKey, old.PostName)
+}
@@ -0,0 +1,66 @@
+package model_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/insionng/zenpress/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPermalinkRoute(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(`/<year:\d{4}>/<monthnum:\d{2}>/<postname:[^/]+>`, model.PermalinkRoute("/%year%/%monthnum%/%postname%/"))
+	assert.Equal(`/<post_id:\d+>\.html`, model.PermalinkRoute("/%post_id%.html"))
+	assert.Equal(`/archives/<post_id:\d+>`, model.PermalinkRoute("/archives/%post_id%"))
+
+	assert.NoError(model.ValidatePermalinkStructure(""))
+	assert.Equal(model.ErrInvalidPermalink, model.ValidatePermalinkStructure("/%year%/"))
+	assert.Error(model.ValidatePermalinkStructure("/%foo%/%postname%"))
+	assert.Error(model.ValidatePermalinkStructure("/category/%postname%"))
+}
+
+func TestPermalink(t *testing.T) {
+	assert := assert.New(t)
+	assert.NoError(model.SetPermalinkStructure("/%year%/%monthnum%/%postname%/"))
+	model.LoadPermalinkStructure()
+	defer model.LoadPermalinkStructure()
+	defer model.SetPermalinkStructure("")
+
+	date := time.Date(2017, 3, 9, 10, 0, 0, 0, time.Local)
+	post := &model.Post{PostTitle: "Hello World", PostStatus: model.PostStatusPublish, PostDate: date}
+	if !assert.NoError(model.InsertPost(post)) {
+		return
+	}
+	defer model.RemovePost(post.ID)
+	assert.Equal("hello-world", post.PostName)
+	assert.Equal("/2017/03/hello-world/", model.GetPermalink(post))
+
+	other := &model.Post{PostTitle: "Hello World", PostStatus: model.PostStatusPublish, PostDate: date}
+	if assert.NoError(model.InsertPost(other)) {
+		defer model.RemovePost(other.ID)
+		assert.Equal("hello-world-2", other.PostName)
+	}
+
+	post.PostName = "hello-zenpress"
+	assert.NoError(model.SavePost(post))
+	db, found := model.ResolvePermalink("", "hello-world")
+	if assert.NoError(db.Error, "old slugs keep working") {
+		assert.Equal(post.ID, found.ID)
+		link, canonical := model.CanonicalPermalink(found, "/2017/03/hello-world/")
+		assert.False(canonical)
+		assert.Equal("/2017/03/hello-zenpress/", link)
+	}
+	_, canonical := model.CanonicalPermalink(*post, "/2017/03/hello-zenpress/")
+	assert.True(canonical)
+
+	assert.NoError(model.SetPermalinkStructure("/archives/%post_id%"))
+	db, found = model.ResolvePermalink(fmt.Sprint(post.ID), "")
+	if assert.NoError(db.Error) {
+		assert.Equal("/2017/03/hello-zenpress/", model.GetPermalink(&found), "links should follow the routes until they are reloaded")
+		assert.Equal("/archives/%post_id%", model.LoadPermalinkStructure())
+		assert.Equal(fmt.Sprintf("/archives/%d", post.ID), model.GetPermalink(&found))
+	}
+}
@@ -1,8 +1,13 @@
 package model
 
 import (
+	"fmt"
+	"net/url"
+	"strconv"
 	"time"
 
+	"github.com/insionng/zenpress/helper"
+
 	"github.com/jinzhu/gorm"
 )
 
@@ -51,10 +56,18 @@ func GetPost(id uint64) (db *gorm.DB, Post Post) {
 	return
 }
 
-// UpdatePost 更新文章
+// UpdatePost 更新文章内容
 func UpdatePost(id uint64, postContent string) (db *gorm.DB, post Post) {
-	post = Post{PostContent: postContent}
-	db = Database.Model(&post).Update("id", id)
+	now := time.Now()
+	db = Database.Model(&Post{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"post_content":      postContent,
+		"post_modified":     now,
+		"post_modified_gmt": now.UTC(),
+	})
+	if db.Error != nil {
+		return
+	}
+	db, post = GetPost(id)
 	return
 }
 
@@ -63,3 +76,65 @@ func DeletePost(id uint64) (db *gorm.DB) {
 	db = Database.Delete(Post{}, "id = ?", id)
 	return
 }
+
+// GetPermalink 获得文章链接
+func GetPermalink(post *Post) string {
+	if post.PostType == "page" {
+		return fmt.Sprintf("/page?p=%d", post.ID)
+	}
+	if post.PostType == AttachmentPostType {
+		return fmt.Sprintf("/attachment/%d", post.ID)
+	}
+	if pt := GetPostType(post.PostType); pt != nil && pt.Public && !pt.Builtin {
+		if len(post.PostName) > 0 {
+			return fmt.Sprintf("/%s/%s", pt.ArchiveSlug, url.PathEscape(post.PostName))
+		}
+		return fmt.Sprintf("/%s/%d", pt.ArchiveSlug, post.ID)
+	}
+	if structure := ActivePermalinkStructure(); post.PostType == "post" && len(structure) > 0 {
+		return BuildPermalink(structure, post)
+	}
+	return fmt.Sprintf("/single?p=%d", post.ID)
+}
+
+// GetPostsByType 获得指定类型的已发布文章
+func GetPostsByType(postType string, limit int) (posts []Post) {
+	Database.Where("post_type = ? and post_status = ?", postType, "publish").Order("post_date desc").Limit(limit).Find(&posts)
+	return
+}
+
+// PagePosts 按类型及状态分页获得文章，status为空时获得回收站以外的全部文章
+func PagePosts(postType, status string, pageNo, pageSize int) helper.Page {
+	var posts []Post
+	var count int
+	db := Database.Model(&Post{}).Where("post_type = ?", postType)
+	if len(status) > 0 {
+		db = db.Where("post_status = ?", status)
+	} else {
+		db = db.Where("post_status not in (?)", []string{PostStatusTrash, PostStatusAutoDraft})
+	}
+	db.Count(&count)
+	if pageNo < 1 {
+		pageNo = 1
+	}
+	db.Order("post_date desc").Offset((pageNo - 1) * pageSize).Limit(pageSize).Find(&posts)
+	return helper.PageUtil(count, pageNo, pageSize, posts)
+}
+
+// GetPublishedPostByName 根据别名获得指定类型的已发布文章，别名为数字时同时按ID查找
+func GetPublishedPostByName(postType, name string) (db *gorm.DB, post Post) {
+	db = Database.Where("post_type = ? and post_status = ?", postType, PostStatusPublish)
+	if id, err := strconv.ParseUint(name, 10, 64); err == nil {
+		db = db.Where("post_name = ? or id = ?", name, id)
+	} else {
+		db = db.Where("post_name = ?", name)
+	}
+	db = db.First(&post)
+	return
+}
+
+// GetChildPosts 获得文章的子文章，用于层级文章类型
+func GetChildPosts(parentID uint64, postType string) (posts []Post) {
+	Database.Where("post_parent = ? and post_type = ?", parentID, postType).Order("menu_order asc, post_title asc").Find(&posts)
+	return
+}
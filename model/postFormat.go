@@ -0,0 +1,179 @@
+package model
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/insionng/zenpress/helper"
+	"github.com/insionng/zenpress/module/hook"
+)
+
+const (
+	// PostFormatHTML 以HTML编写的文章内容
+	PostFormatHTML = "html"
+	// PostFormatMarkdown 以Markdown编写的文章内容
+	PostFormatMarkdown = "markdown"
+
+	// PostFormatMetaKey 文章内容格式保存在Postmeta中的键名，不存在时为html
+	PostFormatMetaKey = "_post_format"
+	// DefaultPostFormatOption 新建文章默认使用的内容格式保存在选项中的键名
+	DefaultPostFormatOption = "default_post_format"
+)
+
+var (
+	// ErrInvalidPostFormat 文章内容格式未注册
+	ErrInvalidPostFormat = errors.New("文章内容格式未注册")
+
+	postFormats     []*PostFormat
+	postFormatsLock sync.RWMutex
+)
+
+// PostFormat 文章内容格式，Convert将原文转换为HTML，结果统一经helper.ObjPolicy过滤后缓存在PostContentFiltered中
+type PostFormat struct {
+	Name    string
+	Label   string
+	Convert func(string) string
+}
+
+func init() {
+	RegisterPostFormat(PostFormatHTML, "HTML", nil)
+	RegisterPostFormat(PostFormatMarkdown, "Markdown", helper.MarkdownUnsafe)
+}
+
+// RegisterPostFormat 注册文章内容格式，插件可注册其他格式，同名格式将被替换，convert为nil时原样输出
+func RegisterPostFormat(name, label string, convert func(string) string) {
+	postFormatsLock.Lock()
+	defer postFormatsLock.Unlock()
+	format := &PostFormat{Name: name, Label: label, Convert: convert}
+	for i, f := range postFormats {
+		if f.Name == name {
+			postFormats[i] = format
+			return
+		}
+	}
+	postFormats = append(postFormats, format)
+}
+
+// GetPostFormats 按注册顺序获得全部文章内容格式
+func GetPostFormats() []*PostFormat {
+	postFormatsLock.RLock()
+	defer postFormatsLock.RUnlock()
+	return append([]*PostFormat(nil), postFormats...)
+}
+
+// IsValidPostFormat 是否为已注册的文章内容格式
+func IsValidPostFormat(name string) bool {
+	return getPostFormat(name) != nil
+}
+
+// GetDefaultPostFormat 获得新建文章默认使用的内容格式
+func GetDefaultPostFormat() string {
+	if name := GetOptionValue(DefaultPostFormatOption); IsValidPostFormat(name) {
+		return name
+	}
+	return PostFormatHTML
+}
+
+// GetPostFormat 获得文章的内容格式
+func GetPostFormat(id uint64) string {
+	if name := GetPostmetaValue(id, PostFormatMetaKey); IsValidPostFormat(name) {
+		return name
+	}
+	return PostFormatHTML
+}
+
+// SetPostFormat 设置已保存文章的内容格式，按新格式重新生成PostContentFiltered并更新搜索索引，格式未改变时不重新生成
+func SetPostFormat(id uint64, name string) error {
+	if !IsValidPostFormat(name) {
+		return ErrInvalidPostFormat
+	}
+	db, post := GetPost(id)
+	if db.Error != nil {
+		return db.Error
+	}
+	if GetPostmetaValue(id, PostFormatMetaKey) == name {
+		return nil
+	}
+	if err := SetPostmetaValue(id, PostFormatMetaKey, name).Error; err != nil {
+		return err
+	}
+	post.PostContentFiltered = RenderPostContent(post.PostContent, name)
+	if err := Database.Model(&Post{}).Where("id = ?", id).UpdateColumn("post_content_filtered", post.PostContentFiltered).Error; err != nil {
+		return err
+	}
+	indexPostForSearch(&post)
+	return nil
+}
+
+// RenderPostContent 将指定格式的原文转换为过滤后的HTML
+// 转换前后分别执行pre_format_<格式>及format_<格式>过滤钩子，过滤后执行post_content_filtered过滤钩子
+func RenderPostContent(content, name string) string {
+	format := getPostFormat(name)
+	if format == nil {
+		format = getPostFormat(PostFormatHTML)
+	}
+
+	if b := hook.ApplyFilterHook("pre_format_"+format.Name, []byte(content)); b != nil {
+		content = string(b)
+	}
+	if format.Convert != nil {
+		content = format.Convert(content)
+	}
+	if b := hook.ApplyFilterHook("format_"+format.Name, []byte(content)); b != nil {
+		content = string(b)
+	}
+
+	b := helper.ObjPolicy().SanitizeBytes([]byte(content))
+	if filtered := hook.ApplyFilterHook("post_content_filtered", b); filtered != nil {
+		b = filtered
+	}
+	return string(b)
+}
+
+// GetPostContentHTML 获得文章用于输出的HTML，优先使用缓存的PostContentFiltered
+func GetPostContentHTML(post *Post) string {
+	if len(post.PostContentFiltered) > 0 || len(post.PostContent) == 0 {
+		return post.PostContentFiltered
+	}
+	return RenderPostContent(post.PostContent, GetPostFormat(post.ID))
+}
+
+// RebuildPostContentFiltered 按各文章的内容格式重新生成全部文章的PostContentFiltered，修订版本除外
+func RebuildPostContentFiltered() (int, error) {
+	var count int
+	var lastID uint64
+	for {
+		var posts []Post
+		if err := Database.Where("id > ? and post_type <> ?", lastID, PostTypeRevision).
+			Order("id").Limit(200).Find(&posts).Error; err != nil {
+			return count, err
+		}
+		if len(posts) == 0 {
+			return count, nil
+		}
+		for i := range posts {
+			filtered := RenderPostContent(posts[i].PostContent, GetPostFormat(posts[i].ID))
+			if err := Database.Model(&Post{}).Where("id = ?", posts[i].ID).UpdateColumn("post_content_filtered", filtered).Error; err != nil {
+				return count, err
+			}
+		}
+		count += len(posts)
+		lastID = posts[len(posts)-1].ID
+	}
+}
+
+// renderPostContentFiltered 保存文章前按文章的内容格式生成PostContentFiltered
+func renderPostContentFiltered(post *Post) {
+	post.PostContentFiltered = RenderPostContent(post.PostContent, GetPostFormat(post.ID))
+}
+
+func getPostFormat(name string) *PostFormat {
+	postFormatsLock.RLock()
+	defer postFormatsLock.RUnlock()
+	for _, f := range postFormats {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package model_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/insionng/zenpress/model"
+	"github.com/insionng/zenpress/module/hook"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderPostContent(t *testing.T) {
+	assert := assert.New(t)
+
+	html := model.RenderPostContent("# 标题\n\n**加粗**<script>alert(1)</script>", model.PostFormatMarkdown)
+	assert.Contains(html, "<h1>标题</h1>")
+	assert.Contains(html, "<strong>加粗</strong>")
+	assert.NotContains(html, "<script>")
+
+	html = model.RenderPostContent("<p style=\"color: red\" onclick=\"x()\">内容</p>", model.PostFormatHTML)
+	assert.Contains(html, "内容")
+	assert.NotContains(html, "onclick")
+
+	assert.Equal(model.RenderPostContent("<p>未知格式</p>", model.PostFormatHTML), model.RenderPostContent("<p>未知格式</p>", "unknown"))
+
+	hook.AddFilterHook("format_markdown", func(b []byte) []byte {
+		return []byte(strings.Replace(string(b), "<h1>", "<h1 class=\"title\">", -1))
+	})
+	assert.Contains(model.RenderPostContent("# 钩子", model.PostFormatMarkdown), "<h1 class=\"title\">钩子</h1>")
+}
+
+func TestPostFormat(t *testing.T) {
+	assert := assert.New(t)
+	post := &model.Post{PostTitle: "测试内容格式", PostContent: "*强调*"}
+	if !assert.NoError(model.InsertPost(post)) {
+		return
+	}
+	defer model.RemovePost(post.ID)
+	defer model.DeletePostmetaByKey(post.ID, model.PostFormatMetaKey)
+
+	assert.Equal(model.PostFormatHTML, model.GetPostFormat(post.ID))
+	assert.Equal("*强调*", post.PostContentFiltered)
+
+	assert.Equal(model.ErrInvalidPostFormat, model.SetPostFormat(post.ID, "unknown"))
+	if !assert.NoError(model.SetPostFormat(post.ID, model.PostFormatMarkdown)) {
+		return
+	}
+	assert.Equal(model.PostFormatMarkdown, model.GetPostFormat(post.ID))
+	_, saved := model.GetPost(post.ID)
+	assert.Equal("<p><em>强调</em></p>", strings.TrimSpace(saved.PostContentFiltered))
+
+	//格式未改变时不重新生成
+	model.Database.Model(&model.Post{}).Where("id = ?", post.ID).UpdateColumn("post_content_filtered", "<p>缓存</p>")
+	assert.NoError(model.SetPostFormat(post.ID, model.PostFormatMarkdown))
+	_, cached := model.GetPost(post.ID)
+	assert.Equal("<p>缓存</p>", cached.PostContentFiltered)
+
+	saved.PostContent = "## 修改"
+	if assert.NoError(model.SavePost(&saved)) {
+		_, saved = model.GetPost(post.ID)
+		assert.Equal("<h2>修改</h2>", strings.TrimSpace(saved.PostContentFiltered))
+		assert.Equal(saved.PostContentFiltered, model.GetPostContentHTML(&saved))
+	}
+
+	saved.PostContentFiltered = ""
+	assert.Equal("<h2>修改</h2>", strings.TrimSpace(model.GetPostContentHTML(&saved)))
+
+	model.RegisterPostFormat("shout", "Shout", strings.ToUpper)
+	assert.True(model.IsValidPostFormat("shout"))
+	assert.Equal("ABC", model.RenderPostContent("abc", "shout"))
+}
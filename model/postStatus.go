@@ -0,0 +1,349 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/insionng/zenpress/module/hook"
+)
+
+const (
+	PostStatusPublish   = "publish"    //已发布
+	PostStatusFuture    = "future"     //定时发布
+	PostStatusDraft     = "draft"      //草稿
+	PostStatusPending   = "pending"    //待审
+	PostStatusPrivate   = "private"    //私密
+	PostStatusTrash     = "trash"      //回收站
+	PostStatusAutoDraft = "auto-draft" //自动草稿
+	PostStatusInherit   = "inherit"    //继承父文章状态，用于修订及附件
+)
+
+var (
+	// ErrInvalidPostStatus 文章状态无效
+	ErrInvalidPostStatus = errors.New("文章状态无效")
+	// ErrPostNotInTrash 文章不在回收站中
+	ErrPostNotInTrash = errors.New("文章不在回收站中")
+
+	postStatuses = map[string]bool{
+		PostStatusPublish:   true,
+		PostStatusFuture:    true,
+		PostStatusDraft:     true,
+		PostStatusPending:   true,
+		PostStatusPrivate:   true,
+		PostStatusTrash:     true,
+		PostStatusAutoDraft: true,
+		PostStatusInherit:   true,
+	}
+)
+
+// PostTransition 文章状态变更，作为transition_post_status等钩子的JSON内容
+type PostTransition struct {
+	PostID    uint64 `json:"post_id"`
+	PostType  string `json:"post_type"`
+	OldStatus string `json:"old_status"`
+	NewStatus string `json:"new_status"`
+}
+
+// IsValidPostStatus 是否为有效的文章状态
+func IsValidPostStatus(status string) bool {
+	return postStatuses[status]
+}
+
+// InsertPost 创建文章，处理文章状态、发布时间及修改时间，并执行状态变更钩子
+func InsertPost(post *Post) error {
+	if err := preparePost(post, time.Now()); err != nil {
+		return err
+	}
+	renderPostContentFiltered(post)
+	if err := NewPost(post).Error; err != nil {
+		return err
+	}
+
+	transitionPostStatus(post, "new")
+	if _, err := SavePostRevision(post.ID); err != nil {
+		return err
+	}
+	doPostHook("save_post", post)
+	return nil
+}
+
+// SavePost 更新文章的全部字段，处理状态变更并保持修改时间一致
+func SavePost(post *Post) error {
+	db, old := GetPost(post.ID)
+	if db.Error != nil {
+		return db.Error
+	}
+	if err := preparePost(post, time.Now()); err != nil {
+		return err
+	}
+	renderPostContentFiltered(post)
+	post.CommentCount = old.CommentCount
+	if err := Database.Save(post).Error; err != nil {
+		return err
+	}
+	saveOldSlug(&old, post)
+
+	transitionPostStatus(post, old.PostStatus)
+	if _, err := SavePostRevision(post.ID); err != nil {
+		return err
+	}
+	if err := DeletePostAutosaves(post.ID); err != nil {
+		return err
+	}
+	doPostHook("save_post", post)
+	return nil
+}
+
+// SetPostStatus 修改文章状态
+func SetPostStatus(id uint64, status string) error {
+	return setPostStatus(id, status, time.Now())
+}
+
+func setPostStatus(id uint64, status string, now time.Time) error {
+	db, post := GetPost(id)
+	if db.Error != nil {
+		return db.Error
+	}
+	if !IsValidPostStatus(status) {
+		return ErrInvalidPostStatus
+	}
+
+	oldStatus := post.PostStatus
+	post.PostStatus = status
+	if err := preparePost(&post, now); err != nil {
+		return err
+	}
+	if err := Database.Model(&Post{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"post_status":       post.PostStatus,
+		"post_name":         post.PostName,
+		"post_modified":     post.PostModified,
+		"post_modified_gmt": post.PostModifiedGmt,
+	}).Error; err != nil {
+		return err
+	}
+
+	transitionPostStatus(&post, oldStatus)
+	return nil
+}
+
+// PublishPost 立即发布文章
+func PublishPost(id uint64) error {
+	db, post := GetPost(id)
+	if db.Error != nil {
+		return db.Error
+	}
+	if now := time.Now(); post.PostDate.After(now) {
+		Database.Model(&Post{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"post_date":     now,
+			"post_date_gmt": now.UTC(),
+		})
+	}
+	return SetPostStatus(id, PostStatusPublish)
+}
+
+// TrashPost 将文章移至回收站，原状态保存于Postmeta以便还原
+func TrashPost(id uint64) error {
+	db, post := GetPost(id)
+	if db.Error != nil {
+		return db.Error
+	}
+	if post.PostStatus == PostStatusTrash {
+		return nil
+	}
+
+	SetPostmetaValue(id, "_wp_trash_meta_status", post.PostStatus)
+	SetPostmetaValue(id, "_wp_trash_meta_time", strconv.FormatInt(time.Now().Unix(), 10))
+	if err := SetPostStatus(id, PostStatusTrash); err != nil {
+		return err
+	}
+	doPostHook("trashed_post", &post)
+	return nil
+}
+
+// UntrashPost 从回收站还原文章
+func UntrashPost(id uint64) error {
+	db, post := GetPost(id)
+	if db.Error != nil {
+		return db.Error
+	}
+	if post.PostStatus != PostStatusTrash {
+		return ErrPostNotInTrash
+	}
+
+	status := GetPostmetaValue(id, "_wp_trash_meta_status")
+	if !IsValidPostStatus(status) || status == PostStatusTrash {
+		status = PostStatusDraft
+	}
+	if err := SetPostStatus(id, status); err != nil {
+		return err
+	}
+	DeletePostmetaByKey(id, "_wp_trash_meta_status")
+	DeletePostmetaByKey(id, "_wp_trash_meta_time")
+	doPostHook("untrashed_post", &post)
+	return nil
+}
+
+// RemovePost 永久删除文章及其Postmeta、评论及Commentmeta、分类关系和子文章
+func RemovePost(id uint64) error {
+	db, post := GetPost(id)
+	if db.Error != nil {
+		return db.Error
+	}
+	doPostHook("before_delete_post", &post)
+
+	var children []Post
+	Database.Where("post_parent = ? and post_type = ?", id, PostTypeRevision).Find(&children)
+	for _, child := range children {
+		if err := RemovePost(child.ID); err != nil {
+			return err
+		}
+	}
+	Database.Model(&Post{}).Where("post_parent = ?", id).Update("post_parent", post.PostParent)
+
+	termTaxonomyIDs := getObjectTermTaxonomyIDs(id)
+	var commentIDs []uint64
+	Database.Model(&Comment{}).Where("comment_post_id = ?", id).Pluck("id", &commentIDs)
+	tx := Database.Begin()
+	if len(commentIDs) > 0 {
+		if err := tx.Delete(Commentmeta{}, "comment_id in (?)", commentIDs).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	for _, value := range []interface{}{Postmeta{}, Comment{}, TermRelationship{}, Post{}} {
+		var where string
+		switch value.(type) {
+		case Postmeta:
+			where = "post_id = ?"
+		case Comment:
+			where = "comment_post_id = ?"
+		case TermRelationship:
+			where = "object_id = ?"
+		default:
+			where = "id = ?"
+		}
+		if err := tx.Delete(value, where, id).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+	UpdateTermCount(termTaxonomyIDs...)
+	if err := CurrentSearchBackend().Remove(id); err != nil {
+		log.Printf("search index remove post %d error: %v", id, err)
+	}
+
+	doPostHook("deleted_post", &post)
+	return nil
+}
+
+// PublishFuturePosts 发布所有已到发布时间的定时文章，返回发布数量
+func PublishFuturePosts(now time.Time) (int, error) {
+	var posts []Post
+	if err := Database.Where("post_status = ? and post_date <= ?", PostStatusFuture, now).Find(&posts).Error; err != nil {
+		return 0, err
+	}
+	for _, post := range posts {
+		if err := setPostStatus(post.ID, PostStatusPublish, now); err != nil {
+			return 0, err
+		}
+	}
+	return len(posts), nil
+}
+
+// StartFuturePostScheduler 按间隔检查并发布定时文章，向返回的通道发送数据即停止
+func StartFuturePostScheduler(interval time.Duration) chan<- bool {
+	stop := make(chan bool)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if n, err := PublishFuturePosts(time.Now()); err != nil {
+					log.Println("PublishFuturePosts error:", err)
+				} else if n > 0 {
+					log.Printf("PublishFuturePosts published %d posts\n", n)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return stop
+}
+
+// UpdatePostCommentCount 根据已审核评论重新统计文章评论数
+func UpdatePostCommentCount(postID uint64) error {
+	var count uint64
+	if err := Database.Model(&Comment{}).Where("comment_post_id = ? and comment_approved = ?", postID, "1").Count(&count).Error; err != nil {
+		return err
+	}
+	return Database.Model(&Post{}).Where("id = ?", postID).UpdateColumn("comment_count", count).Error
+}
+
+func preparePost(post *Post, now time.Time) error {
+	if post.PostStatus == "" {
+		post.PostStatus = PostStatusDraft
+	}
+	if !IsValidPostStatus(post.PostStatus) {
+		return ErrInvalidPostStatus
+	}
+	if post.PostType == "" {
+		post.PostType = "post"
+	}
+	if post.CommentStatus == "" {
+		post.CommentStatus = "open"
+	}
+	if post.PingStatus == "" {
+		post.PingStatus = "open"
+	}
+
+	if post.PostDate.IsZero() {
+		post.PostDate = now
+	}
+	post.PostDateGmt = post.PostDate.UTC()
+
+	switch {
+	case post.PostStatus == PostStatusPublish && post.PostDate.After(now):
+		post.PostStatus = PostStatusFuture
+	case post.PostStatus == PostStatusFuture && !post.PostDate.After(now):
+		post.PostStatus = PostStatusPublish
+	}
+
+	preparePostName(post)
+
+	post.PostModified = now
+	post.PostModifiedGmt = now.UTC()
+	return nil
+}
+
+func transitionPostStatus(post *Post, oldStatus string) {
+	b, _ := json.Marshal(&PostTransition{
+		PostID:    post.ID,
+		PostType:  post.PostType,
+		OldStatus: oldStatus,
+		NewStatus: post.PostStatus,
+	})
+
+	hook.ApplyFilterHook("transition_post_status", b)
+	indexPostForSearch(post)
+	if oldStatus != post.PostStatus {
+		if oldStatus == PostStatusPublish || post.PostStatus == PostStatusPublish {
+			updatePostTermCounts(post.ID)
+		}
+		hook.ApplyFilterHook(fmt.Sprintf("%s_to_%s", oldStatus, post.PostStatus), b)
+	}
+	hook.ApplyFilterHook(fmt.Sprintf("%s_%s", post.PostStatus, post.PostType), b)
+}
+
+func doPostHook(key string, post *Post) {
+	b, _ := json.Marshal(post)
+	hook.ApplyFilterHook(key, b)
+}
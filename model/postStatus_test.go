@@ -0,0 +1,121 @@
+package model_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/insionng/zenpress/model"
+	"github.com/insionng/zenpress/module/hook"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInsertPost(t *testing.T) {
+	assert := assert.New(t)
+	post := &model.Post{PostTitle: "测试文章状态", PostContent: "测试文章内容"}
+	if !assert.NoError(model.InsertPost(post)) {
+		return
+	}
+	defer model.RemovePost(post.ID)
+
+	assert.Equal(model.PostStatusDraft, post.PostStatus)
+	assert.Equal("post", post.PostType)
+	assert.False(post.PostModified.IsZero())
+
+	post.PostStatus = "unknown"
+	assert.Equal(model.ErrInvalidPostStatus, model.SavePost(post))
+}
+
+func TestFuturePost(t *testing.T) {
+	assert := assert.New(t)
+	post := &model.Post{
+		PostTitle:  "测试定时文章",
+		PostStatus: model.PostStatusPublish,
+		PostDate:   time.Now().Add(time.Hour),
+	}
+	if !assert.NoError(model.InsertPost(post)) {
+		return
+	}
+	defer model.RemovePost(post.ID)
+	assert.Equal(model.PostStatusFuture, post.PostStatus, "publish with a future date should be scheduled")
+
+	var transition model.PostTransition
+	hook.AddFilterHook("future_to_publish", func(b []byte) []byte {
+		json.Unmarshal(b, &transition)
+		return b
+	})
+	n, err := model.PublishFuturePosts(time.Now().Add(2 * time.Hour))
+	if assert.NoError(err) {
+		assert.True(n >= 1)
+	}
+	_, post2 := model.GetPost(post.ID)
+	assert.Equal(model.PostStatusPublish, post2.PostStatus)
+	assert.Equal(post.ID, transition.PostID)
+}
+
+func TestTransitionHookFiresEveryTime(t *testing.T) {
+	assert := assert.New(t)
+	post := &model.Post{PostTitle: "测试状态钩子", PostStatus: model.PostStatusDraft}
+	if !assert.NoError(model.InsertPost(post)) {
+		return
+	}
+	defer model.RemovePost(post.ID)
+
+	var transitions, actions int
+	hook.AddFilterHook("draft_to_pending", func(b []byte) []byte {
+		var transition model.PostTransition
+		if json.Unmarshal(b, &transition) == nil && transition.PostID == post.ID {
+			transitions++
+		}
+		return b
+	})
+	defer hook.RemoveActionHook("draft_to_pending")
+	hook.AddFilterHook("transition_post_status", func(b []byte) []byte {
+		actions++
+		return b
+	})
+	defer hook.RemoveActionHook("transition_post_status")
+
+	for i := 0; i < 2; i++ {
+		assert.NoError(model.SetPostStatus(post.ID, model.PostStatusPending))
+		assert.NoError(model.SetPostStatus(post.ID, model.PostStatusDraft))
+	}
+	assert.Equal(2, transitions, "hooks should fire on every transition, not only the first")
+	assert.Equal(4, actions)
+
+	assert.NoError(model.SetPostStatus(post.ID, model.PostStatusPublish))
+	_, published := model.GetPost(post.ID)
+	assert.NotEmpty(published.PostName, "publishing should store the generated slug")
+}
+
+func TestTrashPost(t *testing.T) {
+	assert := assert.New(t)
+	post := &model.Post{PostTitle: "测试回收站", PostStatus: model.PostStatusPending}
+	if !assert.NoError(model.InsertPost(post)) {
+		return
+	}
+
+	assert.NoError(model.TrashPost(post.ID))
+	_, trashed := model.GetPost(post.ID)
+	assert.Equal(model.PostStatusTrash, trashed.PostStatus)
+
+	assert.NoError(model.UntrashPost(post.ID))
+	_, restored := model.GetPost(post.ID)
+	assert.Equal(model.PostStatusPending, restored.PostStatus)
+	assert.Equal(model.ErrPostNotInTrash, model.UntrashPost(post.ID))
+
+	comment := &model.Comment{CommentPostID: int64(post.ID), CommentContent: "测试评论", CommentApproved: "1"}
+	model.NewComment(comment)
+	model.AddCommentmeta(comment.ID, "rating", "5")
+	assert.NoError(model.UpdatePostCommentCount(post.ID))
+	_, counted := model.GetPost(post.ID)
+	assert.Equal(uint64(1), counted.CommentCount)
+
+	assert.NoError(model.RemovePost(post.ID))
+	db, _ := model.GetPost(post.ID)
+	assert.Error(db.Error)
+	var metas int
+	model.Database.Model(&model.Commentmeta{}).Where("comment_id = ?", comment.ID).Count(&metas)
+	assert.Equal(0, metas, "comment meta should be removed with the post")
+}
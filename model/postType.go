@@ -0,0 +1,309 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrInvalidPostType 文章类型名称无效
+	ErrInvalidPostType = errors.New("文章类型名称无效，只能由不超过20个小写字母、数字、下划线或连字符组成")
+	// ErrPostTypeNotFound 文章类型未注册
+	ErrPostTypeNotFound = errors.New("文章类型未注册")
+	// ErrBuiltinPostType 内置文章类型不能注销
+	ErrBuiltinPostType = errors.New("内置文章类型不能注销")
+
+	postTypeNameRegexp = regexp.MustCompile(`^[a-z0-9_-]{1,20}$`)
+
+	// reservedArchiveSlugs 已被前台及后台路由占用的路径
+	reservedArchiveSlugs = map[string]bool{
+		"": true, "single": true, "page": true, "category": true, "tag": true, "taxonomy": true, "author": true,
+		"attachment": true, "date": true, "archive": true, "archives": true, "search": true, "root": true,
+		"comment": true, "vote": true, "signin": true, "signout": true, "signup": true, "activate": true,
+		"forgot": true, "reset": true, "img": true, "captcha": true,
+	}
+
+	postTypes     = map[string]*PostType{}
+	postTypesLock sync.RWMutex
+)
+
+// PostTypeLabels 文章类型在后台及主题中显示的文字
+type PostTypeLabels struct {
+	Name         string `json:"name"`
+	SingularName string `json:"singular_name"`
+	AddNew       string `json:"add_new"`
+	EditItem     string `json:"edit_item"`
+	AllItems     string `json:"all_items"`
+	NotFound     string `json:"not_found"`
+}
+
+// PostTypeOptions 注册文章类型的选项
+type PostTypeOptions struct {
+	Labels       PostTypeLabels `json:"labels"`
+	Description  string         `json:"description"`
+	Public       bool           `json:"public"`
+	ShowInAdmin  bool           `json:"show_in_admin"`
+	Hierarchical bool           `json:"hierarchical"`
+	// Supports 支持的字段：title、editor、author、thumbnail、excerpt、comments、revisions、page-attributes
+	Supports []string `json:"supports"`
+	// HasArchive 是否有归档页，归档页及文章页的路由为 /{ArchiveSlug} 与 /{ArchiveSlug}/{PostName}
+	HasArchive  bool   `json:"has_archive"`
+	ArchiveSlug string `json:"archive_slug"`
+	// CapabilityType 用于生成权限名称，如 product 生成 edit_products
+	CapabilityType string            `json:"capability_type"`
+	Capabilities   map[string]string `json:"capabilities"`
+	MenuIcon       string            `json:"menu_icon"`
+	MenuPosition   int               `json:"menu_position"`
+	// ExcludeFromSearch 公开的文章类型默认可被站内搜索，为true时不建立搜索索引
+	ExcludeFromSearch bool `json:"exclude_from_search"`
+}
+
+// PostType 已注册的文章类型
+type PostType struct {
+	Name    string
+	Builtin bool
+	PostTypeOptions
+}
+
+func init() {
+	for _, pt := range []*PostType{
+		{Name: "post", Builtin: true, PostTypeOptions: PostTypeOptions{
+			Labels:       PostTypeLabels{Name: "文章", SingularName: "文章"},
+			Public:       true,
+			ShowInAdmin:  true,
+			Supports:     []string{"title", "editor", "author", "thumbnail", "excerpt", "comments", "revisions"},
+			MenuPosition: 5,
+		}},
+		{Name: "page", Builtin: true, PostTypeOptions: PostTypeOptions{
+			Labels:         PostTypeLabels{Name: "页面", SingularName: "页面"},
+			Public:         true,
+			ShowInAdmin:    true,
+			Hierarchical:   true,
+			Supports:       []string{"title", "editor", "author", "thumbnail", "page-attributes", "comments", "revisions"},
+			CapabilityType: "page",
+			MenuPosition:   20,
+		}},
+		{Name: "attachment", Builtin: true, PostTypeOptions: PostTypeOptions{
+			Labels:            PostTypeLabels{Name: "媒体", SingularName: "媒体"},
+			Public:            true,
+			Supports:          []string{"title", "author", "comments"},
+			ExcludeFromSearch: true,
+		}},
+		{Name: PostTypeRevision, Builtin: true, PostTypeOptions: PostTypeOptions{
+			Labels:   PostTypeLabels{Name: "修订版本", SingularName: "修订版本"},
+			Supports: []string{"author"},
+		}},
+		{Name: NavMenuItemPostType, Builtin: true, PostTypeOptions: PostTypeOptions{
+			Labels: PostTypeLabels{Name: "菜单项", SingularName: "菜单项"},
+		}},
+	} {
+		completePostType(pt)
+		postTypes[pt.Name] = pt
+	}
+}
+
+// RegisterPostType 注册文章类型，重复注册同名类型将覆盖原有选项，options为nil时使用默认选项
+func RegisterPostType(name string, options *PostTypeOptions) (*PostType, error) {
+	if !postTypeNameRegexp.MatchString(name) {
+		return nil, ErrInvalidPostType
+	}
+
+	pt := &PostType{Name: name}
+	if options != nil {
+		pt.PostTypeOptions = *options
+	}
+	completePostType(pt)
+
+	postTypesLock.Lock()
+	defer postTypesLock.Unlock()
+	if old, okay := postTypes[name]; okay && old.Builtin {
+		pt.Builtin = true
+	} else if pt.Public && reservedArchiveSlugs[pt.ArchiveSlug] {
+		return nil, fmt.Errorf("文章类型[%s]的归档别名[%s]为保留路径", name, pt.ArchiveSlug)
+	}
+	for _, other := range postTypes {
+		if other.Name != name && other.Public && pt.Public && other.ArchiveSlug == pt.ArchiveSlug {
+			return nil, fmt.Errorf("文章类型[%s]的归档别名[%s]已被[%s]使用", name, pt.ArchiveSlug, other.Name)
+		}
+	}
+	postTypes[name] = pt
+	return pt, nil
+}
+
+// RegisterPostTypeByMap 以map注册文章类型，键名与PostTypeOptions的json标签一致，便于在qlang中调用
+func RegisterPostTypeByMap(name string, options map[string]interface{}) (*PostType, error) {
+	b, err := json.Marshal(options)
+	if err != nil {
+		return nil, err
+	}
+	opts := new(PostTypeOptions)
+	if err := json.Unmarshal(b, opts); err != nil {
+		return nil, err
+	}
+	return RegisterPostType(name, opts)
+}
+
+// UnregisterPostType 注销文章类型，内置类型不能注销
+func UnregisterPostType(name string) error {
+	postTypesLock.Lock()
+	defer postTypesLock.Unlock()
+	pt, okay := postTypes[name]
+	if !okay {
+		return ErrPostTypeNotFound
+	}
+	if pt.Builtin {
+		return ErrBuiltinPostType
+	}
+	delete(postTypes, name)
+	return nil
+}
+
+// GetPostType 获得已注册的文章类型，未注册时返回nil
+func GetPostType(name string) *PostType {
+	postTypesLock.RLock()
+	defer postTypesLock.RUnlock()
+	return postTypes[name]
+}
+
+// GetPostTypes 获得全部已注册的文章类型，按菜单位置及名称排序
+func GetPostTypes() []*PostType {
+	postTypesLock.RLock()
+	types := make([]*PostType, 0, len(postTypes))
+	for _, pt := range postTypes {
+		types = append(types, pt)
+	}
+	postTypesLock.RUnlock()
+
+	sort.Slice(types, func(i, j int) bool {
+		if types[i].MenuPosition != types[j].MenuPosition {
+			return types[i].MenuPosition < types[j].MenuPosition
+		}
+		return types[i].Name < types[j].Name
+	})
+	return types
+}
+
+// GetCustomPostTypes 获得非内置的公开文章类型，用于生成前台路由
+func GetCustomPostTypes() []*PostType {
+	var types []*PostType
+	for _, pt := range GetPostTypes() {
+		if pt.Public && !pt.Builtin {
+			types = append(types, pt)
+		}
+	}
+	return types
+}
+
+// GetSearchPostTypes 获得可被站内搜索的文章类型名称
+func GetSearchPostTypes() []string {
+	var names []string
+	for _, pt := range GetPostTypes() {
+		if pt.Searchable() {
+			names = append(names, pt.Name)
+		}
+	}
+	return names
+}
+
+// GetAdminPostTypes 获得在后台显示管理界面的文章类型
+func GetAdminPostTypes() []*PostType {
+	var types []*PostType
+	for _, pt := range GetPostTypes() {
+		if pt.ShowInAdmin {
+			types = append(types, pt)
+		}
+	}
+	return types
+}
+
+// GetPostTypeByArchiveSlug 根据归档别名获得文章类型
+func GetPostTypeByArchiveSlug(slug string) *PostType {
+	for _, pt := range GetPostTypes() {
+		if pt.Public && !pt.Builtin && pt.ArchiveSlug == slug {
+			return pt
+		}
+	}
+	return nil
+}
+
+// PostTypeSupports 文章类型是否支持指定字段
+func PostTypeSupports(name, feature string) bool {
+	pt := GetPostType(name)
+	return pt != nil && pt.Supported(feature)
+}
+
+// Searchable 文章类型是否建立搜索索引
+func (pt *PostType) Searchable() bool {
+	return pt.Public && !pt.ExcludeFromSearch
+}
+
+// Supported 是否支持指定字段
+func (pt *PostType) Supported(feature string) bool {
+	for _, f := range pt.Supports {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
+
+// Capability 获得元权限对应的实际权限名称，如edit_posts
+func (pt *PostType) Capability(capability string) string {
+	if c, okay := pt.Capabilities[capability]; okay {
+		return c
+	}
+	return capability
+}
+
+func completePostType(pt *PostType) {
+	if len(pt.Labels.Name) == 0 {
+		pt.Labels.Name = pt.Name
+	}
+	if len(pt.Labels.SingularName) == 0 {
+		pt.Labels.SingularName = pt.Labels.Name
+	}
+	if len(pt.Labels.AddNew) == 0 {
+		pt.Labels.AddNew = "新建" + pt.Labels.SingularName
+	}
+	if len(pt.Labels.EditItem) == 0 {
+		pt.Labels.EditItem = "编辑" + pt.Labels.SingularName
+	}
+	if len(pt.Labels.AllItems) == 0 {
+		pt.Labels.AllItems = "所有" + pt.Labels.Name
+	}
+	if len(pt.Labels.NotFound) == 0 {
+		pt.Labels.NotFound = "没有找到" + pt.Labels.Name
+	}
+
+	if pt.Supports == nil {
+		pt.Supports = []string{"title", "editor"}
+	}
+	if len(pt.ArchiveSlug) == 0 {
+		pt.ArchiveSlug = pt.Name
+	}
+	pt.ArchiveSlug = strings.Trim(pt.ArchiveSlug, "/")
+
+	if len(pt.CapabilityType) == 0 {
+		pt.CapabilityType = "post"
+	}
+	singular, plural := pt.CapabilityType, pt.CapabilityType+"s"
+	capabilities := map[string]string{
+		"edit_post":          "edit_" + singular,
+		"read_post":          "read_" + singular,
+		"delete_post":        "delete_" + singular,
+		"edit_posts":         "edit_" + plural,
+		"edit_others_posts":  "edit_others_" + plural,
+		"publish_posts":      "publish_" + plural,
+		"read_private_posts": "read_private_" + plural,
+		"delete_posts":       "delete_" + plural,
+	}
+	for k, v := range pt.Capabilities {
+		capabilities[k] = v
+	}
+	pt.Capabilities = capabilities
+}
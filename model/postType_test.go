@@ -0,0 +1,57 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/insionng/zenpress/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterPostType(t *testing.T) {
+	assert := assert.New(t)
+	pt, err := model.RegisterPostTypeByMap("novel_chapter", map[string]interface{}{
+		"labels":          map[string]interface{}{"name": "章节"},
+		"public":          true,
+		"show_in_admin":   true,
+		"hierarchical":    true,
+		"has_archive":     true,
+		"archive_slug":    "chapters",
+		"supports":        []string{"title", "editor", "revisions"},
+		"capability_type": "chapter",
+	})
+	if !assert.NoError(err) {
+		return
+	}
+	defer model.UnregisterPostType("novel_chapter")
+
+	assert.Equal("章节", pt.Labels.SingularName)
+	assert.Equal("新建章节", pt.Labels.AddNew)
+	assert.Equal("edit_chapters", pt.Capability("edit_posts"))
+	assert.True(model.PostTypeSupports("novel_chapter", "revisions"))
+	assert.False(model.PostTypeSupports("novel_chapter", "comments"))
+	assert.Equal(pt, model.GetPostTypeByArchiveSlug("chapters"))
+	assert.Contains(model.GetCustomPostTypes(), pt)
+
+	_, err = model.RegisterPostType("novel", &model.PostTypeOptions{Public: true, ArchiveSlug: "chapters"})
+	assert.Error(err, "archive slug already used")
+	_, err = model.RegisterPostType("Bad Name", nil)
+	assert.Equal(model.ErrInvalidPostType, err)
+	_, err = model.RegisterPostType("gallery", &model.PostTypeOptions{Public: true, ArchiveSlug: "category"})
+	assert.Error(err, "reserved archive slug")
+	_, err = model.RegisterPostType("gallery", &model.PostTypeOptions{Public: true, ArchiveSlug: "signup"})
+	assert.Error(err, "archive slugs should not shadow front routes")
+	assert.Equal(model.ErrBuiltinPostType, model.UnregisterPostType("post"))
+
+	post := &model.Post{PostTitle: "第一章", PostName: "chapter-1", PostType: "novel_chapter", PostStatus: model.PostStatusPublish}
+	if assert.NoError(model.InsertPost(post)) {
+		defer model.RemovePost(post.ID)
+		assert.Equal("/chapters/chapter-1", model.GetPermalink(post))
+		assert.Equal(1, len(model.GetPostRevisions(post.ID)))
+
+		db, found := model.GetPublishedPostByName("novel_chapter", "chapter-1")
+		if assert.NoError(db.Error) {
+			assert.Equal(post.ID, found.ID)
+		}
+	}
+}
@@ -40,3 +40,31 @@ func DeletePostmeta(id uint64) (db *gorm.DB) {
 	db = Database.Delete(Postmeta{}, "id = ?", id)
 	return
 }
+
+// GetPostmetaByKey 获得指定文章的指定数据
+func GetPostmetaByKey(postID uint64, metaKey string) (db *gorm.DB, postmeta Postmeta) {
+	db = Database.First(&postmeta, "post_id = ? and meta_key = ?", postID, metaKey)
+	return
+}
+
+// GetPostmetaValue 获得指定文章的数据值，不存在时返回空字符串
+func GetPostmetaValue(postID uint64, metaKey string) string {
+	if db, postmeta := GetPostmetaByKey(postID, metaKey); db.Error == nil {
+		return postmeta.MetaValue
+	}
+	return ""
+}
+
+// SetPostmetaValue 设置指定文章的数据值，不存在时新增
+func SetPostmetaValue(postID uint64, metaKey, metaValue string) (db *gorm.DB) {
+	if db, postmeta := GetPostmetaByKey(postID, metaKey); db.Error == nil {
+		return Database.Model(&postmeta).Update("meta_value", metaValue)
+	}
+	return AddPostmeta(postID, metaKey, metaValue)
+}
+
+// DeletePostmetaByKey 删除指定文章的指定数据
+func DeletePostmetaByKey(postID uint64, metaKey string) (db *gorm.DB) {
+	db = Database.Delete(Postmeta{}, "post_id = ? and meta_key = ?", postID, metaKey)
+	return
+}
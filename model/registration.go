@@ -0,0 +1,329 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/insionng/zenpress/helper"
+	"github.com/insionng/zenpress/module/auth"
+)
+
+const (
+	// UsersCanRegisterOption 为1时开放注册
+	UsersCanRegisterOption = "users_can_register"
+	// DefaultRoleOption 注册用户默认所属的角色名，未设置时为订阅者
+	DefaultRoleOption = "default_role"
+	// SubscriberRoleName 没有任何后台权限的订阅者角色名
+	SubscriberRoleName = "subscriber"
+
+	// SignupActivationEmail 注册激活的邮件模板
+	SignupActivationEmail = "signup_activation"
+	// PasswordResetEmail 找回密码的邮件模板
+	PasswordResetEmail = "password_reset"
+
+	// ActivatePath 激活账号的链接路径
+	ActivatePath = "/activate"
+	// ResetPasswordPath 重设密码的链接路径
+	ResetPasswordPath = "/reset"
+
+	// PasswordMinLength 注册及重设密码时密码的最小长度
+	PasswordMinLength = 8
+	// PasswordMaxLength 密码的最大长度，bcrypt只使用前72个字节
+	PasswordMaxLength = 72
+)
+
+var (
+	// SignupActivationTTL 注册激活链接的有效期
+	SignupActivationTTL = 48 * time.Hour
+	// PasswordResetTTL 重设密码链接的有效期
+	PasswordResetTTL = 2 * time.Hour
+)
+
+// 注册及找回密码的错误
+var (
+	ErrRegistrationClosed   = errors.New("本站暂未开放注册")
+	ErrInvalidUserLogin     = errors.New("用户名须为2至30位的中文、字母、数字、下划线或减号")
+	ErrInvalidUserEmail     = errors.New("邮箱地址格式不正确")
+	ErrInvalidPassword      = fmt.Errorf("密码长度须为%d至%d位", PasswordMinLength, PasswordMaxLength)
+	ErrUserLoginExists      = errors.New("该用户名已被注册")
+	ErrUserEmailExists      = errors.New("该邮箱已被注册")
+	ErrInvalidActivationKey = errors.New("激活链接无效或已过期")
+	ErrInvalidResetKey      = errors.New("重设密码链接无效或已过期")
+	ErrUserNotFound         = errors.New("用户不存在")
+	ErrInvalidDefaultRole   = errors.New("注册用户的默认角色不存在或为管理员角色")
+)
+
+// UsersCanRegister 判断是否开放注册
+func UsersCanRegister() bool {
+	return GetOptionValue(UsersCanRegisterOption) == "1"
+}
+
+// ValidatePassword 检查密码长度是否符合要求
+func ValidatePassword(password string) error {
+	if n := len([]rune(password)); n < PasswordMinLength || len(password) > PasswordMaxLength {
+		return ErrInvalidPassword
+	}
+	return nil
+}
+
+// activationKeyHash 激活及重设密码的令牌只保存哈希
+func activationKeyHash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// signupMeta 注册申请中保存在Meta字段的数据
+type signupMeta struct {
+	UserPass string `json:"user_pass"`
+}
+
+// pendingSignup 查找尚未过期的同名或同邮箱的注册申请
+func pendingSignup(query string, value interface{}) (Signup, bool) {
+	var signup Signup
+	db := Database.Where("active = 0 and registered > ? and "+query, time.Now().Add(-SignupActivationTTL), value).First(&signup)
+	return signup, db.Error == nil
+}
+
+// Register 提交注册申请，返回申请及激活令牌，账号在激活后才会创建；同一邮箱重复申请时替换之前的申请
+func Register(login, email, password, ip string) (Signup, string, error) {
+	if !UsersCanRegister() {
+		return Signup{}, "", ErrRegistrationClosed
+	}
+	login, email = strings.TrimSpace(login), strings.ToLower(strings.TrimSpace(email))
+	if !helper.CheckUsername(login) {
+		return Signup{}, "", ErrInvalidUserLogin
+	}
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		return Signup{}, "", ErrInvalidUserEmail
+	}
+	if err := ValidatePassword(password); err != nil {
+		return Signup{}, "", err
+	}
+	if Database.Where("user_login = ?", login).First(&User{}).Error == nil {
+		return Signup{}, "", ErrUserLoginExists
+	}
+	if Database.Where("lower(user_email) = ?", email).First(&User{}).Error == nil {
+		return Signup{}, "", ErrUserEmailExists
+	}
+	if pending, found := pendingSignup("user_login = ?", login); found && pending.UserEmail != email {
+		return Signup{}, "", ErrUserLoginExists
+	}
+
+	hashed, err := helper.HashPassword(password)
+	if err != nil {
+		return Signup{}, "", err
+	}
+	meta, _ := json.Marshal(signupMeta{UserPass: hashed})
+	key := auth.NewToken()
+	signup := Signup{
+		UserLogin:     login,
+		UserEmail:     email,
+		Registered:    time.Now(),
+		ActivationKey: activationKeyHash(key)[:40],
+		Meta:          string(meta),
+	}
+	tx := Database.Begin()
+	if err = tx.Delete(Signup{}, "active = 0 and user_email = ?", email).Error; err == nil {
+		if err = tx.Create(&signup).Error; err == nil {
+			err = tx.Create(&RegistrationLog{Email: email, IP: ip, DateRegistered: signup.Registered}).Error
+		}
+	}
+	if err != nil {
+		tx.Rollback()
+		return Signup{}, "", err
+	}
+	return signup, key, tx.Commit().Error
+}
+
+// ActivationURL 获得激活账号的链接
+func ActivationURL(key string) string {
+	return GetSiteURL() + ActivatePath + "?key=" + url.QueryEscape(key)
+}
+
+// SendActivationEmail 发送注册激活邮件
+func SendActivationEmail(signup Signup, key string) bool {
+	return queueEmail(SignupActivationEmail, signup.UserEmail, map[string]interface{}{
+		"blogname":     GetBlogName(),
+		"siteurl":      GetSiteURL(),
+		"signup":       signup,
+		"activateLink": ActivationURL(key),
+		"expireHours":  int(SignupActivationTTL / time.Hour),
+	})
+}
+
+// DefaultUserRole 获得注册用户默认所属的角色，未设置或设置无效时为订阅者角色，不存在时创建
+func DefaultUserRole() (Role, error) {
+	var role Role
+	if name := GetOptionValue(DefaultRoleOption); len(name) > 0 && name != AdministratorRoleName {
+		if Database.Where("name = ?", name).First(&role).Error == nil {
+			return role, nil
+		}
+	}
+	if Database.Where("name = ?", SubscriberRoleName).First(&role).Error == nil {
+		return role, nil
+	}
+	role = Role{Name: SubscriberRoleName}
+	return role, Database.Create(&role).Error
+}
+
+// SetDefaultRole 设置注册用户默认所属的角色，为空时为订阅者，不能设为管理员角色
+func SetDefaultRole(name string) error {
+	if name = strings.TrimSpace(name); len(name) == 0 {
+		name = SubscriberRoleName
+	}
+	if name != SubscriberRoleName {
+		if name == AdministratorRoleName || Database.Where("name = ?", name).First(&Role{}).Error != nil {
+			return ErrInvalidDefaultRole
+		}
+	}
+	return SetOptionValue(DefaultRoleOption, name).Error
+}
+
+// ActivateSignup 以激活令牌激活注册申请并创建账号，账号属于注册用户的默认角色，令牌只能使用一次
+func ActivateSignup(key string) (User, error) {
+	if len(key) == 0 {
+		return User{}, ErrInvalidActivationKey
+	}
+	var signup Signup
+	if Database.Where("activation_key = ? and active = 0", activationKeyHash(key)[:40]).First(&signup).Error != nil {
+		return User{}, ErrInvalidActivationKey
+	}
+	if time.Since(signup.Registered) > SignupActivationTTL {
+		Database.Delete(&signup)
+		return User{}, ErrInvalidActivationKey
+	}
+	if Database.Where("user_login = ?", signup.UserLogin).First(&User{}).Error == nil {
+		return User{}, ErrUserLoginExists
+	}
+	if Database.Where("lower(user_email) = ?", signup.UserEmail).First(&User{}).Error == nil {
+		return User{}, ErrUserEmailExists
+	}
+
+	var meta signupMeta
+	json.Unmarshal([]byte(signup.Meta), &meta)
+	user := User{
+		UserLogin:      signup.UserLogin,
+		UserPass:       meta.UserPass,
+		UserNicename:   strings.ToLower(signup.UserLogin),
+		UserEmail:      signup.UserEmail,
+		UserRegistered: signup.Registered,
+		DisplayName:    signup.UserLogin,
+	}
+	role, err := DefaultUserRole()
+	if err != nil {
+		return User{}, err
+	}
+	tx := Database.Begin()
+	err = tx.Create(&user).Error
+	if err == nil {
+		err = tx.Exec("insert into "+DatabaseTablePrefix+"user_roles (user_id, role_id) values (?, ?)", user.ID, role.ID).Error
+	}
+	if err == nil {
+		err = tx.Model(&Signup{}).Where("signup_id = ?", signup.SignupID).Updates(map[string]interface{}{
+			"active":         1,
+			"activated":      time.Now(),
+			"activation_key": "",
+			"meta":           "",
+		}).Error
+	}
+	if err != nil {
+		tx.Rollback()
+		return User{}, err
+	}
+	if err = tx.Commit().Error; err != nil {
+		return User{}, err
+	}
+	if Enforcer != nil {
+		Enforcer.AddRoleForUser(fmt.Sprint(user.ID), roleSubject(role.ID))
+	}
+	return user, nil
+}
+
+// FindUserByLoginOrEmail 以登录名或邮箱查找用户
+func FindUserByLoginOrEmail(login string) (bool, User) {
+	var user User
+	login = strings.TrimSpace(login)
+	query := "user_login = ?"
+	if strings.Contains(login, "@") {
+		query, login = "lower(user_email) = ?", strings.ToLower(login)
+	}
+	db := Database.Where(query, login).First(&user)
+	return db.Error == nil, user
+}
+
+// RequestPasswordReset 为登录名或邮箱对应的用户生成重设密码令牌，之前的令牌随之失效
+func RequestPasswordReset(login string) (User, string, error) {
+	found, user := FindUserByLoginOrEmail(login)
+	if !found || len(login) == 0 || user.Deleted != 0 || user.Spam != 0 {
+		return User{}, "", ErrUserNotFound
+	}
+	key := auth.NewToken()
+	value := fmt.Sprintf("%d:%s", time.Now().Unix(), activationKeyHash(key))
+	if err := Database.Model(&User{}).Where("id = ?", user.ID).Update("user_activation_key", value).Error; err != nil {
+		return User{}, "", err
+	}
+	user.UserActivationKey = value
+	return user, key, nil
+}
+
+// PasswordResetURL 获得重设密码的链接
+func PasswordResetURL(user User, key string) string {
+	return GetSiteURL() + ResetPasswordPath + "?login=" + url.QueryEscape(user.UserLogin) + "&key=" + url.QueryEscape(key)
+}
+
+// SendPasswordResetEmail 发送找回密码邮件
+func SendPasswordResetEmail(user User, key string) bool {
+	return queueEmail(PasswordResetEmail, user.UserEmail, map[string]interface{}{
+		"blogname":    GetBlogName(),
+		"siteurl":     GetSiteURL(),
+		"user":        user,
+		"resetLink":   PasswordResetURL(user, key),
+		"expireHours": int(PasswordResetTTL / time.Hour),
+	})
+}
+
+// CheckPasswordResetKey 校验重设密码令牌，成功时返回对应的用户
+func CheckPasswordResetKey(login, key string) (User, error) {
+	found, user := FindUserByUserName(login)
+	if !found || len(login) == 0 || len(key) == 0 {
+		return User{}, ErrInvalidResetKey
+	}
+	parts := strings.SplitN(user.UserActivationKey, ":", 2)
+	if len(parts) != 2 {
+		return User{}, ErrInvalidResetKey
+	}
+	if issued, _ := strconv.ParseInt(parts[0], 10, 64); time.Since(time.Unix(issued, 0)) > PasswordResetTTL {
+		return User{}, ErrInvalidResetKey
+	}
+	if !auth.VerifyToken(parts[1], activationKeyHash(key)) {
+		return User{}, ErrInvalidResetKey
+	}
+	return user, nil
+}
+
+// ResetPassword 以重设密码令牌设置新密码，令牌只能使用一次，成功后已登录的会话、“记住登录”令牌及个人访问令牌全部失效
+func ResetPassword(login, key, password string) (User, error) {
+	user, err := CheckPasswordResetKey(login, key)
+	if err != nil {
+		return User{}, err
+	}
+	if err = ValidatePassword(password); err != nil {
+		return User{}, err
+	}
+	if err = SetUserPassword(&user, password); err != nil {
+		return User{}, err
+	}
+	Database.Model(&User{}).Where("id = ?", user.ID).Update("user_activation_key", "")
+	user.UserActivationKey = ""
+	RevokeUserLogins(user.ID)
+	LoginAccountThrottle.Reset(strings.ToLower(user.UserLogin))
+	return user, nil
+}
@@ -0,0 +1,195 @@
+package model_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/insionng/zenpress/helper"
+	"github.com/insionng/zenpress/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegister(t *testing.T) {
+	assert := assert.New(t)
+	defer func(cost int) { helper.PasswordHashCost = cost }(helper.PasswordHashCost)
+	helper.PasswordHashCost = 4
+	defer model.DeleteOption(model.UsersCanRegisterOption)
+
+	login := fmt.Sprintf("reg%d", time.Now().UnixNano()%1e9)
+	email := login + "@Example.com"
+	_, _, err := model.Register(login, email, "password", "10.0.0.1")
+	assert.Equal(model.ErrRegistrationClosed, err)
+
+	model.SetOptionValue(model.UsersCanRegisterOption, "1")
+	_, _, err = model.Register("a", email, "password", "10.0.0.1")
+	assert.Equal(model.ErrInvalidUserLogin, err)
+	_, _, err = model.Register(login, "not-an-email", "password", "10.0.0.1")
+	assert.Equal(model.ErrInvalidUserEmail, err)
+	_, _, err = model.Register(login, email, "short", "10.0.0.1")
+	assert.Equal(model.ErrInvalidPassword, err)
+
+	signup, key, err := model.Register(login, email, "password", "10.0.0.1")
+	if !assert.NoError(err) {
+		return
+	}
+	defer model.Database.Delete(model.Signup{}, "user_login = ?", login)
+	defer model.Database.Delete(model.RegistrationLog{}, "email = ?", signup.UserEmail)
+	assert.Equal(strings.ToLower(email), signup.UserEmail)
+	assert.NotContains(signup.ActivationKey, key, "only the hash of the key should be stored")
+	var logs []model.RegistrationLog
+	model.Database.Where("email = ?", signup.UserEmail).Find(&logs)
+	if assert.Len(logs, 1) {
+		assert.Equal("10.0.0.1", logs[0].IP)
+	}
+
+	_, _, err = model.Register(login, "other"+email, "password", "10.0.0.2")
+	assert.Equal(model.ErrUserLoginExists, err, "a pending signup should reserve the login")
+	_, key, err = model.Register(login, email, "password2", "10.0.0.3")
+	assert.NoError(err, "signing up again with the same email should replace the pending signup")
+
+	_, err = model.ActivateSignup("wrong" + key)
+	assert.Equal(model.ErrInvalidActivationKey, err)
+	user, err := model.ActivateSignup(key)
+	if !assert.NoError(err) {
+		return
+	}
+	defer model.Database.Delete(&user)
+	assert.Equal(login, user.UserLogin)
+	ok, _ := model.Login(login, "password2")
+	assert.True(ok)
+	_, err = model.ActivateSignup(key)
+	assert.Equal(model.ErrInvalidActivationKey, err, "keys should be single-use")
+
+	_, _, err = model.Register(login, "new"+email, "password", "10.0.0.4")
+	assert.Equal(model.ErrUserLoginExists, err)
+	_, _, err = model.Register("new"+login, email, "password", "10.0.0.4")
+	assert.Equal(model.ErrUserEmailExists, err)
+
+	defer func(ttl time.Duration) { model.SignupActivationTTL = ttl }(model.SignupActivationTTL)
+	_, key, _ = model.Register("late"+login, "late"+email, "password", "10.0.0.5")
+	defer model.Database.Delete(model.Signup{}, "user_login = ?", "late"+login)
+	model.SignupActivationTTL = -time.Second
+	_, err = model.ActivateSignup(key)
+	assert.Equal(model.ErrInvalidActivationKey, err, "expired keys should be rejected")
+}
+
+func TestActivatedUserRole(t *testing.T) {
+	assert := assert.New(t)
+	defer func(cost int) { helper.PasswordHashCost = cost }(helper.PasswordHashCost)
+	helper.PasswordHashCost = 4
+	loadEnforcer(t)
+	model.SetOptionValue(model.UsersCanRegisterOption, "1")
+	defer model.DeleteOption(model.UsersCanRegisterOption)
+
+	assert.Equal(model.ErrInvalidDefaultRole, model.SetDefaultRole(model.AdministratorRoleName))
+	assert.Equal(model.ErrInvalidDefaultRole, model.SetDefaultRole("missing"))
+	model.SetOptionValue(model.DefaultRoleOption, model.AdministratorRoleName)
+	defer model.DeleteOption(model.DefaultRoleOption)
+
+	login := fmt.Sprintf("member%d", time.Now().UnixNano())
+	_, key, err := model.Register(login, login+"@example.com", "password", "10.0.0.1")
+	if !assert.NoError(err) {
+		return
+	}
+	defer model.Database.Delete(model.Signup{}, "user_login = ?", login)
+	user, err := model.ActivateSignup(key)
+	if !assert.NoError(err) {
+		return
+	}
+	defer model.Database.Delete(&user)
+	defer model.DeleteUserRolesByUserId(int(user.ID))
+
+	role, _ := model.DefaultUserRole()
+	assert.Equal(model.SubscriberRoleName, role.Name, "the administrator role should never be the default")
+	var count int
+	model.Database.Table(model.DatabaseTablePrefix+"user_roles").Where("user_id = ? and role_id = ?", user.ID, role.ID).Count(&count)
+	assert.Equal(1, count)
+	assert.False(model.CanAccessAdmin(user.ID, "/root/"), "self-registered users should not access the admin")
+	model.LoadEnforcer()
+	assert.False(model.CanAccessAdmin(user.ID, "/root/"))
+}
+
+func TestPasswordReset(t *testing.T) {
+	assert := assert.New(t)
+	defer func(cost int) { helper.PasswordHashCost = cost }(helper.PasswordHashCost)
+	helper.PasswordHashCost = 4
+
+	login := fmt.Sprintf("reset%d", time.Now().UnixNano())
+	hashed, _ := helper.HashPassword("oldpassword")
+	user := &model.User{UserLogin: login, UserEmail: login + "@example.com", UserPass: hashed}
+	model.SaveUser(user)
+	defer model.Database.Delete(user)
+	remember, _ := model.CreateRememberToken(user.ID)
+	defer model.RevokeUserRememberTokens(user.ID)
+	_, secret, _ := model.CreateAccessToken(user.ID, "deploy", "read", 0)
+	defer model.Database.Delete(model.AccessToken{}, "user_id = ?", user.ID)
+	defer model.DeleteUsermetaByKey(user.ID, model.SessionEpochMetaKey)
+	epoch := model.UserSessionEpoch(user.ID)
+
+	_, _, err := model.RequestPasswordReset("nobody" + login)
+	assert.Equal(model.ErrUserNotFound, err)
+	found, key, err := model.RequestPasswordReset(strings.ToUpper(user.UserEmail))
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(user.ID, found.ID)
+	assert.NotContains(found.UserActivationKey, key)
+	assert.Contains(model.PasswordResetURL(found, key), "/reset?login="+login+"&key=")
+
+	_, err = model.CheckPasswordResetKey(login, key+"x")
+	assert.Equal(model.ErrInvalidResetKey, err)
+	_, err = model.ResetPassword(login, key, "short")
+	assert.Equal(model.ErrInvalidPassword, err)
+	_, err = model.ResetPassword(login, key, "newpassword")
+	assert.NoError(err)
+	ok, _ := model.Login(login, "newpassword")
+	assert.True(ok)
+	ok, _ = model.ValidateRememberToken(remember)
+	assert.False(ok, "resetting the password should sign out remembered sessions")
+	assert.NotEqual(epoch, model.UserSessionEpoch(user.ID), "resetting the password should sign out current sessions")
+	ok, _, _ = model.FindAccessToken(secret)
+	assert.False(ok, "resetting the password should revoke access tokens")
+	_, err = model.ResetPassword(login, key, "otherpassword")
+	assert.Equal(model.ErrInvalidResetKey, err, "keys should be single-use")
+
+	_, key, _ = model.RequestPasswordReset(login)
+	_, first, _ := model.RequestPasswordReset(login)
+	_, err = model.CheckPasswordResetKey(login, key)
+	assert.Equal(model.ErrInvalidResetKey, err, "a new request should invalidate older keys")
+	defer func(ttl time.Duration) { model.PasswordResetTTL = ttl }(model.PasswordResetTTL)
+	model.PasswordResetTTL = -time.Second
+	_, err = model.CheckPasswordResetKey(login, first)
+	assert.Equal(model.ErrInvalidResetKey, err, "expired keys should be rejected")
+}
+
+func TestRegistrationEmails(t *testing.T) {
+	assert := assert.New(t)
+	fake := &fakeMailer{}
+	defer model.SetMailer(nil)
+	model.SetMailer(fake)
+	model.ProcessMailQueue(time.Now())
+	fake.sent = nil
+	defer model.SetEmailRenderer(nil)
+	model.SetEmailRenderer(func(name string, data map[string]interface{}) (string, error) {
+		link := data["activateLink"]
+		if name == model.PasswordResetEmail {
+			link = data["resetLink"]
+		}
+		return fmt.Sprintf("Subject: %s\n%v", name, link), nil
+	})
+	defer model.DeleteOption(model.SiteURLOption)
+	model.SetOptionValue(model.SiteURLOption, "https://example.com/")
+
+	assert.True(model.SendActivationEmail(model.Signup{UserLogin: "mailer", UserEmail: "signup@example.com"}, "a+b"))
+	assert.True(model.SendPasswordResetEmail(model.User{UserLogin: "mailer", UserEmail: "reset@example.com"}, "c"))
+	model.ProcessMailQueue(time.Now())
+	if assert.Len(fake.sent, 2) {
+		assert.Equal([]string{"signup@example.com"}, fake.sent[0].To)
+		assert.Equal(model.SignupActivationEmail, fake.sent[0].Subject)
+		assert.Equal("https://example.com/activate?key=a%2Bb", fake.sent[0].HTML)
+		assert.Equal("https://example.com/reset?login=mailer&key=c", fake.sent[1].HTML)
+	}
+}
@@ -0,0 +1,200 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/insionng/zenpress/helper"
+)
+
+const (
+	// PostTypeRevision 修订版本在Post中的文章类型
+	PostTypeRevision = "revision"
+	// PostRevisionsOption 修订版本数量限制的设置项，-1为不限制，0为不保存修订版本
+	PostRevisionsOption = "post_revisions"
+)
+
+var (
+	// ErrNotRevision 文章不是修订版本
+	ErrNotRevision = errors.New("文章不是修订版本")
+)
+
+// RevisionDiff 两个修订版本之间的差异
+type RevisionDiff struct {
+	From    Post
+	To      Post
+	Title   []helper.DiffLine
+	Content []helper.DiffLine
+	Excerpt []helper.DiffLine
+}
+
+// GetPostRevisionsLimit 获得修订版本数量限制
+func GetPostRevisionsLimit() int {
+	limit, err := strconv.Atoi(GetOptionValue(PostRevisionsOption, "-1"))
+	if err != nil {
+		return -1
+	}
+	return limit
+}
+
+// SavePostRevision 以文章当前内容保存修订版本，内容与最近的修订版本相同时不保存
+func SavePostRevision(postID uint64) (*Post, error) {
+	db, post := GetPost(postID)
+	if db.Error != nil {
+		return nil, db.Error
+	}
+	if !PostTypeSupports(post.PostType, "revisions") || post.PostStatus == PostStatusAutoDraft {
+		return nil, nil
+	}
+
+	limit := GetPostRevisionsLimit()
+	if limit == 0 {
+		return nil, nil
+	}
+
+	revisions := GetPostRevisions(postID)
+	if len(revisions) > 0 && samePostContent(&revisions[0], &post) {
+		return nil, nil
+	}
+
+	revision := newRevision(&post, fmt.Sprintf("%d-revision-v1", postID))
+	if err := NewPost(revision).Error; err != nil {
+		return nil, err
+	}
+
+	if limit > 0 && len(revisions)+1 > limit {
+		for _, old := range revisions[limit-1:] {
+			if err := DeletePost(old.ID).Error; err != nil {
+				return revision, err
+			}
+		}
+	}
+	return revision, nil
+}
+
+// AutosavePost 自动保存文章，每位作者对每篇文章只保留一份自动保存
+func AutosavePost(postID, authorID uint64, title, content, excerpt string) (*Post, error) {
+	db, post := GetPost(postID)
+	if db.Error != nil {
+		return nil, db.Error
+	}
+
+	post.PostTitle = title
+	post.PostContent = content
+	post.PostExcerpt = excerpt
+	post.PostAuthor = authorID
+
+	autosave := GetPostAutosave(postID, authorID)
+	if autosave == nil {
+		autosave = newRevision(&post, fmt.Sprintf("%d-autosave-v1", postID))
+		return autosave, NewPost(autosave).Error
+	}
+
+	now := time.Now()
+	autosave.PostTitle = title
+	autosave.PostContent = content
+	autosave.PostExcerpt = excerpt
+	autosave.PostModified = now
+	autosave.PostModifiedGmt = now.UTC()
+	return autosave, Database.Save(autosave).Error
+}
+
+// GetPostAutosave 获得作者对文章的自动保存，不存在时返回nil
+func GetPostAutosave(postID, authorID uint64) *Post {
+	var autosave Post
+	if Database.Where("post_parent = ? and post_type = ? and post_name = ? and post_author = ?",
+		postID, PostTypeRevision, fmt.Sprintf("%d-autosave-v1", postID), authorID).First(&autosave).Error != nil {
+		return nil
+	}
+	return &autosave
+}
+
+// DeletePostAutosaves 删除文章的全部自动保存
+func DeletePostAutosaves(postID uint64) error {
+	return Database.Delete(Post{}, "post_parent = ? and post_type = ? and post_name = ?",
+		postID, PostTypeRevision, fmt.Sprintf("%d-autosave-v1", postID)).Error
+}
+
+// GetPostRevisions 获得文章的修订版本，不含自动保存，按时间倒序
+func GetPostRevisions(postID uint64) (revisions []Post) {
+	Database.Where("post_parent = ? and post_type = ? and post_name <> ?",
+		postID, PostTypeRevision, fmt.Sprintf("%d-autosave-v1", postID)).Order("post_date desc, id desc").Find(&revisions)
+	return
+}
+
+// GetPostRevision 获得修订版本
+func GetPostRevision(id uint64) (*Post, error) {
+	db, revision := GetPost(id)
+	if db.Error != nil {
+		return nil, db.Error
+	}
+	if revision.PostType != PostTypeRevision {
+		return nil, ErrNotRevision
+	}
+	return &revision, nil
+}
+
+// DiffPostRevisions 比较两个修订版本或文章的标题、内容及摘要
+func DiffPostRevisions(fromID, toID uint64) (*RevisionDiff, error) {
+	db, from := GetPost(fromID)
+	if db.Error != nil {
+		return nil, db.Error
+	}
+	db, to := GetPost(toID)
+	if db.Error != nil {
+		return nil, db.Error
+	}
+	return &RevisionDiff{
+		From:    from,
+		To:      to,
+		Title:   helper.DiffLines(from.PostTitle, to.PostTitle),
+		Content: helper.DiffLines(from.PostContent, to.PostContent),
+		Excerpt: helper.DiffLines(from.PostExcerpt, to.PostExcerpt),
+	}, nil
+}
+
+// RestorePostRevision 以修订版本还原文章内容，还原本身也会保存为新的修订版本
+func RestorePostRevision(revisionID uint64) (*Post, error) {
+	revision, err := GetPostRevision(revisionID)
+	if err != nil {
+		return nil, err
+	}
+	db, post := GetPost(revision.PostParent)
+	if db.Error != nil {
+		return nil, db.Error
+	}
+
+	post.PostTitle = revision.PostTitle
+	post.PostContent = revision.PostContent
+	post.PostExcerpt = revision.PostExcerpt
+	if err := SavePost(&post); err != nil {
+		return nil, err
+	}
+	return &post, nil
+}
+
+func newRevision(post *Post, name string) *Post {
+	now := time.Now()
+	return &Post{
+		PostAuthor:      post.PostAuthor,
+		PostDate:        now,
+		PostDateGmt:     now.UTC(),
+		PostModified:    now,
+		PostModifiedGmt: now.UTC(),
+		PostTitle:       post.PostTitle,
+		PostContent:     post.PostContent,
+		PostExcerpt:     post.PostExcerpt,
+		PostStatus:      PostStatusInherit,
+		CommentStatus:   "closed",
+		PingStatus:      "closed",
+		PostName:        name,
+		PostParent:      post.ID,
+		PostType:        PostTypeRevision,
+	}
+}
+
+func samePostContent(a, b *Post) bool {
+	return a.PostTitle == b.PostTitle && a.PostContent == b.PostContent && a.PostExcerpt == b.PostExcerpt
+}
@@ -0,0 +1,91 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/insionng/zenpress/helper"
+	"github.com/insionng/zenpress/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostRevisions(t *testing.T) {
+	assert := assert.New(t)
+	post := &model.Post{PostTitle: "测试修订", PostContent: "第一行\n第二行"}
+	if !assert.NoError(model.InsertPost(post)) {
+		return
+	}
+	defer model.RemovePost(post.ID)
+	assert.Equal(1, len(model.GetPostRevisions(post.ID)))
+
+	post.PostContent = "第一行\n第三行"
+	assert.NoError(model.SavePost(post))
+	assert.NoError(model.SavePost(post), "unchanged content should not add a revision")
+	revisions := model.GetPostRevisions(post.ID)
+	if !assert.Equal(2, len(revisions)) {
+		return
+	}
+
+	diff, err := model.DiffPostRevisions(revisions[1].ID, revisions[0].ID)
+	if assert.NoError(err) {
+		assert.Equal([]helper.DiffLine{
+			{Type: helper.DiffEqual, OldLine: 1, NewLine: 1, Text: "第一行"},
+			{Type: helper.DiffDelete, OldLine: 2, Text: "第二行"},
+			{Type: helper.DiffInsert, NewLine: 2, Text: "第三行"},
+		}, diff.Content)
+	}
+
+	restored, err := model.RestorePostRevision(revisions[1].ID)
+	if assert.NoError(err) {
+		assert.Equal("第一行\n第二行", restored.PostContent)
+		assert.Equal(3, len(model.GetPostRevisions(post.ID)))
+	}
+
+	_, err = model.RestorePostRevision(post.ID)
+	assert.Equal(model.ErrNotRevision, err)
+}
+
+func TestPostRevisionsLimit(t *testing.T) {
+	assert := assert.New(t)
+	assert.NoError(model.SetOptionValue(model.PostRevisionsOption, "2").Error)
+	defer model.SetOptionValue(model.PostRevisionsOption, "-1")
+
+	post := &model.Post{PostTitle: "测试修订数量", PostContent: "1"}
+	if !assert.NoError(model.InsertPost(post)) {
+		return
+	}
+	defer model.RemovePost(post.ID)
+	for _, content := range []string{"2", "3", "4"} {
+		post.PostContent = content
+		assert.NoError(model.SavePost(post))
+	}
+
+	revisions := model.GetPostRevisions(post.ID)
+	if assert.Equal(2, len(revisions)) {
+		assert.Equal("4", revisions[0].PostContent)
+		assert.Equal("3", revisions[1].PostContent)
+	}
+}
+
+func TestAutosavePost(t *testing.T) {
+	assert := assert.New(t)
+	post := &model.Post{PostTitle: "测试自动保存", PostContent: "内容", PostAuthor: 1}
+	if !assert.NoError(model.InsertPost(post)) {
+		return
+	}
+	defer model.RemovePost(post.ID)
+
+	_, err := model.AutosavePost(post.ID, 1, "测试自动保存", "内容一", "")
+	assert.NoError(err)
+	_, err = model.AutosavePost(post.ID, 1, "测试自动保存", "内容二", "")
+	assert.NoError(err)
+
+	autosave := model.GetPostAutosave(post.ID, 1)
+	if assert.NotNil(autosave) {
+		assert.Equal("内容二", autosave.PostContent)
+	}
+	assert.Equal(1, len(model.GetPostRevisions(post.ID)), "autosaves are not listed as revisions")
+
+	assert.NoError(model.SavePost(post))
+	assert.Nil(model.GetPostAutosave(post.ID, 1))
+}
@@ -0,0 +1,318 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/insionng/zenpress/helper"
+	"github.com/insionng/zenpress/module/hook"
+)
+
+const (
+	// SearchBackendOption 站内搜索后端的选项名，为空时按数据库类型选择
+	SearchBackendOption = "search_backend"
+
+	// SearchBackendSqlite sqlite的FTS5全文索引，需以sqlite_fts5编译标签构建
+	SearchBackendSqlite = "sqlite_fts5"
+	// SearchBackendMysql MySQL的FULLTEXT索引，使用ngram分词器
+	SearchBackendMysql = "mysql_fulltext"
+	// SearchBackendPostgres Postgres的tsvector及GIN索引
+	SearchBackendPostgres = "postgres_tsvector"
+	// SearchBackendIndex 内置的倒排索引，保存在content/storage/search目录下
+	SearchBackendIndex = "index"
+
+	// searchSnippetWidth 搜索结果摘要的字符数
+	searchSnippetWidth = 120
+)
+
+var (
+	// ErrSearchBackendNotFound 搜索后端未注册
+	ErrSearchBackendNotFound = errors.New("搜索后端未注册")
+
+	searchBackends       = map[string]SearchBackend{}
+	searchBackendsLock   sync.RWMutex
+	currentSearchBackend SearchBackend
+)
+
+// SearchDocument 建立索引的文章内容，Content为去除HTML后的正文及摘要
+type SearchDocument struct {
+	PostID   uint64
+	PostType string
+	Title    string
+	Content  string
+}
+
+// SearchHit 搜索后端返回的匹配文章及相关度
+type SearchHit struct {
+	PostID uint64
+	Score  float64
+}
+
+// SearchBackend 站内搜索后端，检索词由SearchTokenize切分，多个检索词须同时匹配
+type SearchBackend interface {
+	Name() string
+	// Open 准备索引所需的表或文件，不可用时返回错误
+	Open() error
+	Index(docs ...*SearchDocument) error
+	Remove(postIDs ...uint64) error
+	Clear() error
+	// Search 按相关度从高到低返回第offset条起的limit条结果及匹配总数
+	Search(tokens []string, postTypes []string, offset, limit int) ([]SearchHit, int, error)
+}
+
+// SearchQuery 搜索条件，Tokens为空时由Keyword切分，可由pre_search_query过滤钩子修改
+type SearchQuery struct {
+	Keyword   string   `json:"keyword"`
+	Tokens    []string `json:"tokens"`
+	PostTypes []string `json:"post_types"`
+	PageNo    int      `json:"page_no"`
+	PageSize  int      `json:"page_size"`
+}
+
+// SearchResult 搜索结果，Title及Snippet为已转义并以<mark>标记检索词的HTML
+type SearchResult struct {
+	Post    Post    `json:"post"`
+	Score   float64 `json:"score"`
+	Title   string  `json:"title"`
+	Snippet string  `json:"snippet"`
+	Link    string  `json:"link"`
+}
+
+func init() {
+	RegisterSearchBackend(&sqliteSearchBackend{})
+	RegisterSearchBackend(&mysqlSearchBackend{})
+	RegisterSearchBackend(&postgresSearchBackend{})
+	RegisterSearchBackend(newIndexSearchBackend("content/storage/search"))
+}
+
+// RegisterSearchBackend 注册搜索后端，插件可注册其他实现，同名后端将被替换
+func RegisterSearchBackend(backend SearchBackend) {
+	searchBackendsLock.Lock()
+	defer searchBackendsLock.Unlock()
+	searchBackends[backend.Name()] = backend
+	if currentSearchBackend != nil && currentSearchBackend.Name() == backend.Name() {
+		currentSearchBackend = nil
+	}
+}
+
+// GetSearchBackends 获得已注册的搜索后端名称
+func GetSearchBackends() []string {
+	searchBackendsLock.RLock()
+	defer searchBackendsLock.RUnlock()
+	var names []string
+	for name := range searchBackends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CurrentSearchBackend 获得当前使用的搜索后端，所选后端不可用时使用内置的倒排索引
+func CurrentSearchBackend() SearchBackend {
+	searchBackendsLock.Lock()
+	defer searchBackendsLock.Unlock()
+	if currentSearchBackend != nil {
+		return currentSearchBackend
+	}
+
+	name := GetOptionValue(SearchBackendOption)
+	if len(name) == 0 {
+		name = defaultSearchBackend()
+	}
+	backend, okay := searchBackends[name]
+	if okay {
+		if err := backend.Open(); err != nil {
+			log.Printf("search backend %s unavailable: %v", name, err)
+			okay = false
+		}
+	}
+	if !okay {
+		backend = searchBackends[SearchBackendIndex]
+		if err := backend.Open(); err != nil {
+			log.Println("open search index error:", err)
+		}
+	}
+	currentSearchBackend = backend
+	return backend
+}
+
+// SetSearchBackend 切换搜索后端并重建索引，name为空时按数据库类型选择
+func SetSearchBackend(name string) (int, error) {
+	searchBackendsLock.RLock()
+	backend, okay := searchBackends[name]
+	if len(name) == 0 {
+		backend, okay = searchBackends[defaultSearchBackend()]
+	}
+	searchBackendsLock.RUnlock()
+	if !okay {
+		return 0, ErrSearchBackendNotFound
+	}
+	if err := backend.Open(); err != nil {
+		return 0, err
+	}
+	if err := SetOptionValue(SearchBackendOption, name).Error; err != nil {
+		return 0, err
+	}
+
+	searchBackendsLock.Lock()
+	currentSearchBackend = backend
+	searchBackendsLock.Unlock()
+	return RebuildSearchIndex()
+}
+
+// RebuildSearchIndex 清空并重建当前搜索后端的索引，返回建立索引的文章数
+func RebuildSearchIndex() (int, error) {
+	backend := CurrentSearchBackend()
+	if err := backend.Clear(); err != nil {
+		return 0, err
+	}
+
+	postTypes := GetSearchPostTypes()
+	if len(postTypes) == 0 {
+		return 0, nil
+	}
+	var count int
+	var lastID uint64
+	for {
+		var posts []Post
+		if err := Database.Where("id > ? and post_status = ? and post_type in (?)", lastID, PostStatusPublish, postTypes).
+			Order("id").Limit(200).Find(&posts).Error; err != nil {
+			return count, err
+		}
+		if len(posts) == 0 {
+			return count, nil
+		}
+		docs := make([]*SearchDocument, len(posts))
+		for i := range posts {
+			docs[i] = newSearchDocument(&posts[i])
+		}
+		if err := backend.Index(docs...); err != nil {
+			return count, err
+		}
+		count += len(posts)
+		lastID = posts[len(posts)-1].ID
+	}
+}
+
+// Search 站内搜索已发布的文章，结果按相关度排序并附带高亮的标题及摘要，可由search_results过滤钩子修改
+func Search(query *SearchQuery) (helper.Page, error) {
+	if len(query.Tokens) == 0 {
+		query.Tokens = SearchQueryTokens(query.Keyword)
+	}
+	if len(query.PostTypes) == 0 {
+		query.PostTypes = GetSearchPostTypes()
+	}
+	if b, err := json.Marshal(query); err == nil {
+		if b = hook.ApplyFilterHook("pre_search_query", b); len(b) > 0 {
+			json.Unmarshal(b, query)
+		}
+	}
+	if query.PageNo < 1 {
+		query.PageNo = 1
+	}
+	if query.PageSize < 1 {
+		query.PageSize = 10
+	}
+
+	results := []SearchResult{}
+	if len(query.Tokens) == 0 || len(query.PostTypes) == 0 {
+		return helper.PageUtil(0, query.PageNo, query.PageSize, results), nil
+	}
+	hits, total, err := CurrentSearchBackend().Search(query.Tokens, query.PostTypes, (query.PageNo-1)*query.PageSize, query.PageSize)
+	if err != nil {
+		return helper.PageUtil(0, query.PageNo, query.PageSize, results), err
+	}
+
+	results = loadSearchResults(hits, query.Tokens)
+	if b, err := json.Marshal(results); err == nil {
+		if b = hook.ApplyFilterHook("search_results", b); len(b) > 0 {
+			json.Unmarshal(b, &results)
+		}
+	}
+	return helper.PageUtil(total, query.PageNo, query.PageSize, results), nil
+}
+
+// SearchPosts 以关键词分页搜索已发布的文章
+func SearchPosts(keyword string, pageNo, pageSize int) (helper.Page, error) {
+	return Search(&SearchQuery{Keyword: keyword, PageNo: pageNo, PageSize: pageSize})
+}
+
+func loadSearchResults(hits []SearchHit, tokens []string) []SearchResult {
+	results := []SearchResult{}
+	if len(hits) == 0 {
+		return results
+	}
+	ids := make([]uint64, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.PostID
+	}
+	var posts []Post
+	Database.Where("id in (?) and post_status = ?", ids, PostStatusPublish).Find(&posts)
+	postsMap := make(map[uint64]*Post, len(posts))
+	for i := range posts {
+		postsMap[posts[i].ID] = &posts[i]
+	}
+
+	for _, hit := range hits {
+		post, okay := postsMap[hit.PostID]
+		if !okay {
+			continue
+		}
+		text := helper.Htmlunquote(helper.HTML2str(GetPostContentHTML(post)))
+		if len(strings.TrimSpace(text)) == 0 {
+			text = post.PostExcerpt
+		}
+		results = append(results, SearchResult{
+			Post:    *post,
+			Score:   hit.Score,
+			Title:   SearchHighlight(post.PostTitle, tokens, 0),
+			Snippet: SearchHighlight(text, tokens, searchSnippetWidth),
+			Link:    GetPermalink(post),
+		})
+	}
+	return results
+}
+
+func newSearchDocument(post *Post) *SearchDocument {
+	content := helper.Htmlunquote(helper.HTML2str(GetPostContentHTML(post)))
+	if len(post.PostExcerpt) > 0 {
+		content += "\n" + post.PostExcerpt
+	}
+	return &SearchDocument{PostID: post.ID, PostType: post.PostType, Title: post.PostTitle, Content: content}
+}
+
+// indexPostForSearch 文章发布或修改后更新索引，取消发布时移除索引
+func indexPostForSearch(post *Post) {
+	if pt := GetPostType(post.PostType); pt == nil || !pt.Searchable() {
+		return
+	}
+	backend := CurrentSearchBackend()
+	var err error
+	if post.PostStatus == PostStatusPublish {
+		err = backend.Index(newSearchDocument(post))
+	} else {
+		err = backend.Remove(post.ID)
+	}
+	if err != nil {
+		log.Printf("search index post %d error: %v", post.ID, err)
+	}
+}
+
+func defaultSearchBackend() string {
+	switch Database.Dialect().GetName() {
+	case "mysql":
+		return SearchBackendMysql
+	case "postgres":
+		return SearchBackendPostgres
+	}
+	return SearchBackendSqlite
+}
+
+// searchTokenText 将检索词以空格连接，供按空白分词的数据库索引使用
+func searchTokenText(text string) string {
+	return strings.Join(SearchTokenize(text), " ")
+}
@@ -0,0 +1,268 @@
+package model
+
+import (
+	"encoding/gob"
+	"errors"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// searchTitleWeight 标题中的检索词在计算相关度时的权重
+	searchTitleWeight = 3
+	// searchIndexSaveDelay 索引变更后延迟保存的时间，期间的多次变更只写入一次磁盘
+	searchIndexSaveDelay = 2 * time.Second
+)
+
+var errSearchIndexClosed = errors.New("搜索索引尚未打开")
+
+// indexSearchBackend 内置的倒排索引，常驻内存并以gob格式保存到磁盘，适用于未启用全文索引的数据库
+type indexSearchBackend struct {
+	dir    string
+	lock   sync.RWMutex
+	opened bool
+	data   searchIndexData
+	// total 全部文章的长度之和，用于计算平均长度
+	total int
+	timer *time.Timer
+}
+
+// searchIndexData 倒排索引的持久化数据，Postings为检索词到文章及词频的映射
+type searchIndexData struct {
+	Postings map[string]map[uint64]int
+	Docs     map[uint64]searchIndexDoc
+}
+
+// searchIndexDoc 已建立索引的文章，Tokens用于移除索引时定位倒排表
+type searchIndexDoc struct {
+	PostType string
+	Length   int
+	Tokens   []string
+}
+
+func newIndexSearchBackend(dir string) *indexSearchBackend {
+	return &indexSearchBackend{dir: dir}
+}
+
+func (s *indexSearchBackend) Name() string {
+	return SearchBackendIndex
+}
+
+func (s *indexSearchBackend) file() string {
+	return filepath.Join(s.dir, "index.gob")
+}
+
+func (s *indexSearchBackend) Open() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.opened {
+		return nil
+	}
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+
+	s.data = searchIndexData{Postings: map[string]map[uint64]int{}, Docs: map[uint64]searchIndexDoc{}}
+	f, err := os.Open(s.file())
+	if os.IsNotExist(err) {
+		s.opened = true
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := gob.NewDecoder(f).Decode(&s.data); err != nil {
+		//索引文件损坏时从空索引开始，可在后台重建
+		s.data = searchIndexData{Postings: map[string]map[uint64]int{}, Docs: map[uint64]searchIndexDoc{}}
+	}
+	s.total = 0
+	for _, doc := range s.data.Docs {
+		s.total += doc.Length
+	}
+	s.opened = true
+	return nil
+}
+
+func (s *indexSearchBackend) Index(docs ...*SearchDocument) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if !s.opened {
+		return errSearchIndexClosed
+	}
+	for _, doc := range docs {
+		s.remove(doc.PostID)
+
+		freqs := map[string]int{}
+		length := 0
+		for _, token := range SearchTokenize(doc.Title) {
+			freqs[token] += searchTitleWeight
+			length++
+		}
+		for _, token := range SearchTokenize(doc.Content) {
+			freqs[token]++
+			length++
+		}
+
+		tokens := make([]string, 0, len(freqs))
+		for token, freq := range freqs {
+			postings, okay := s.data.Postings[token]
+			if !okay {
+				postings = map[uint64]int{}
+				s.data.Postings[token] = postings
+			}
+			postings[doc.PostID] = freq
+			tokens = append(tokens, token)
+		}
+		s.data.Docs[doc.PostID] = searchIndexDoc{PostType: doc.PostType, Length: length, Tokens: tokens}
+		s.total += length
+	}
+	s.scheduleSave()
+	return nil
+}
+
+func (s *indexSearchBackend) Remove(postIDs ...uint64) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	changed := false
+	for _, id := range postIDs {
+		changed = s.remove(id) || changed
+	}
+	if changed {
+		s.scheduleSave()
+	}
+	return nil
+}
+
+func (s *indexSearchBackend) Clear() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if !s.opened {
+		return errSearchIndexClosed
+	}
+	s.data = searchIndexData{Postings: map[string]map[uint64]int{}, Docs: map[uint64]searchIndexDoc{}}
+	s.total = 0
+	s.scheduleSave()
+	return nil
+}
+
+// Search 以BM25计算相关度
+func (s *indexSearchBackend) Search(tokens []string, postTypes []string, offset, limit int) ([]SearchHit, int, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if len(tokens) == 0 || len(s.data.Docs) == 0 {
+		return nil, 0, nil
+	}
+
+	types := map[string]bool{}
+	for _, t := range postTypes {
+		types[t] = true
+	}
+	//从文章最少的检索词开始求交集
+	sorted := append([]string(nil), tokens...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return len(s.data.Postings[sorted[i]]) < len(s.data.Postings[sorted[j]])
+	})
+	var candidates []uint64
+	for id := range s.data.Postings[sorted[0]] {
+		if types[s.data.Docs[id].PostType] {
+			candidates = append(candidates, id)
+		}
+	}
+
+	const k1, b = 1.2, 0.75
+	n := float64(len(s.data.Docs))
+	avg := float64(s.total) / n
+
+	var hits []SearchHit
+	for _, id := range candidates {
+		var score float64
+		matched := true
+		for _, token := range sorted {
+			tf, okay := s.data.Postings[token][id]
+			if !okay {
+				matched = false
+				break
+			}
+			df := float64(len(s.data.Postings[token]))
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			length := float64(s.data.Docs[id].Length)
+			score += idf * float64(tf) * (k1 + 1) / (float64(tf) + k1*(1-b+b*length/avg))
+		}
+		if matched {
+			hits = append(hits, SearchHit{PostID: id, Score: score})
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score == hits[j].Score {
+			return hits[i].PostID > hits[j].PostID
+		}
+		return hits[i].Score > hits[j].Score
+	})
+
+	count := len(hits)
+	if offset >= count {
+		return nil, count, nil
+	}
+	if end := offset + limit; end < count {
+		hits = hits[:end]
+	}
+	return hits[offset:], count, nil
+}
+
+func (s *indexSearchBackend) remove(id uint64) bool {
+	doc, okay := s.data.Docs[id]
+	if !okay {
+		return false
+	}
+	for _, token := range doc.Tokens {
+		if postings, okay := s.data.Postings[token]; okay {
+			delete(postings, id)
+			if len(postings) == 0 {
+				delete(s.data.Postings, token)
+			}
+		}
+	}
+	delete(s.data.Docs, id)
+	s.total -= doc.Length
+	return true
+}
+
+// scheduleSave 延迟保存索引，重建索引等连续的变更合并为一次写入
+func (s *indexSearchBackend) scheduleSave() {
+	if s.timer != nil {
+		s.timer.Reset(searchIndexSaveDelay)
+		return
+	}
+	s.timer = time.AfterFunc(searchIndexSaveDelay, func() {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		s.timer = nil
+		if err := s.save(); err != nil {
+			log.Println("save search index error:", err)
+		}
+	})
+}
+
+// save 先写入临时文件再替换，避免写入中断时损坏索引
+func (s *indexSearchBackend) save() error {
+	tmp := s.file() + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(f).Encode(&s.data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, s.file())
+}
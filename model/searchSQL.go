@@ -0,0 +1,202 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// 数据库全文索引的搜索后端，索引表保存SearchTokenize切分后以空格连接的文本，各数据库只需按空白分词
+
+// searchTable 搜索索引表的表名
+func searchTable() string {
+	return DatabaseTablePrefix + "post_search"
+}
+
+// checkSearchDialect 检查当前数据库是否为后端所需的类型
+func checkSearchDialect(backend SearchBackend, dialect string) error {
+	if name := Database.Dialect().GetName(); name != dialect {
+		return fmt.Errorf("搜索后端[%s]不能用于%s数据库", backend.Name(), name)
+	}
+	return nil
+}
+
+// quoteSearchTokens 将检索词转为带引号的短语，prefix为每个短语前附加的运算符
+func quoteSearchTokens(tokens []string, prefix string) string {
+	quoted := make([]string, len(tokens))
+	for i, token := range tokens {
+		quoted[i] = prefix + `"` + strings.Replace(token, `"`, `""`, -1) + `"`
+	}
+	return strings.Join(quoted, " ")
+}
+
+func scanSearchHits(rows *sql.Rows, err error) ([]SearchHit, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var hits []SearchHit
+	for rows.Next() {
+		var hit SearchHit
+		if err := rows.Scan(&hit.PostID, &hit.Score); err != nil {
+			return nil, err
+		}
+		hits = append(hits, hit)
+	}
+	return hits, rows.Err()
+}
+
+func countSearchHits(query string, values ...interface{}) (int, error) {
+	var count int
+	err := Database.Raw(query, values...).Row().Scan(&count)
+	return count, err
+}
+
+func indexSearchDocuments(statement string, docs []*SearchDocument, values func(doc *SearchDocument) []interface{}) error {
+	tx := Database.Begin()
+	for _, doc := range docs {
+		if err := tx.Exec(statement, values(doc)...).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit().Error
+}
+
+// sqliteSearchBackend sqlite的FTS5虚拟表，标题的权重为正文的3倍
+type sqliteSearchBackend struct{}
+
+func (s *sqliteSearchBackend) Name() string {
+	return SearchBackendSqlite
+}
+
+func (s *sqliteSearchBackend) Open() error {
+	if err := checkSearchDialect(s, "sqlite3"); err != nil {
+		return err
+	}
+	return Database.Exec("CREATE VIRTUAL TABLE IF NOT EXISTS " + searchTable() + " USING fts5(post_id UNINDEXED, post_type UNINDEXED, title, content, tokenize = 'unicode61')").Error
+}
+
+func (s *sqliteSearchBackend) Index(docs ...*SearchDocument) error {
+	tx := Database.Begin()
+	for _, doc := range docs {
+		if err := tx.Exec("DELETE FROM "+searchTable()+" WHERE post_id = ?", doc.PostID).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Exec("INSERT INTO "+searchTable()+" (post_id, post_type, title, content) VALUES (?, ?, ?, ?)",
+			doc.PostID, doc.PostType, searchTokenText(doc.Title), searchTokenText(doc.Content)).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit().Error
+}
+
+func (s *sqliteSearchBackend) Remove(postIDs ...uint64) error {
+	return Database.Exec("DELETE FROM "+searchTable()+" WHERE post_id IN (?)", postIDs).Error
+}
+
+func (s *sqliteSearchBackend) Clear() error {
+	return Database.Exec("DELETE FROM " + searchTable()).Error
+}
+
+func (s *sqliteSearchBackend) Search(tokens []string, postTypes []string, offset, limit int) ([]SearchHit, int, error) {
+	match := quoteSearchTokens(tokens, "")
+	count, err := countSearchHits("SELECT COUNT(*) FROM "+searchTable()+" WHERE "+searchTable()+" MATCH ? AND post_type IN (?)", match, postTypes)
+	if err != nil || count == 0 {
+		return nil, count, err
+	}
+	hits, err := scanSearchHits(Database.Raw("SELECT post_id, -bm25("+searchTable()+", 0, 0, 3.0, 1.0) AS score FROM "+searchTable()+
+		" WHERE "+searchTable()+" MATCH ? AND post_type IN (?) ORDER BY score DESC, post_id DESC LIMIT ? OFFSET ?", match, postTypes, limit, offset).Rows())
+	return hits, count, err
+}
+
+// mysqlSearchBackend MySQL的InnoDB全文索引，使用ngram分词器以支持两个字的中文检索词，需MySQL 5.7.6以上
+type mysqlSearchBackend struct{}
+
+func (s *mysqlSearchBackend) Name() string {
+	return SearchBackendMysql
+}
+
+func (s *mysqlSearchBackend) Open() error {
+	if err := checkSearchDialect(s, "mysql"); err != nil {
+		return err
+	}
+	return Database.Exec("CREATE TABLE IF NOT EXISTS " + searchTable() + ` (
+		post_id BIGINT UNSIGNED NOT NULL PRIMARY KEY,
+		post_type VARCHAR(20) NOT NULL,
+		title TEXT NOT NULL,
+		content LONGTEXT NOT NULL,
+		KEY idx_post_search_post_type (post_type),
+		FULLTEXT KEY ft_post_search (title, content) WITH PARSER ngram
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`).Error
+}
+
+func (s *mysqlSearchBackend) Index(docs ...*SearchDocument) error {
+	return indexSearchDocuments("REPLACE INTO "+searchTable()+" (post_id, post_type, title, content) VALUES (?, ?, ?, ?)", docs, func(doc *SearchDocument) []interface{} {
+		return []interface{}{doc.PostID, doc.PostType, searchTokenText(doc.Title), searchTokenText(doc.Content)}
+	})
+}
+
+func (s *mysqlSearchBackend) Remove(postIDs ...uint64) error {
+	return Database.Exec("DELETE FROM "+searchTable()+" WHERE post_id IN (?)", postIDs).Error
+}
+
+func (s *mysqlSearchBackend) Clear() error {
+	return Database.Exec("DELETE FROM " + searchTable()).Error
+}
+
+func (s *mysqlSearchBackend) Search(tokens []string, postTypes []string, offset, limit int) ([]SearchHit, int, error) {
+	match := quoteSearchTokens(tokens, "+")
+	count, err := countSearchHits("SELECT COUNT(*) FROM "+searchTable()+" WHERE MATCH(title, content) AGAINST(? IN BOOLEAN MODE) AND post_type IN (?)", match, postTypes)
+	if err != nil || count == 0 {
+		return nil, count, err
+	}
+	hits, err := scanSearchHits(Database.Raw("SELECT post_id, MATCH(title, content) AGAINST(? IN BOOLEAN MODE) AS score FROM "+searchTable()+
+		" WHERE MATCH(title, content) AGAINST(? IN BOOLEAN MODE) AND post_type IN (?) ORDER BY score DESC, post_id DESC LIMIT ? OFFSET ?", match, match, postTypes, limit, offset).Rows())
+	return hits, count, err
+}
+
+// postgresSearchBackend Postgres的tsvector及GIN索引，使用simple配置，标题的权重为A
+type postgresSearchBackend struct{}
+
+func (s *postgresSearchBackend) Name() string {
+	return SearchBackendPostgres
+}
+
+func (s *postgresSearchBackend) Open() error {
+	if err := checkSearchDialect(s, "postgres"); err != nil {
+		return err
+	}
+	if err := Database.Exec("CREATE TABLE IF NOT EXISTS " + searchTable() + " (post_id BIGINT NOT NULL PRIMARY KEY, post_type VARCHAR(20) NOT NULL, document TSVECTOR NOT NULL)").Error; err != nil {
+		return err
+	}
+	return Database.Exec("CREATE INDEX IF NOT EXISTS idx_" + searchTable() + "_document ON " + searchTable() + " USING GIN (document)").Error
+}
+
+func (s *postgresSearchBackend) Index(docs ...*SearchDocument) error {
+	return indexSearchDocuments("INSERT INTO "+searchTable()+" (post_id, post_type, document) VALUES (?, ?, setweight(to_tsvector('simple', ?), 'A') || setweight(to_tsvector('simple', ?), 'D'))"+
+		" ON CONFLICT (post_id) DO UPDATE SET post_type = EXCLUDED.post_type, document = EXCLUDED.document", docs, func(doc *SearchDocument) []interface{} {
+		return []interface{}{doc.PostID, doc.PostType, searchTokenText(doc.Title), searchTokenText(doc.Content)}
+	})
+}
+
+func (s *postgresSearchBackend) Remove(postIDs ...uint64) error {
+	return Database.Exec("DELETE FROM "+searchTable()+" WHERE post_id IN (?)", postIDs).Error
+}
+
+func (s *postgresSearchBackend) Clear() error {
+	return Database.Exec("DELETE FROM " + searchTable()).Error
+}
+
+func (s *postgresSearchBackend) Search(tokens []string, postTypes []string, offset, limit int) ([]SearchHit, int, error) {
+	text := strings.Join(tokens, " ")
+	count, err := countSearchHits("SELECT COUNT(*) FROM "+searchTable()+" WHERE document @@ plainto_tsquery('simple', ?) AND post_type IN (?)", text, postTypes)
+	if err != nil || count == 0 {
+		return nil, count, err
+	}
+	hits, err := scanSearchHits(Database.Raw("SELECT post_id, ts_rank(document, plainto_tsquery('simple', ?)) AS score FROM "+searchTable()+
+		" WHERE document @@ plainto_tsquery('simple', ?) AND post_type IN (?) ORDER BY score DESC, post_id DESC LIMIT ? OFFSET ?", text, text, postTypes, limit, offset).Rows())
+	return hits, count, err
+}
@@ -0,0 +1,160 @@
+package model
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxSearchTokenLength 单个检索词的最大长度，超出部分截断
+const maxSearchTokenLength = 64
+
+// SearchTokenize 将文本切分为检索词，中日韩文字按相邻两字切分，其他语言按空白及标点切分并转为小写
+func SearchTokenize(text string) []string {
+	var tokens []string
+	var word []rune
+	var cjk []rune
+
+	flushWord := func() {
+		if len(word) > 0 {
+			if len(word) > maxSearchTokenLength {
+				word = word[:maxSearchTokenLength]
+			}
+			tokens = append(tokens, strings.ToLower(string(word)))
+			word = word[:0]
+		}
+	}
+	flushCJK := func() {
+		switch len(cjk) {
+		case 0:
+		case 1:
+			tokens = append(tokens, string(cjk))
+		default:
+			for i := 0; i+1 < len(cjk); i++ {
+				tokens = append(tokens, string(cjk[i:i+2]))
+			}
+		}
+		cjk = cjk[:0]
+	}
+
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			flushWord()
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushCJK()
+			word = append(word, r)
+		default:
+			flushWord()
+			flushCJK()
+		}
+	}
+	flushWord()
+	flushCJK()
+	return tokens
+}
+
+// SearchQueryTokens 切分搜索关键词并去除重复的检索词
+func SearchQueryTokens(keyword string) []string {
+	var tokens []string
+	seen := map[string]bool{}
+	for _, token := range SearchTokenize(keyword) {
+		if !seen[token] {
+			seen[token] = true
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+// SearchHighlight 转义文本并以<mark>标记其中的检索词，width大于0时截取首个检索词附近约width个字符作为摘要
+func SearchHighlight(text string, tokens []string, width int) string {
+	runes := []rune(text)
+	lower := []rune(strings.ToLower(text))
+	if len(lower) != len(runes) {
+		//个别字符转小写后长度变化，无法按位置对应，直接使用原文
+		lower = runes
+	}
+
+	marks := make([]bool, len(runes))
+	first := -1
+	for _, token := range tokens {
+		t := []rune(token)
+		if len(t) == 0 {
+			continue
+		}
+		for i := 0; i+len(t) <= len(lower); i++ {
+			if !hasRunesAt(lower, t, i) {
+				continue
+			}
+			for j := i; j < i+len(t); j++ {
+				marks[j] = true
+			}
+			if first < 0 || i < first {
+				first = i
+			}
+		}
+	}
+
+	start, end := 0, len(runes)
+	if width > 0 && len(runes) > width {
+		if first > width/3 {
+			start = first - width/3
+		}
+		end = start + width
+		if end > len(runes) {
+			end = len(runes)
+			start = end - width
+		}
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	for i := start; i < end; i++ {
+		if marks[i] && (i == start || !marks[i-1]) {
+			b.WriteString("<mark>")
+		}
+		b.WriteString(escapeSearchText(runes[i]))
+		if marks[i] && (i+1 == end || !marks[i+1]) {
+			b.WriteString("</mark>")
+		}
+	}
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+func escapeSearchText(r rune) string {
+	switch r {
+	case '<':
+		return "&lt;"
+	case '>':
+		return "&gt;"
+	case '&':
+		return "&amp;"
+	case '"':
+		return "&#34;"
+	case '\'':
+		return "&#39;"
+	case utf8.RuneError:
+		return ""
+	}
+	return string(r)
+}
+
+func hasRunesAt(s, sub []rune, i int) bool {
+	for j, r := range sub {
+		if s[i+j] != r {
+			return false
+		}
+	}
+	return true
+}
+
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r)
+}
@@ -0,0 +1,59 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/insionng/zenpress/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchTokenize(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal([]string{"全文", "文检", "检索", "zenpress", "v2"}, model.SearchTokenize("全文检索 ZenPress, v2"))
+	assert.Equal([]string{"搜", "go"}, model.SearchQueryTokens("搜 Go go"))
+	assert.Equal("<mark>Go</mark>语言的&lt;<mark>全文</mark>&gt;", model.SearchHighlight("Go语言的<全文>", []string{"go", "全文"}, 0))
+	assert.Equal("…的<mark>检索</mark>测…", model.SearchHighlight("这是一段很长的检索测试", []string{"检索"}, 4))
+}
+
+func TestSearch(t *testing.T) {
+	assert := assert.New(t)
+	var ids []uint64
+	for _, post := range []*model.Post{
+		{PostTitle: "站内搜索测试", PostContent: "<p>支持中文分词的全文检索</p>", PostStatus: model.PostStatusPublish},
+		{PostTitle: "Search test", PostContent: "全文检索 and full-text search", PostStatus: model.PostStatusPublish},
+		{PostTitle: "草稿中的全文检索", PostStatus: model.PostStatusDraft},
+	} {
+		if !assert.NoError(model.InsertPost(post)) {
+			return
+		}
+		defer model.RemovePost(post.ID)
+		ids = append(ids, post.ID)
+	}
+
+	page, err := model.SearchPosts("全文检索", 1, 10)
+	if assert.NoError(err) {
+		assert.Equal(2, page.TotalCount, "drafts are not indexed")
+		results := page.List.([]model.SearchResult)
+		if assert.Equal(2, len(results)) {
+			assert.Contains(results[0].Snippet, "<mark>全文检索</mark>")
+		}
+	}
+
+	page, _ = model.SearchPosts("站内 检索", 1, 10)
+	if assert.Equal(1, page.TotalCount) {
+		assert.Equal(ids[0], page.List.([]model.SearchResult)[0].Post.ID)
+		assert.Equal("<mark>站内</mark>搜索测试", page.List.([]model.SearchResult)[0].Title)
+	}
+
+	assert.NoError(model.TrashPost(ids[1]))
+	page, _ = model.SearchPosts("search", 1, 10)
+	assert.Equal(0, page.TotalCount, "unpublished posts are removed from the index")
+
+	count, err := model.RebuildSearchIndex()
+	if assert.NoError(err) {
+		assert.True(count >= 1)
+	}
+	page, _ = model.SearchPosts("支持中文", 1, 10)
+	assert.Equal(1, page.TotalCount)
+}
@@ -0,0 +1,43 @@
+package model
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+)
+
+// SecretKeyOption 站点密钥保存在选项中的键名，首次使用时随机生成
+const SecretKeyOption = "secret_key"
+
+var secretKeyLock sync.Mutex
+
+// GetSecretKey 获得站点密钥，用于签名链接等需要防篡改的场合
+func GetSecretKey() []byte {
+	secretKeyLock.Lock()
+	defer secretKeyLock.Unlock()
+	if key := GetOptionValue(SecretKeyOption); len(key) > 0 {
+		return []byte(key)
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	key := hex.EncodeToString(b)
+	SetOptionValue(SecretKeyOption, key)
+	return []byte(key)
+}
+
+// SignValue 以站点密钥计算值的HMAC-SHA256签名，返回前16字节的十六进制
+func SignValue(value string) string {
+	mac := hmac.New(sha256.New, GetSecretKey())
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// VerifySignature 验证签名是否与值一致
+func VerifySignature(value, signature string) bool {
+	return hmac.Equal([]byte(SignValue(value)), []byte(signature))
+}
@@ -0,0 +1,264 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrInvalidTaxonomy 分类法名称无效
+	ErrInvalidTaxonomy = errors.New("分类法名称无效，只能由不超过32个小写字母、数字、下划线或连字符组成")
+	// ErrTaxonomyNotFound 分类法未注册
+	ErrTaxonomyNotFound = errors.New("分类法未注册")
+	// ErrBuiltinTaxonomy 内置分类法不能注销
+	ErrBuiltinTaxonomy = errors.New("内置分类法不能注销")
+
+	taxonomyNameRegexp = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+	taxonomies     = map[string]*Taxonomy{}
+	taxonomiesLock sync.RWMutex
+)
+
+// TaxonomyLabels 分类法在后台及主题中显示的文字
+type TaxonomyLabels struct {
+	Name         string `json:"name"`
+	SingularName string `json:"singular_name"`
+	AddNew       string `json:"add_new"`
+	EditItem     string `json:"edit_item"`
+	AllItems     string `json:"all_items"`
+	NotFound     string `json:"not_found"`
+}
+
+// TaxonomyOptions 注册分类法的选项
+type TaxonomyOptions struct {
+	Labels      TaxonomyLabels `json:"labels"`
+	Description string         `json:"description"`
+	// ObjectTypes 使用该分类法的对象类型，如post、page、link或自定义文章类型
+	ObjectTypes  []string `json:"object_types"`
+	Public       bool     `json:"public"`
+	ShowInAdmin  bool     `json:"show_in_admin"`
+	Hierarchical bool     `json:"hierarchical"`
+	// RewriteSlug 前台归档页的路径前缀，默认为 taxonomy/{Name}
+	RewriteSlug string `json:"rewrite_slug"`
+}
+
+// Taxonomy 已注册的分类法
+type Taxonomy struct {
+	Name    string
+	Builtin bool
+	TaxonomyOptions
+}
+
+func init() {
+	for _, tax := range []*Taxonomy{
+		{Name: "category", Builtin: true, TaxonomyOptions: TaxonomyOptions{
+			Labels:       TaxonomyLabels{Name: "分类目录", SingularName: "分类目录"},
+			ObjectTypes:  []string{"post"},
+			Public:       true,
+			ShowInAdmin:  true,
+			Hierarchical: true,
+			RewriteSlug:  "category",
+		}},
+		{Name: "post_tag", Builtin: true, TaxonomyOptions: TaxonomyOptions{
+			Labels:      TaxonomyLabels{Name: "标签", SingularName: "标签"},
+			ObjectTypes: []string{"post"},
+			Public:      true,
+			ShowInAdmin: true,
+			RewriteSlug: "tag",
+		}},
+		{Name: "link_category", Builtin: true, TaxonomyOptions: TaxonomyOptions{
+			Labels:      TaxonomyLabels{Name: "链接分类", SingularName: "链接分类"},
+			ObjectTypes: []string{"link"},
+			ShowInAdmin: true,
+		}},
+		{Name: NavMenuTaxonomy, Builtin: true, TaxonomyOptions: TaxonomyOptions{
+			Labels:      TaxonomyLabels{Name: "菜单", SingularName: "菜单"},
+			ObjectTypes: []string{NavMenuItemPostType},
+		}},
+	} {
+		completeTaxonomy(tax)
+		taxonomies[tax.Name] = tax
+	}
+}
+
+// RegisterTaxonomy 注册分类法，重复注册同名分类法将覆盖原有选项，options为nil时使用默认选项
+func RegisterTaxonomy(name string, options *TaxonomyOptions) (*Taxonomy, error) {
+	if !taxonomyNameRegexp.MatchString(name) {
+		return nil, ErrInvalidTaxonomy
+	}
+
+	tax := &Taxonomy{Name: name}
+	if options != nil {
+		tax.TaxonomyOptions = *options
+	}
+	completeTaxonomy(tax)
+
+	taxonomiesLock.Lock()
+	defer taxonomiesLock.Unlock()
+	if old, okay := taxonomies[name]; okay && old.Builtin {
+		tax.Builtin = true
+	} else if tax.Public && reservedArchiveSlugs[tax.RewriteSlug] {
+		return nil, fmt.Errorf("分类法[%s]的路径[%s]为保留路径", name, tax.RewriteSlug)
+	} else if tax.Public {
+		postTypesLock.RLock()
+		defer postTypesLock.RUnlock()
+		for _, pt := range postTypes {
+			if pt.Public && !pt.Builtin && pt.ArchiveSlug == tax.RewriteSlug {
+				return nil, fmt.Errorf("分类法[%s]的路径[%s]已被文章类型[%s]使用", name, tax.RewriteSlug, pt.Name)
+			}
+		}
+	}
+	for _, other := range taxonomies {
+		if other.Name != name && other.Public && tax.Public && other.RewriteSlug == tax.RewriteSlug {
+			return nil, fmt.Errorf("分类法[%s]的路径[%s]已被[%s]使用", name, tax.RewriteSlug, other.Name)
+		}
+	}
+	taxonomies[name] = tax
+	return tax, nil
+}
+
+// RegisterTaxonomyByMap 以map注册分类法，键名与TaxonomyOptions的json标签一致，便于在qlang中调用
+func RegisterTaxonomyByMap(name string, options map[string]interface{}) (*Taxonomy, error) {
+	b, err := json.Marshal(options)
+	if err != nil {
+		return nil, err
+	}
+	opts := new(TaxonomyOptions)
+	if err := json.Unmarshal(b, opts); err != nil {
+		return nil, err
+	}
+	return RegisterTaxonomy(name, opts)
+}
+
+// RegisterTaxonomyForObjectType 为对象类型添加分类法
+func RegisterTaxonomyForObjectType(taxonomy, objectType string) error {
+	taxonomiesLock.Lock()
+	defer taxonomiesLock.Unlock()
+	tax, okay := taxonomies[taxonomy]
+	if !okay {
+		return ErrTaxonomyNotFound
+	}
+	if !tax.IsObjectType(objectType) {
+		tax.ObjectTypes = append(tax.ObjectTypes, objectType)
+	}
+	return nil
+}
+
+// UnregisterTaxonomy 注销分类法，已有的分类数据不会被删除，内置分类法不能注销
+func UnregisterTaxonomy(name string) error {
+	taxonomiesLock.Lock()
+	defer taxonomiesLock.Unlock()
+	tax, okay := taxonomies[name]
+	if !okay {
+		return ErrTaxonomyNotFound
+	}
+	if tax.Builtin {
+		return ErrBuiltinTaxonomy
+	}
+	delete(taxonomies, name)
+	return nil
+}
+
+// GetTaxonomy 获得已注册的分类法，未注册时返回nil
+func GetTaxonomy(name string) *Taxonomy {
+	taxonomiesLock.RLock()
+	defer taxonomiesLock.RUnlock()
+	return taxonomies[name]
+}
+
+// GetTaxonomies 获得全部已注册的分类法，按名称排序
+func GetTaxonomies() []*Taxonomy {
+	taxonomiesLock.RLock()
+	list := make([]*Taxonomy, 0, len(taxonomies))
+	for _, tax := range taxonomies {
+		list = append(list, tax)
+	}
+	taxonomiesLock.RUnlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// GetObjectTaxonomies 获得对象类型使用的全部分类法
+func GetObjectTaxonomies(objectType string) []*Taxonomy {
+	var list []*Taxonomy
+	for _, tax := range GetTaxonomies() {
+		if tax.IsObjectType(objectType) {
+			list = append(list, tax)
+		}
+	}
+	return list
+}
+
+// GetAdminTaxonomies 获得在后台显示管理界面的分类法
+func GetAdminTaxonomies() []*Taxonomy {
+	var list []*Taxonomy
+	for _, tax := range GetTaxonomies() {
+		if tax.ShowInAdmin {
+			list = append(list, tax)
+		}
+	}
+	return list
+}
+
+// GetTaxonomyByRewriteSlug 根据路径前缀获得公开的分类法
+func GetTaxonomyByRewriteSlug(slug string) *Taxonomy {
+	for _, tax := range GetTaxonomies() {
+		if tax.Public && tax.RewriteSlug == slug {
+			return tax
+		}
+	}
+	return nil
+}
+
+// IsObjectType 分类法是否用于指定对象类型
+func (tax *Taxonomy) IsObjectType(objectType string) bool {
+	for _, t := range tax.ObjectTypes {
+		if t == objectType {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPostTaxonomy 分类法是否用于文章类型，文章分类只统计已发布的文章
+func (tax *Taxonomy) IsPostTaxonomy() bool {
+	for _, t := range tax.ObjectTypes {
+		if GetPostType(t) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func completeTaxonomy(tax *Taxonomy) {
+	if len(tax.Labels.Name) == 0 {
+		tax.Labels.Name = tax.Name
+	}
+	if len(tax.Labels.SingularName) == 0 {
+		tax.Labels.SingularName = tax.Labels.Name
+	}
+	if len(tax.Labels.AddNew) == 0 {
+		tax.Labels.AddNew = "添加" + tax.Labels.SingularName
+	}
+	if len(tax.Labels.EditItem) == 0 {
+		tax.Labels.EditItem = "编辑" + tax.Labels.SingularName
+	}
+	if len(tax.Labels.AllItems) == 0 {
+		tax.Labels.AllItems = "所有" + tax.Labels.Name
+	}
+	if len(tax.Labels.NotFound) == 0 {
+		tax.Labels.NotFound = "没有找到" + tax.Labels.Name
+	}
+	if len(tax.RewriteSlug) == 0 {
+		tax.RewriteSlug = "taxonomy/" + tax.Name
+	}
+	tax.RewriteSlug = strings.Trim(tax.RewriteSlug, "/")
+}
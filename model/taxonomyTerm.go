@@ -0,0 +1,559 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/insionng/zenpress/helper"
+	"github.com/insionng/zenpress/module/hook"
+
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	// ErrTermExists 同一分类法的同一父级下已存在同名分类
+	ErrTermExists = errors.New("同一父级下已存在同名分类")
+	// ErrEmptyTermName 分类名称为空
+	ErrEmptyTermName = errors.New("分类名称为空")
+	// ErrInvalidTermParent 父级分类无效
+	ErrInvalidTermParent = errors.New("父级分类无效")
+
+	termSlugRegexp = regexp.MustCompile(`[^\p{L}\p{N}_-]+`)
+)
+
+// TaxonomyTerm 分类法中的分类，合并了Term与TermTaxonomy的信息
+type TaxonomyTerm struct {
+	TermTaxonomyID uint64
+	TermID         uint64
+	Name           string
+	Slug           string
+	Taxonomy       string
+	Description    string
+	Parent         uint64 //父级分类的TermTaxonomyID
+	Count          int64
+	Depth          int             `gorm:"-"` //在分类树中的层级，顶级为0
+	Children       []*TaxonomyTerm `gorm:"-"`
+}
+
+// TermOptions 创建或更新分类的选项
+type TermOptions struct {
+	Name        string
+	Slug        string
+	Description string
+	Parent      uint64
+}
+
+// SanitizeTermSlug 生成分类别名，保留文字、数字、下划线及连字符，其余字符替换为连字符
+func SanitizeTermSlug(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	s = termSlugRegexp.ReplaceAllString(s, "-")
+	return strings.Trim(s, "-")
+}
+
+// UniqueTermSlug 获得分类法中唯一的别名，重复时先附加父级别名，再附加数字后缀
+func UniqueTermSlug(slug, taxonomy string, parent, excludeID uint64) string {
+	exists := func(s string) bool {
+		term, err := GetTaxonomyTermBySlug(taxonomy, s)
+		return err == nil && term.TermTaxonomyID != excludeID
+	}
+	if !exists(slug) {
+		return slug
+	}
+
+	if parent > 0 {
+		if p, err := GetTaxonomyTerm(parent); err == nil {
+			slug = slug + "-" + p.Slug
+			if !exists(slug) {
+				return slug
+			}
+		}
+	}
+	for i := 2; ; i++ {
+		if s := fmt.Sprintf("%s-%d", slug, i); !exists(s) {
+			return s
+		}
+	}
+}
+
+// InsertTerm 在分类法中创建分类，别名为空时由名称生成
+func InsertTerm(name, taxonomy string, options *TermOptions) (*TaxonomyTerm, error) {
+	tax := GetTaxonomy(taxonomy)
+	if tax == nil {
+		return nil, ErrTaxonomyNotFound
+	}
+	if options == nil {
+		options = new(TermOptions)
+	}
+	name = strings.TrimSpace(name)
+	if len(name) == 0 {
+		return nil, ErrEmptyTermName
+	}
+
+	parent := options.Parent
+	if !tax.Hierarchical {
+		parent = 0
+	}
+	if parent > 0 {
+		if p, err := GetTaxonomyTerm(parent); err != nil || p.Taxonomy != taxonomy {
+			return nil, ErrInvalidTermParent
+		}
+	}
+	if term, err := getTaxonomyTermByName(taxonomy, name, parent); err == nil {
+		return term, ErrTermExists
+	}
+
+	slug := SanitizeTermSlug(options.Slug)
+	if len(slug) == 0 {
+		slug = SanitizeTermSlug(name)
+	}
+	slug = UniqueTermSlug(slug, taxonomy, parent, 0)
+
+	term := &Term{Name: name, Slug: slug}
+	tt := &TermTaxonomy{Taxonomy: taxonomy, Description: options.Description, Parent: int64(parent)}
+	tx := Database.Begin()
+	if err := tx.Create(term).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	tt.TermID = term.ID
+	if err := tx.Create(tt).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	result, err := GetTaxonomyTerm(tt.TermTaxonomyID)
+	if err == nil {
+		doTermHook("created_term", result)
+	}
+	return result, err
+}
+
+// UpdateTaxonomyTerm 更新分类的名称、别名、描述及父级
+func UpdateTaxonomyTerm(termTaxonomyID uint64, options *TermOptions) (*TaxonomyTerm, error) {
+	term, err := GetTaxonomyTerm(termTaxonomyID)
+	if err != nil {
+		return nil, err
+	}
+	tax := GetTaxonomy(term.Taxonomy)
+	if tax == nil {
+		return nil, ErrTaxonomyNotFound
+	}
+
+	name := strings.TrimSpace(options.Name)
+	if len(name) == 0 {
+		return nil, ErrEmptyTermName
+	}
+	parent := options.Parent
+	if !tax.Hierarchical {
+		parent = 0
+	}
+	if parent > 0 {
+		p, err := GetTaxonomyTerm(parent)
+		if err != nil || p.Taxonomy != term.Taxonomy || parent == termTaxonomyID {
+			return nil, ErrInvalidTermParent
+		}
+		for _, ancestor := range GetTermAncestors(parent) {
+			if ancestor.TermTaxonomyID == termTaxonomyID {
+				return nil, ErrInvalidTermParent
+			}
+		}
+	}
+	if other, err := getTaxonomyTermByName(term.Taxonomy, name, parent); err == nil && other.TermTaxonomyID != termTaxonomyID {
+		return nil, ErrTermExists
+	}
+
+	slug := SanitizeTermSlug(options.Slug)
+	if len(slug) == 0 {
+		slug = SanitizeTermSlug(name)
+	}
+	if slug != term.Slug {
+		slug = UniqueTermSlug(slug, term.Taxonomy, parent, termTaxonomyID)
+	}
+
+	tx := Database.Begin()
+	if err := tx.Model(&Term{}).Where("id = ?", term.TermID).Updates(map[string]interface{}{
+		"name": name,
+		"slug": slug,
+	}).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Model(&TermTaxonomy{}).Where("term_taxonomy_id = ?", termTaxonomyID).Updates(map[string]interface{}{
+		"description": options.Description,
+		"parent":      int64(parent),
+	}).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	result, err := GetTaxonomyTerm(termTaxonomyID)
+	if err == nil {
+		doTermHook("edited_term", result)
+	}
+	return result, err
+}
+
+// DeleteTaxonomyTerm 删除分类及其关联关系，子分类提升至其父级
+func DeleteTaxonomyTerm(termTaxonomyID uint64) error {
+	term, err := GetTaxonomyTerm(termTaxonomyID)
+	if err != nil {
+		return err
+	}
+	doTermHook("delete_term", term)
+
+	tx := Database.Begin()
+	if err := tx.Model(&TermTaxonomy{}).Where("parent = ? and taxonomy = ?", termTaxonomyID, term.Taxonomy).Update("parent", int64(term.Parent)).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Delete(TermRelationship{}, "term_taxonomy_id = ?", termTaxonomyID).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Delete(TermTaxonomy{}, "term_taxonomy_id = ?", termTaxonomyID).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	var shared int
+	tx.Model(&TermTaxonomy{}).Where("term_id = ?", term.TermID).Count(&shared)
+	if shared == 0 {
+		if err := tx.Delete(Termmeta{}, "term_id = ?", term.TermID).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Delete(Term{}, "id = ?", term.TermID).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit().Error
+}
+
+// GetTaxonomyTerm 获得分类
+func GetTaxonomyTerm(termTaxonomyID uint64) (*TaxonomyTerm, error) {
+	var term TaxonomyTerm
+	if err := taxonomyTermQuery().Where("tt.term_taxonomy_id = ?", termTaxonomyID).Limit(1).Scan(&term).Error; err != nil {
+		return nil, err
+	}
+	return &term, nil
+}
+
+// GetTaxonomyTermBySlug 根据别名获得分类
+func GetTaxonomyTermBySlug(taxonomy, slug string) (*TaxonomyTerm, error) {
+	var term TaxonomyTerm
+	if err := taxonomyTermQuery().Where("tt.taxonomy = ? and t.slug = ?", taxonomy, slug).Limit(1).Scan(&term).Error; err != nil {
+		return nil, err
+	}
+	return &term, nil
+}
+
+// GetTaxonomyTermByName 根据名称获得分类
+func GetTaxonomyTermByName(taxonomy, name string) (*TaxonomyTerm, error) {
+	var term TaxonomyTerm
+	if err := taxonomyTermQuery().Where("tt.taxonomy = ? and t.name = ?", taxonomy, name).Limit(1).Scan(&term).Error; err != nil {
+		return nil, err
+	}
+	return &term, nil
+}
+
+// GetTaxonomyTerms 获得分类法中的全部分类，按名称排序
+func GetTaxonomyTerms(taxonomy string) (terms []*TaxonomyTerm) {
+	taxonomyTermQuery().Where("tt.taxonomy = ?", taxonomy).Order("t.name asc").Scan(&terms)
+	return
+}
+
+// GetTaxonomyTermTree 获得分类法中的分类树
+func GetTaxonomyTermTree(taxonomy string) (tree []*TaxonomyTerm) {
+	terms := GetTaxonomyTerms(taxonomy)
+	index := map[uint64]*TaxonomyTerm{}
+	for _, term := range terms {
+		index[term.TermTaxonomyID] = term
+	}
+	for _, term := range terms {
+		if parent, okay := index[term.Parent]; okay && term.Parent > 0 {
+			parent.Children = append(parent.Children, term)
+		} else {
+			tree = append(tree, term)
+		}
+	}
+	return
+}
+
+// GetTaxonomyTermsHierarchy 按分类树深度优先排列分类法中的分类，并设置层级，便于在后台列表及下拉框中缩进显示
+func GetTaxonomyTermsHierarchy(taxonomy string) (terms []*TaxonomyTerm) {
+	var walk func(nodes []*TaxonomyTerm, depth int)
+	walk = func(nodes []*TaxonomyTerm, depth int) {
+		for _, node := range nodes {
+			node.Depth = depth
+			terms = append(terms, node)
+			walk(node.Children, depth+1)
+		}
+	}
+	walk(GetTaxonomyTermTree(taxonomy), 0)
+	return
+}
+
+// GetTermAncestors 获得分类的全部祖先分类，由近及远
+func GetTermAncestors(termTaxonomyID uint64) (ancestors []*TaxonomyTerm) {
+	seen := map[uint64]bool{termTaxonomyID: true}
+	term, err := GetTaxonomyTerm(termTaxonomyID)
+	for err == nil && term.Parent > 0 && !seen[term.Parent] {
+		seen[term.Parent] = true
+		if term, err = GetTaxonomyTerm(term.Parent); err == nil {
+			ancestors = append(ancestors, term)
+		}
+	}
+	return
+}
+
+// GetTermDescendantIDs 获得分类的全部子孙分类ID
+func GetTermDescendantIDs(termTaxonomyID uint64) (ids []uint64) {
+	queue := []uint64{termTaxonomyID}
+	seen := map[uint64]bool{termTaxonomyID: true}
+	for len(queue) > 0 {
+		var children []TermTaxonomy
+		Database.Where("parent in (?)", queue).Find(&children)
+		queue = nil
+		for _, child := range children {
+			if !seen[child.TermTaxonomyID] {
+				seen[child.TermTaxonomyID] = true
+				ids = append(ids, child.TermTaxonomyID)
+				queue = append(queue, child.TermTaxonomyID)
+			}
+		}
+	}
+	return
+}
+
+// GetObjectTerms 获得对象在分类法中的分类，taxonomy为空时获得全部分类
+func GetObjectTerms(objectID uint64, taxonomy string) (terms []*TaxonomyTerm) {
+	db := taxonomyTermQuery().
+		Joins("inner join "+Database.NewScope(&TermRelationship{}).TableName()+" tr on tr.term_taxonomy_id = tt.term_taxonomy_id").
+		Where("tr.object_id = ?", objectID)
+	if len(taxonomy) > 0 {
+		db = db.Where("tt.taxonomy = ?", taxonomy)
+	}
+	db.Order("tr.term_order asc, t.name asc").Scan(&terms)
+	return
+}
+
+// SetObjectTerms 设置对象在分类法中的分类，appendTerms为false时替换原有分类
+func SetObjectTerms(objectID uint64, taxonomy string, termTaxonomyIDs []uint64, appendTerms bool) error {
+	if GetTaxonomy(taxonomy) == nil {
+		return ErrTaxonomyNotFound
+	}
+
+	old := map[uint64]bool{}
+	for _, term := range GetObjectTerms(objectID, taxonomy) {
+		old[term.TermTaxonomyID] = true
+	}
+
+	changed := []uint64{}
+	keep := map[uint64]bool{}
+	tx := Database.Begin()
+	for i, id := range termTaxonomyIDs {
+		if keep[id] {
+			continue
+		}
+		term, err := GetTaxonomyTerm(id)
+		if err != nil || term.Taxonomy != taxonomy {
+			tx.Rollback()
+			return fmt.Errorf("分类[%d]不属于分类法[%s]", id, taxonomy)
+		}
+		keep[id] = true
+		if old[id] {
+			if err := tx.Model(&TermRelationship{}).Where("object_id = ? and term_taxonomy_id = ?", objectID, id).Update("term_order", i).Error; err != nil {
+				tx.Rollback()
+				return err
+			}
+			continue
+		}
+		if err := tx.Create(&TermRelationship{ObjectID: objectID, TermTaxonomyID: id, TermOrder: i}).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+		changed = append(changed, id)
+	}
+	if !appendTerms {
+		for id := range old {
+			if keep[id] {
+				continue
+			}
+			if err := tx.Delete(TermRelationship{}, "object_id = ? and term_taxonomy_id = ?", objectID, id).Error; err != nil {
+				tx.Rollback()
+				return err
+			}
+			changed = append(changed, id)
+		}
+	}
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	b, _ := json.Marshal(map[string]interface{}{"object_id": objectID, "taxonomy": taxonomy, "terms": termTaxonomyIDs, "append": appendTerms})
+	hook.ApplyFilterHook("set_object_terms", b)
+	return UpdateTermCount(changed...)
+}
+
+// SetObjectTermsByName 根据名称设置对象的分类，不存在的分类将被创建，常用于标签
+func SetObjectTermsByName(objectID uint64, taxonomy string, names []string, appendTerms bool) error {
+	var ids []uint64
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if len(name) == 0 {
+			continue
+		}
+		term, err := GetTaxonomyTermByName(taxonomy, name)
+		if err != nil {
+			if term, err = InsertTerm(name, taxonomy, nil); err != nil {
+				return err
+			}
+		}
+		ids = append(ids, term.TermTaxonomyID)
+	}
+	return SetObjectTerms(objectID, taxonomy, ids, appendTerms)
+}
+
+// ParseTermTaxonomyIDs 将表单中提交的分类ID转为数值，忽略无效的值
+func ParseTermTaxonomyIDs(form url.Values, key string) (ids []uint64) {
+	for _, v := range form[key] {
+		if id, err := strconv.ParseUint(strings.TrimSpace(v), 10, 64); err == nil && id > 0 {
+			ids = append(ids, id)
+		}
+	}
+	return
+}
+
+// GetObjectTermIDs 获得对象在分类法中的分类ID
+func GetObjectTermIDs(objectID uint64, taxonomy string) (ids []uint64) {
+	for _, term := range GetObjectTerms(objectID, taxonomy) {
+		ids = append(ids, term.TermTaxonomyID)
+	}
+	return
+}
+
+// GetObjectTermNames 获得对象在分类法中的分类名称
+func GetObjectTermNames(objectID uint64, taxonomy string) (names []string) {
+	for _, term := range GetObjectTerms(objectID, taxonomy) {
+		names = append(names, term.Name)
+	}
+	return
+}
+
+// RemoveObjectTerms 移除对象的指定分类
+func RemoveObjectTerms(objectID uint64, termTaxonomyIDs ...uint64) error {
+	if len(termTaxonomyIDs) == 0 {
+		return nil
+	}
+	if err := Database.Delete(TermRelationship{}, "object_id = ? and term_taxonomy_id in (?)", objectID, termTaxonomyIDs).Error; err != nil {
+		return err
+	}
+	return UpdateTermCount(termTaxonomyIDs...)
+}
+
+// DeleteObjectTermRelationships 删除对象的全部分类关系并更新分类计数
+func DeleteObjectTermRelationships(objectID uint64) error {
+	ids := getObjectTermTaxonomyIDs(objectID)
+	if err := Database.Delete(TermRelationship{}, "object_id = ?", objectID).Error; err != nil {
+		return err
+	}
+	return UpdateTermCount(ids...)
+}
+
+// UpdateTermCount 重新统计分类的对象数量，文章分类法只统计已发布的文章
+func UpdateTermCount(termTaxonomyIDs ...uint64) error {
+	relationships := Database.NewScope(&TermRelationship{}).TableName()
+	for _, id := range termTaxonomyIDs {
+		var tt TermTaxonomy
+		if Database.First(&tt, "term_taxonomy_id = ?", id).Error != nil {
+			continue
+		}
+
+		var count int64
+		db := Database.Table(relationships+" tr").Where("tr.term_taxonomy_id = ?", id)
+		if tax := GetTaxonomy(tt.Taxonomy); tax == nil || tax.IsPostTaxonomy() {
+			db = db.Joins("inner join "+Database.NewScope(&Post{}).TableName()+" p on p.id = tr.object_id").
+				Where("p.post_status = ?", PostStatusPublish)
+		}
+		if err := db.Count(&count).Error; err != nil {
+			return err
+		}
+		if err := Database.Model(&TermTaxonomy{}).Where("term_taxonomy_id = ?", id).Update("count", count).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PageTermPosts 分页获得分类下已发布的文章，层级分类法同时包含子分类下的文章
+func PageTermPosts(term *TaxonomyTerm, pageNo, pageSize int) helper.Page {
+	ids := []uint64{term.TermTaxonomyID}
+	objectTypes := []string{"post"}
+	if tax := GetTaxonomy(term.Taxonomy); tax != nil {
+		if tax.Hierarchical {
+			ids = append(ids, GetTermDescendantIDs(term.TermTaxonomyID)...)
+		}
+		objectTypes = tax.ObjectTypes
+	}
+
+	var posts []Post
+	var count int
+	if pageNo < 1 {
+		pageNo = 1
+	}
+	db := Database.Model(&Post{}).Where("id in (select object_id from "+Database.NewScope(&TermRelationship{}).TableName()+" where term_taxonomy_id in (?))", ids).
+		Where("post_status = ? and post_type in (?)", PostStatusPublish, objectTypes)
+	db.Count(&count)
+	db.Order("post_date desc").Offset((pageNo - 1) * pageSize).Limit(pageSize).Find(&posts)
+	return helper.PageUtil(count, pageNo, pageSize, posts)
+}
+
+// GetTaxonomyTermLink 获得分类的前台链接
+func GetTaxonomyTermLink(term *TaxonomyTerm) string {
+	return GetTermLink(&Term{ID: term.TermID, Name: term.Name, Slug: term.Slug}, term.Taxonomy)
+}
+
+// updatePostTermCounts 文章状态变化后更新其所属分类的计数
+func updatePostTermCounts(postID uint64) error {
+	return UpdateTermCount(getObjectTermTaxonomyIDs(postID)...)
+}
+
+func getObjectTermTaxonomyIDs(objectID uint64) (ids []uint64) {
+	var relationships []TermRelationship
+	Database.Where("object_id = ?", objectID).Find(&relationships)
+	for _, r := range relationships {
+		ids = append(ids, r.TermTaxonomyID)
+	}
+	return
+}
+
+func getTaxonomyTermByName(taxonomy, name string, parent uint64) (*TaxonomyTerm, error) {
+	var term TaxonomyTerm
+	if err := taxonomyTermQuery().Where("tt.taxonomy = ? and t.name = ? and tt.parent = ?", taxonomy, name, int64(parent)).Limit(1).Scan(&term).Error; err != nil {
+		return nil, err
+	}
+	return &term, nil
+}
+
+func taxonomyTermQuery() *gorm.DB {
+	return Database.Table(Database.NewScope(&TermTaxonomy{}).TableName() + " tt").
+		Select("tt.term_taxonomy_id, t.id as term_id, t.name, t.slug, tt.taxonomy, tt.description, tt.parent, tt.count").
+		Joins("inner join " + Database.NewScope(&Term{}).TableName() + " t on t.id = tt.term_id")
+}
+
+func doTermHook(key string, term *TaxonomyTerm) {
+	b, _ := json.Marshal(term)
+	hook.ApplyFilterHook(key, b)
+}
@@ -0,0 +1,114 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/insionng/zenpress/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterTaxonomy(t *testing.T) {
+	assert := assert.New(t)
+	tax, err := model.RegisterTaxonomy("genre", &model.TaxonomyOptions{ObjectTypes: []string{"post"}, Public: true, Hierarchical: true})
+	if !assert.NoError(err) {
+		return
+	}
+	defer model.UnregisterTaxonomy("genre")
+	assert.Equal("taxonomy/genre", tax.RewriteSlug)
+	assert.Equal(tax, model.GetTaxonomyByRewriteSlug("taxonomy/genre"))
+	assert.Contains(model.GetObjectTaxonomies("post"), tax)
+
+	_, err = model.RegisterTaxonomy("Genre!", nil)
+	assert.Equal(model.ErrInvalidTaxonomy, err)
+	_, err = model.RegisterTaxonomy("topic", &model.TaxonomyOptions{Public: true, RewriteSlug: "tag"})
+	assert.Error(err, "rewrite slug conflict should fail")
+	_, err = model.RegisterTaxonomy("topic", &model.TaxonomyOptions{Public: true, RewriteSlug: "search"})
+	assert.Error(err, "reserved rewrite slug should fail")
+
+	_, err = model.RegisterPostType("movie", &model.PostTypeOptions{Public: true, HasArchive: true, ArchiveSlug: "movies"})
+	if assert.NoError(err) {
+		defer model.UnregisterPostType("movie")
+		_, err = model.RegisterTaxonomy("topic", &model.TaxonomyOptions{Public: true, RewriteSlug: "movies"})
+		assert.Error(err, "rewrite slug used by a post type archive should fail")
+	}
+	assert.Equal(model.ErrBuiltinTaxonomy, model.UnregisterTaxonomy("category"))
+}
+
+func TestTaxonomyTerms(t *testing.T) {
+	assert := assert.New(t)
+	parent, err := model.InsertTerm("测试父分类", "category", &model.TermOptions{Slug: "test-parent"})
+	if !assert.NoError(err) {
+		return
+	}
+	defer model.DeleteTaxonomyTerm(parent.TermTaxonomyID)
+	child, err := model.InsertTerm("测试子分类", "category", &model.TermOptions{Slug: "test-parent", Parent: parent.TermTaxonomyID})
+	if !assert.NoError(err) {
+		return
+	}
+	defer model.DeleteTaxonomyTerm(child.TermTaxonomyID)
+	assert.Equal("test-parent-test-parent", child.Slug, "duplicate slug should append the parent slug")
+
+	_, err = model.InsertTerm("测试父分类", "category", nil)
+	assert.Equal(model.ErrTermExists, err)
+	_, err = model.UpdateTaxonomyTerm(parent.TermTaxonomyID, &model.TermOptions{Name: parent.Name, Parent: child.TermTaxonomyID})
+	assert.Equal(model.ErrInvalidTermParent, err, "a term can not be its own descendant")
+	assert.Equal([]uint64{child.TermTaxonomyID}, model.GetTermDescendantIDs(parent.TermTaxonomyID))
+
+	post := &model.Post{PostTitle: "测试分类法", PostStatus: model.PostStatusDraft}
+	if !assert.NoError(model.InsertPost(post)) {
+		return
+	}
+	defer model.RemovePost(post.ID)
+	assert.NoError(model.SetObjectTerms(post.ID, "category", []uint64{child.TermTaxonomyID}, false))
+	assert.NoError(model.SetObjectTermsByName(post.ID, "post_tag", []string{"测试标签一", "测试标签二"}, false))
+	tags := model.GetObjectTerms(post.ID, "post_tag")
+	if assert.Equal(2, len(tags)) {
+		defer model.DeleteTaxonomyTerm(tags[0].TermTaxonomyID)
+		defer model.DeleteTaxonomyTerm(tags[1].TermTaxonomyID)
+	}
+
+	term, _ := model.GetTaxonomyTerm(child.TermTaxonomyID)
+	assert.Equal(int64(0), term.Count, "drafts are not counted")
+	assert.NoError(model.PublishPost(post.ID))
+	term, _ = model.GetTaxonomyTerm(child.TermTaxonomyID)
+	assert.Equal(int64(1), term.Count)
+
+	assert.NoError(model.RemoveObjectTerms(post.ID, child.TermTaxonomyID))
+	term, _ = model.GetTaxonomyTerm(child.TermTaxonomyID)
+	assert.Equal(int64(0), term.Count)
+	assert.Equal(2, len(model.GetObjectTerms(post.ID, "")))
+
+	assert.NoError(model.DeleteTaxonomyTerm(parent.TermTaxonomyID))
+	term, _ = model.GetTaxonomyTerm(child.TermTaxonomyID)
+	assert.Equal(uint64(0), term.Parent, "children move up to the deleted term's parent")
+}
+
+func TestPageTermPosts(t *testing.T) {
+	assert := assert.New(t)
+	parent, err := model.InsertTerm("测试归档父分类", "category", nil)
+	if !assert.NoError(err) {
+		return
+	}
+	defer model.DeleteTaxonomyTerm(parent.TermTaxonomyID)
+	child, err := model.InsertTerm("测试归档子分类", "category", &model.TermOptions{Slug: "archive-child", Parent: parent.TermTaxonomyID})
+	if !assert.NoError(err) {
+		return
+	}
+	defer model.DeleteTaxonomyTerm(child.TermTaxonomyID)
+
+	for _, status := range []string{model.PostStatusPublish, model.PostStatusPublish, model.PostStatusDraft} {
+		post := &model.Post{PostTitle: "测试分类归档", PostStatus: status}
+		if !assert.NoError(model.InsertPost(post)) {
+			return
+		}
+		defer model.RemovePost(post.ID)
+		assert.NoError(model.SetObjectTerms(post.ID, "category", []uint64{child.TermTaxonomyID}, false))
+	}
+
+	page := model.PageTermPosts(parent, 1, 1)
+	assert.Equal(2, page.TotalCount, "posts in child categories are listed, drafts are not")
+	assert.Equal(2, page.TotalPage)
+	assert.Equal(1, len(page.List.([]model.Post)))
+	assert.Equal("/category/archive-child", model.GetTaxonomyTermLink(child))
+}
@@ -1,6 +1,11 @@
 package model
 
-import "github.com/jinzhu/gorm"
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/jinzhu/gorm"
+)
 
 // Term 文章分类、链接分类、标签的信息表。
 type Term struct {
@@ -30,8 +35,10 @@ func GetTerm(id uint64) (db *gorm.DB, Term Term) {
 
 // UpdateTerm 更新分类
 func UpdateTerm(id uint64, name string) (db *gorm.DB, term Term) {
-	term = Term{Name: name}
-	db = Database.Model(&term).Update("id", id)
+	db = Database.Model(&Term{}).Where("id = ?", id).Update("name", name)
+	if db.Error == nil {
+		db = Database.First(&term, "id = ?", id)
+	}
 	return
 }
 
@@ -40,3 +47,22 @@ func DeleteTerm(id uint64) (db *gorm.DB) {
 	db = Database.Delete(Term{}, "id = ?", id)
 	return
 }
+
+// GetTermLink 获得分类链接
+func GetTermLink(term *Term, taxonomy string) string {
+	if tax := GetTaxonomy(taxonomy); tax != nil {
+		return fmt.Sprintf("/%s/%s", tax.RewriteSlug, url.PathEscape(term.Slug))
+	}
+	return fmt.Sprintf("/taxonomy/%s/%s", taxonomy, url.PathEscape(term.Slug))
+}
+
+// GetTermsByTaxonomy 获得指定分类类型下的所有分类
+func GetTermsByTaxonomy(taxonomy string) (terms []Term) {
+	Database.Table(Database.NewScope(&Term{}).TableName()+" t").
+		Select("t.*").
+		Joins("inner join "+Database.NewScope(&TermTaxonomy{}).TableName()+" tt on tt.term_id = t.id").
+		Where("tt.taxonomy = ?", taxonomy).
+		Order("t.name asc").
+		Scan(&terms)
+	return
+}
@@ -1,8 +1,73 @@
 package model
 
-// TermRelationship 分类与文章信息表（wp_posts）、链接表(wp_links)的关联表。
+import (
+	"github.com/jinzhu/gorm"
+)
+
+// TermRelationship 分类与文章信息表（wp_posts）、链接表(wp_links)的关联表，以对象ID与分类方法ID为联合主键。
 type TermRelationship struct {
-	ObjectID       uint64 `gorm:"primary_key"` //对应文章ID/链接ID
-	TermTaxonomyID uint64 //对应分类方法ID
+	ObjectID       uint64 `gorm:"primary_key;auto_increment:false"`       //对应文章ID/链接ID
+	TermTaxonomyID uint64 `gorm:"primary_key;auto_increment:false;index"` //对应分类方法ID
 	TermOrder      int    //排序
 }
+
+// createTermRelationshipsTable 以显式的建表语句创建关联表：gorm为sqlite生成建表语句时会把每个整数主键都设为自增主键，无法建立联合主键
+func createTermRelationshipsTable(db *gorm.DB) error {
+	table := db.NewScope(&TermRelationship{}).TableName()
+	if db.HasTable(table) {
+		return nil
+	}
+	options := ""
+	if DataType == "mysql" {
+		options = " ENGINE=InnoDB"
+	}
+	return db.Exec("CREATE TABLE " + table + " (object_id bigint NOT NULL DEFAULT 0, term_taxonomy_id bigint NOT NULL DEFAULT 0, term_order integer NOT NULL DEFAULT 0, PRIMARY KEY (object_id, term_taxonomy_id))" + options).Error
+}
+
+// migrateTermRelationships 早期版本的关联表仅以object_id为主键，一个对象只能关联一个分类，迁移为联合主键
+func migrateTermRelationships(db *gorm.DB) error {
+	table := db.NewScope(&TermRelationship{}).TableName()
+	if !db.HasTable(table) {
+		return nil
+	}
+
+	var keys int
+	switch DataType {
+	case "sqlite":
+		db.Raw("SELECT COUNT(*) FROM pragma_table_info(?) WHERE pk > 0", table).Row().Scan(&keys)
+	case "mysql":
+		db.Raw("SELECT COUNT(*) FROM information_schema.KEY_COLUMN_USAGE WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND CONSTRAINT_NAME = 'PRIMARY'", table).Row().Scan(&keys)
+	case "postgres":
+		db.Raw("SELECT COUNT(*) FROM information_schema.table_constraints tc INNER JOIN information_schema.key_column_usage kcu ON kcu.constraint_name = tc.constraint_name AND kcu.table_name = tc.table_name WHERE tc.table_name = ? AND tc.constraint_type = 'PRIMARY KEY'", table).Row().Scan(&keys)
+	}
+	if keys != 1 {
+		return nil
+	}
+
+	old := table + "_old"
+	tx := db.Begin()
+	if err := tx.Exec("ALTER TABLE " + table + " RENAME TO " + old).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	//PostgreSQL的主键约束名不随表名改变，须一并改名，否则新表的主键约束会重名
+	if DataType == "postgres" {
+		if err := tx.Exec("ALTER TABLE " + old + " RENAME CONSTRAINT " + table + "_pkey TO " + old + "_pkey").Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := createTermRelationshipsTable(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Exec("INSERT INTO " + table + " (object_id, term_taxonomy_id, term_order) SELECT object_id, term_taxonomy_id, term_order FROM " + old).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.DropTable(old).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
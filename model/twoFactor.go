@@ -0,0 +1,279 @@
+package model
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/insionng/zenpress/helper"
+	"github.com/insionng/zenpress/module/auth"
+)
+
+// 两步验证的数据保存在用户数据中的键名，密钥以站点密钥加密保存
+const (
+	TwoFactorSecretMetaKey        = "two_factor_secret"
+	TwoFactorPendingSecretMetaKey = "two_factor_pending_secret"
+	TwoFactorRecoveryMetaKey      = "two_factor_recovery_codes"
+	TwoFactorLastStepMetaKey      = "two_factor_last_step"
+)
+
+const (
+	// TwoFactorRecoveryCodeCount 每次生成的恢复码数量
+	TwoFactorRecoveryCodeCount = 10
+	// TwoFactorPermissionName 要求两步验证的权限名，授予角色后该角色的用户须启用两步验证
+	TwoFactorPermissionName = "two_factor_required"
+	// TwoFactorPermissionURL 要求两步验证的权限地址，不是站内路径，不会被其它权限的URL规则匹配
+	TwoFactorPermissionURL = "two-factor:required"
+)
+
+// 两步验证的错误
+var (
+	ErrInvalidTwoFactorCode   = errors.New("验证码错误")
+	ErrTwoFactorNotEnrolling  = errors.New("请先生成两步验证密钥")
+	ErrTwoFactorNotEnabled    = errors.New("尚未启用两步验证")
+	ErrTwoFactorSecretCorrupt = errors.New("两步验证密钥无法解密")
+)
+
+// TwoFactorThrottle 两步验证失败的限制器，按用户统计
+var TwoFactorThrottle = auth.NewThrottle(LoginAccountLimit, LoginThrottleWindow)
+
+// twoFactorKey 由站点密钥派生加密两步验证密钥所用的AES-256密钥
+func twoFactorKey() []byte {
+	sum := sha256.Sum256(append([]byte("two-factor:"), GetSecretKey()...))
+	return sum[:]
+}
+
+func encryptTwoFactorSecret(secret string) (string, error) {
+	sealed, err := helper.AesGCMEncrypt([]byte(secret), twoFactorKey())
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func decryptTwoFactorSecret(value string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return "", ErrTwoFactorSecretCorrupt
+	}
+	secret, err := helper.AesGCMDecrypt(sealed, twoFactorKey())
+	if err != nil {
+		return "", ErrTwoFactorSecretCorrupt
+	}
+	return string(secret), nil
+}
+
+// TwoFactorEnabled 判断用户是否已启用两步验证
+func TwoFactorEnabled(userID uint64) bool {
+	return len(GetUsermetaValue(userID, TwoFactorSecretMetaKey)) > 0
+}
+
+// TwoFactorEnrollment 获得用户尚未确认的两步验证密钥，不存在时生成，返回密钥及供身份验证器扫描的地址
+func TwoFactorEnrollment(user User) (string, string, error) {
+	secret, err := decryptTwoFactorSecret(GetUsermetaValue(user.ID, TwoFactorPendingSecretMetaKey))
+	if err != nil {
+		secret = auth.NewTOTPSecret()
+		value, err := encryptTwoFactorSecret(secret)
+		if err != nil {
+			return "", "", err
+		}
+		if err = SetUsermetaValue(user.ID, TwoFactorPendingSecretMetaKey, value).Error; err != nil {
+			return "", "", err
+		}
+	}
+	return secret, auth.TOTPURI(GetBlogName(), user.UserLogin, secret), nil
+}
+
+// EnableTwoFactor 以身份验证器上的验证码确认密钥并启用两步验证，返回新的恢复码
+func EnableTwoFactor(userID uint64, code string) ([]string, error) {
+	value := GetUsermetaValue(userID, TwoFactorPendingSecretMetaKey)
+	if len(value) == 0 {
+		return nil, ErrTwoFactorNotEnrolling
+	}
+	secret, err := decryptTwoFactorSecret(value)
+	if err != nil {
+		return nil, err
+	}
+	ok, step := auth.VerifyTOTP(secret, normalizeTwoFactorCode(code), time.Now())
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+	if err = SetUsermetaValue(userID, TwoFactorSecretMetaKey, value).Error; err != nil {
+		return nil, err
+	}
+	DeleteUsermetaByKey(userID, TwoFactorPendingSecretMetaKey)
+	SetUsermetaValue(userID, TwoFactorLastStepMetaKey, strconv.FormatUint(step, 10))
+	return RegenerateRecoveryCodes(userID)
+}
+
+// DisableTwoFactor 关闭两步验证并删除密钥及恢复码
+func DisableTwoFactor(userID uint64) {
+	for _, key := range []string{TwoFactorSecretMetaKey, TwoFactorPendingSecretMetaKey, TwoFactorRecoveryMetaKey, TwoFactorLastStepMetaKey} {
+		DeleteUsermetaByKey(userID, key)
+	}
+}
+
+// normalizeTwoFactorCode 去掉验证码或恢复码中的空格及减号
+func normalizeTwoFactorCode(code string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(code)))
+}
+
+// newRecoveryCode 生成形如xxxxx-xxxxx的恢复码
+func newRecoveryCode() string {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	code := strings.ToLower(base32.StdEncoding.EncodeToString(b))[:10]
+	return code[:5] + "-" + code[5:]
+}
+
+func recoveryCodeHashes(userID uint64) []string {
+	var hashes []string
+	json.Unmarshal([]byte(GetUsermetaValue(userID, TwoFactorRecoveryMetaKey)), &hashes)
+	return hashes
+}
+
+func saveRecoveryCodeHashes(userID uint64, hashes []string) error {
+	value, _ := json.Marshal(hashes)
+	return SetUsermetaValue(userID, TwoFactorRecoveryMetaKey, string(value)).Error
+}
+
+// RegenerateRecoveryCodes 生成新的恢复码，之前的恢复码全部失效；数据库中只保存哈希，明文只在此时返回
+func RegenerateRecoveryCodes(userID uint64) ([]string, error) {
+	if !TwoFactorEnabled(userID) {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	codes := make([]string, TwoFactorRecoveryCodeCount)
+	hashes := make([]string, TwoFactorRecoveryCodeCount)
+	for i := range codes {
+		codes[i] = newRecoveryCode()
+		hashes[i] = activationKeyHash(normalizeTwoFactorCode(codes[i]))
+	}
+	if err := saveRecoveryCodeHashes(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// CountRecoveryCodes 获得用户剩余可用的恢复码数量
+func CountRecoveryCodes(userID uint64) int {
+	return len(recoveryCodeHashes(userID))
+}
+
+// VerifyTwoFactor 校验身份验证器上的验证码或恢复码，恢复码只能使用一次，同一验证码也不能重复使用；
+// 失败次数按用户限制，usedRecovery表示是否使用了恢复码
+func VerifyTwoFactor(userID uint64, code string) (usedRecovery bool, err error) {
+	key, now := fmt.Sprint(userID), time.Now()
+	if blocked, wait := TwoFactorThrottle.Blocked(key, now); blocked {
+		return false, &LoginThrottledError{RetryAfter: wait}
+	}
+	secret, err := decryptTwoFactorSecret(GetUsermetaValue(userID, TwoFactorSecretMetaKey))
+	if err != nil {
+		return false, ErrTwoFactorNotEnabled
+	}
+
+	code = normalizeTwoFactorCode(code)
+	if len(code) == auth.TOTPDigits {
+		ok, step := auth.VerifyTOTP(secret, code, now)
+		//以条件更新记录已使用的时间步，同一验证码并发提交时只有一个请求通过
+		value := GetUsermetaValue(userID, TwoFactorLastStepMetaKey)
+		last, _ := strconv.ParseUint(value, 10, 64)
+		if ok && step > last && CompareAndSetUsermetaValue(userID, TwoFactorLastStepMetaKey, value, strconv.FormatUint(step, 10)) {
+			TwoFactorThrottle.Reset(key)
+			return false, nil
+		}
+	} else if len(code) > 0 {
+		hash := activationKeyHash(code)
+		value := GetUsermetaValue(userID, TwoFactorRecoveryMetaKey)
+		var hashes []string
+		json.Unmarshal([]byte(value), &hashes)
+		for i, stored := range hashes {
+			if auth.VerifyToken(stored, hash) {
+				remaining, _ := json.Marshal(append(hashes[:i:i], hashes[i+1:]...))
+				if !CompareAndSetUsermetaValue(userID, TwoFactorRecoveryMetaKey, value, string(remaining)) {
+					break
+				}
+				TwoFactorThrottle.Reset(key)
+				return true, nil
+			}
+		}
+	}
+	TwoFactorThrottle.Fail(key, now)
+	return false, ErrInvalidTwoFactorCode
+}
+
+// TwoFactorPermission 获得要求两步验证的权限，不存在时创建
+func TwoFactorPermission() (Permission, error) {
+	var permission Permission
+	if Database.Where("name = ?", TwoFactorPermissionName).First(&permission).Error == nil {
+		return permission, nil
+	}
+	permission = Permission{Name: TwoFactorPermissionName, URL: TwoFactorPermissionURL, Description: "要求两步验证"}
+	return permission, Database.Create(&permission).Error
+}
+
+// TwoFactorRoleIDs 获得要求两步验证的角色ID
+func TwoFactorRoleIDs() []uint64 {
+	var permission Permission
+	if Enforcer == nil || Database.Where("name = ?", TwoFactorPermissionName).First(&permission).Error != nil {
+		return nil
+	}
+	var ids []uint64
+	for _, rule := range Enforcer.GetFilteredPolicy(1, fmt.Sprint(permission.ID)) {
+		if id, err := parseRoleSubject(rule[0]); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// RoleRequiresTwoFactor 判断角色是否要求两步验证
+func RoleRequiresTwoFactor(roleID uint64) bool {
+	for _, id := range TwoFactorRoleIDs() {
+		if id == roleID {
+			return true
+		}
+	}
+	return false
+}
+
+// SetRoleTwoFactor 设置角色是否要求两步验证
+func SetRoleTwoFactor(roleID uint64, required bool) error {
+	if Enforcer == nil {
+		return errors.New("权限模块尚未初始化")
+	}
+	if required == RoleRequiresTwoFactor(roleID) {
+		return nil
+	}
+	permission, err := TwoFactorPermission()
+	if err != nil {
+		return err
+	}
+	if required {
+		SaveRolePermission(int(roleID), int(permission.ID))
+		return nil
+	}
+	Enforcer.RemovePolicy([]string{roleSubject(roleID), fmt.Sprint(permission.ID)})
+	return Database.Exec("delete from "+DatabaseTablePrefix+"role_permissions where role_id = ? and permission_id = ?", roleID, permission.ID).Error
+}
+
+// UserRequiresTwoFactor 判断用户所属的角色中是否有要求两步验证的角色
+func UserRequiresTwoFactor(userID uint64) bool {
+	if Enforcer == nil {
+		return false
+	}
+	for _, role := range Enforcer.GetRolesForUser(fmt.Sprint(userID)) {
+		if roleID, err := parseRoleSubject(role); err == nil && RoleRequiresTwoFactor(roleID) {
+			return true
+		}
+	}
+	return false
+}
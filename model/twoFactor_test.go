@@ -0,0 +1,127 @@
+package model_test
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/insionng/zenpress/model"
+	"github.com/insionng/zenpress/module/auth"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTwoFactor(t *testing.T) {
+	assert := assert.New(t)
+	login := fmt.Sprintf("totp%d", time.Now().UnixNano())
+	user := &model.User{UserLogin: login, UserEmail: login + "@example.com"}
+	model.SaveUser(user)
+	defer model.Database.Delete(user)
+	defer model.DisableTwoFactor(user.ID)
+
+	assert.False(model.TwoFactorEnabled(user.ID))
+	_, err := model.EnableTwoFactor(user.ID, "000000")
+	assert.Equal(model.ErrTwoFactorNotEnrolling, err)
+
+	secret, uri, err := model.TwoFactorEnrollment(*user)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Contains(uri, "otpauth://totp/")
+	assert.Contains(uri, "secret="+secret)
+	again, _, _ := model.TwoFactorEnrollment(*user)
+	assert.Equal(secret, again, "the pending secret should be kept until confirmed")
+	stored := model.GetUsermetaValue(user.ID, model.TwoFactorPendingSecretMetaKey)
+	assert.NotContains(stored, secret, "secrets should be stored encrypted")
+
+	_, err = model.EnableTwoFactor(user.ID, "bad")
+	assert.Equal(model.ErrInvalidTwoFactorCode, err)
+	code, _ := auth.TOTPCode(secret, time.Now())
+	codes, err := model.EnableTwoFactor(user.ID, code)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.True(model.TwoFactorEnabled(user.ID))
+	assert.Len(codes, model.TwoFactorRecoveryCodeCount)
+	assert.Equal(model.TwoFactorRecoveryCodeCount, model.CountRecoveryCodes(user.ID))
+	assert.Empty(model.GetUsermetaValue(user.ID, model.TwoFactorPendingSecretMetaKey))
+
+	_, err = model.VerifyTwoFactor(user.ID, code)
+	assert.Equal(model.ErrInvalidTwoFactorCode, err, "codes should not be accepted twice")
+	next, _ := auth.TOTPCode(secret, time.Now().Add(auth.TOTPPeriod*time.Second))
+	var wg sync.WaitGroup
+	var accepted int32
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if used, err := model.VerifyTwoFactor(user.ID, next); err == nil && !used {
+				atomic.AddInt32(&accepted, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(int32(1), accepted, "concurrent submissions of one code should only pass once")
+	model.TwoFactorThrottle.Reset(fmt.Sprint(user.ID))
+
+	used, err := model.VerifyTwoFactor(user.ID, " "+strings.ToUpper(codes[0])+" ")
+	assert.NoError(err)
+	assert.True(used)
+	assert.Equal(model.TwoFactorRecoveryCodeCount-1, model.CountRecoveryCodes(user.ID))
+	_, err = model.VerifyTwoFactor(user.ID, codes[0])
+	assert.Equal(model.ErrInvalidTwoFactorCode, err, "recovery codes should be single-use")
+
+	renewed, err := model.RegenerateRecoveryCodes(user.ID)
+	assert.NoError(err)
+	_, err = model.VerifyTwoFactor(user.ID, codes[1])
+	assert.Equal(model.ErrInvalidTwoFactorCode, err, "regenerating should invalidate old codes")
+	_, err = model.VerifyTwoFactor(user.ID, renewed[1])
+	assert.NoError(err)
+
+	model.TwoFactorThrottle.Reset(fmt.Sprint(user.ID))
+	defer model.TwoFactorThrottle.Reset(fmt.Sprint(user.ID))
+	for i := 0; i < model.LoginAccountLimit; i++ {
+		model.VerifyTwoFactor(user.ID, "000000")
+	}
+	_, err = model.VerifyTwoFactor(user.ID, renewed[2])
+	assert.IsType(&model.LoginThrottledError{}, err)
+
+	model.DisableTwoFactor(user.ID)
+	assert.False(model.TwoFactorEnabled(user.ID))
+	assert.Equal(0, model.CountRecoveryCodes(user.ID))
+}
+
+func TestRoleRequiresTwoFactor(t *testing.T) {
+	assert := assert.New(t)
+	loadEnforcer(t)
+
+	role := &model.Role{Name: fmt.Sprintf("editor%d", time.Now().UnixNano())}
+	model.SaveRole(role)
+	defer model.Database.Delete(role)
+	user := &model.User{UserLogin: fmt.Sprintf("role%d", time.Now().UnixNano())}
+	model.SaveUser(user)
+	defer model.Database.Delete(user)
+	model.SaveUserRole(int(user.ID), int(role.ID))
+	defer model.DeleteUserRolesByUserId(int(user.ID))
+
+	assert.False(model.UserRequiresTwoFactor(user.ID))
+	assert.NoError(model.SetRoleTwoFactor(role.ID, true))
+	defer model.SetRoleTwoFactor(role.ID, false)
+	assert.True(model.RoleRequiresTwoFactor(role.ID))
+	assert.Contains(model.TwoFactorRoleIDs(), role.ID)
+	assert.True(model.UserRequiresTwoFactor(user.ID))
+
+	model.LoadEnforcer()
+	assert.True(model.UserRequiresTwoFactor(user.ID), "the requirement should be loaded from the database")
+	permission, _ := model.TwoFactorPermission()
+	assert.False(model.Enforcer.Enforce(fmt.Sprint(user.ID), "/root/"), "the requirement should not grant other permissions")
+	assert.Equal(model.TwoFactorPermissionURL, permission.URL)
+
+	assert.NoError(model.SetRoleTwoFactor(role.ID, false))
+	assert.False(model.UserRequiresTwoFactor(user.ID))
+	model.LoadEnforcer()
+	assert.False(model.RoleRequiresTwoFactor(role.ID))
+}
@@ -2,6 +2,8 @@ package model
 
 import (
 	"fmt"
+	"log"
+	"sync"
 	"time"
 
 	"github.com/insionng/zenpress/helper"
@@ -29,6 +31,11 @@ type User struct {
 
 var Enforcer *casbin.Enforcer = nil
 
+// RBACModelPath 权限模型配置文件的路径
+var RBACModelPath = "content/config/rbac_model.conf"
+
+var enforcerOnce sync.Once
+
 func getAttr(name string, attr string) string {
 	if attr != "url" {
 		return ""
@@ -50,9 +57,15 @@ func getAttrFunc(args ...interface{}) (interface{}, error) {
 	return (string)(getAttr(name, attr)), nil
 }
 
+// Init 初始化权限模块，主题热加载等重复调用时只执行一次
 func Init() {
+	enforcerOnce.Do(LoadEnforcer)
+}
+
+// LoadEnforcer 重新创建权限模块，从数据库载入用户角色及角色权限
+func LoadEnforcer() {
 	Enforcer = &casbin.Enforcer{}
-	Enforcer.InitWithFile("content/config/rbac_model.conf", "")
+	Enforcer.InitWithFile(RBACModelPath, "")
 	Enforcer.AddActionAttributeFunction(getAttrFunc)
 
 	type UserRoleResult struct {
@@ -60,9 +73,9 @@ func Init() {
 		RoleID uint64
 	}
 	var res []UserRoleResult
-	Database.Raw("select user_id, role_id from user_roles").Scan(&res)
+	Database.Raw("select user_id, role_id from " + DatabaseTablePrefix + "user_roles").Scan(&res)
 	for _, param := range res {
-		Enforcer.AddRoleForUser(fmt.Sprintf("%v", param.UserID), fmt.Sprintf("%v", param.RoleID))
+		Enforcer.AddRoleForUser(fmt.Sprintf("%v", param.UserID), roleSubject(param.RoleID))
 	}
 
 	type RolePermissionResult struct {
@@ -70,9 +83,13 @@ func Init() {
 		PermissionID uint64
 	}
 	var rez []RolePermissionResult
-	Database.Raw("select role_id, permission_id from role_permissions").Scan(&rez)
+	Database.Raw("select role_id, permission_id from " + DatabaseTablePrefix + "role_permissions").Scan(&rez)
 	for _, param := range rez {
-		Enforcer.AddPermissionForUser(fmt.Sprintf("%v", param.RoleID), fmt.Sprintf("%v", param.PermissionID))
+		Enforcer.AddPermissionForUser(roleSubject(param.RoleID), fmt.Sprintf("%v", param.PermissionID))
+	}
+
+	if _, err := EnsureAdministratorRole(); err != nil {
+		log.Println("init administrator role error:", err)
 	}
 }
 
@@ -83,24 +100,61 @@ func FindUserById(id int) (bool, User) {
 	return db.Error == nil, user
 }
 
+// FindUserByToken 以个人访问令牌查找用户，令牌无效、已过期或已撤销时返回false
 func FindUserByToken(token string) (bool, User) {
+	ok, _, user := FindAccessToken(token)
+	return ok, user
+}
 
+var (
+	dummyPasswordHash     string
+	dummyPasswordHashOnce sync.Once
+)
+
+// Login 以登录名及密码校验用户，旧版48位哈希及phpass哈希校验成功后自动以新算法重新生成
+func Login(username string, password string) (bool, User) {
 	var user User
-	db := Database.Where("token = ?", token).First(&user)
+	if Database.Where("user_login = ?", username).First(&user).Error != nil {
+		verifyDummyPassword(password)
+		return false, User{}
+	}
+	ok, rehash := helper.VerifyPassword(user.UserPass, password)
+	if !ok {
+		return false, User{}
+	}
+	if rehash {
+		if err := SetUserPassword(&user, password); err != nil {
+			log.Printf("rehash password of user %d error: %v", user.ID, err)
+		}
+	}
+	return true, user
+}
 
-	return db.Error == nil, user
+// verifyDummyPassword 登录名不存在时同样比较一次bcrypt哈希，使响应时间与密码错误时一致，避免据此探测登录名
+func verifyDummyPassword(password string) {
+	dummyPasswordHashOnce.Do(func() {
+		dummyPasswordHash, _ = helper.HashPassword("zenpress-dummy-password")
+	})
+	helper.VerifyPassword(dummyPasswordHash, password)
 }
 
-func Login(username string, password string) (bool, User) {
-	var user User
-	db := Database.Where("username = ? and password = ?", username, password).First(&user)
-	return db.Error == nil, user
+// SetUserPassword 以当前的密码哈希算法设置用户密码
+func SetUserPassword(user *User, password string) error {
+	hashed, err := helper.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	if err = Database.Model(&User{}).Where("id = ?", user.ID).Update("user_pass", hashed).Error; err != nil {
+		return err
+	}
+	user.UserPass = hashed
+	return nil
 }
 
 func FindUserByUserName(username string) (bool, User) {
 
 	var user User
-	db := Database.Where("username = ?", username).First(&user)
+	db := Database.Where("user_login = ?", username).First(&user)
 	return db.Error == nil, user
 }
 
@@ -138,17 +192,35 @@ func FindPermissionByUserIdAndPermissionName(userId int, name string) bool {
 
 func DeleteUser(user *User) {
 	Enforcer.DeleteUser(fmt.Sprintf("%v", user.ID))
+	RevokeUserLogins(user.ID)
 	Database.Delete(user)
 }
 
+// SetUserDisabled 停用或恢复账号，停用时已登录的会话、“记住登录”令牌及个人访问令牌全部失效
+func SetUserDisabled(userID uint64, disabled bool) error {
+	value := 0
+	if disabled {
+		value = 1
+	}
+	if err := Database.Model(&User{}).Where("id = ?", userID).Update("deleted", value).Error; err != nil {
+		return err
+	}
+	if disabled {
+		RevokeUserLogins(userID)
+	}
+	return nil
+}
+
+// DeleteUserRolesByUserId 移除用户的全部角色，用户的个人访问令牌随之撤销
 func DeleteUserRolesByUserId(user_id int) {
 	Enforcer.DeleteRolesForUser(fmt.Sprintf("%v", user_id))
-	Database.Exec("delete from user_roles where user_id = ?", user_id)
+	Database.Exec("delete from "+DatabaseTablePrefix+"user_roles where user_id = ?", user_id)
+	RevokeUserAccessTokens(uint64(user_id))
 }
 
 func SaveUserRole(user_id int, role_id int) {
-	Enforcer.AddRoleForUser(fmt.Sprintf("%v", user_id), fmt.Sprintf("%v", role_id))
-	Database.Exec("insert into user_roles (user_id, role_id) values (?, ?)", user_id, role_id)
+	Enforcer.AddRoleForUser(fmt.Sprintf("%v", user_id), roleSubject(role_id))
+	Database.Exec("insert into "+DatabaseTablePrefix+"user_roles (user_id, role_id) values (?, ?)", user_id, role_id)
 }
 
 type UserRoleResult struct {
@@ -160,6 +232,6 @@ type UserRoleResult struct {
 func FindUserRolesByUserId(user_id int) []UserRoleResult {
 
 	var res []UserRoleResult
-	Database.Raw("select id, user_id, role_id from user_roles where user_id = ?", user_id).Scan(&res)
+	Database.Raw("select id, user_id, role_id from "+DatabaseTablePrefix+"user_roles where user_id = ?", user_id).Scan(&res)
 	return res
 }
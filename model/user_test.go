@@ -0,0 +1,55 @@
+package model_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/insionng/zenpress/helper"
+	"github.com/insionng/zenpress/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogin(t *testing.T) {
+	assert := assert.New(t)
+	defer func(cost int) { helper.PasswordHashCost = cost }(helper.PasswordHashCost)
+	helper.PasswordHashCost = 4
+
+	login := fmt.Sprintf("legacy%d", time.Now().UnixNano())
+	user := &model.User{UserLogin: login, UserPass: helper.EncryptHash("secret", nil)}
+	model.SaveUser(user)
+	defer model.Database.Delete(user)
+
+	ok, _ := model.Login(login, "wrong")
+	assert.False(ok)
+	ok, found := model.Login(login, "secret")
+	if assert.True(ok) {
+		assert.Equal(user.ID, found.ID)
+		assert.True(strings.HasPrefix(found.UserPass, "$2a$04$"), "legacy hashes should be upgraded to bcrypt")
+	}
+	_, stored := model.FindUserByUserName(login)
+	assert.Equal(found.UserPass, stored.UserPass)
+
+	ok, found = model.Login(login, "secret")
+	assert.True(ok)
+	assert.Equal(stored.UserPass, found.UserPass, "current hashes should not be regenerated")
+
+	helper.PasswordHashCost = 5
+	model.Login(login, "secret")
+	_, stored = model.FindUserByUserName(login)
+	assert.True(strings.HasPrefix(stored.UserPass, "$2a$05$"), "weaker bcrypt hashes should be upgraded")
+
+	wp := &model.User{UserLogin: "wp" + login, UserPass: "$P$9IQRaTwmfeRo7ud9Fh4E2PdI0S3r.L0"}
+	model.SaveUser(wp)
+	defer model.Database.Delete(wp)
+	ok, _ = model.Login(wp.UserLogin, "test1234")
+	assert.False(ok)
+	ok, found = model.Login(wp.UserLogin, "test12345")
+	assert.True(ok)
+	assert.True(strings.HasPrefix(found.UserPass, "$2a$"), "phpass hashes should be upgraded to bcrypt")
+
+	ok, _ = model.Login("nobody"+login, "secret")
+	assert.False(ok)
+}
@@ -40,3 +40,38 @@ func DeleteUsermeta(id uint64) (db *gorm.DB) {
 	db = Database.Delete(Usermeta{}, "id = ?", id)
 	return
 }
+
+// GetUsermetaByKey 获得指定用户的指定数据
+func GetUsermetaByKey(userID uint64, metaKey string) (db *gorm.DB, usermeta Usermeta) {
+	db = Database.First(&usermeta, "user_id = ? and meta_key = ?", userID, metaKey)
+	return
+}
+
+// GetUsermetaValue 获得指定用户的数据值，不存在时返回空字符串
+func GetUsermetaValue(userID uint64, metaKey string) string {
+	if db, usermeta := GetUsermetaByKey(userID, metaKey); db.Error == nil {
+		return usermeta.MetaValue
+	}
+	return ""
+}
+
+// SetUsermetaValue 设置指定用户的数据值，不存在时新增
+func SetUsermetaValue(userID uint64, metaKey, metaValue string) (db *gorm.DB) {
+	if db, usermeta := GetUsermetaByKey(userID, metaKey); db.Error == nil {
+		return Database.Model(&usermeta).Update("meta_value", metaValue)
+	}
+	return AddUsermeta(userID, metaKey, metaValue)
+}
+
+// CompareAndSetUsermetaValue 仅当指定用户的数据仍为old时才更新为value，以条件更新保证并发时只有一个请求成功
+func CompareAndSetUsermetaValue(userID uint64, metaKey, old, value string) bool {
+	db := Database.Model(&Usermeta{}).Where("user_id = ? and meta_key = ? and meta_value = ?", userID, metaKey, old).
+		UpdateColumn("meta_value", value)
+	return db.Error == nil && db.RowsAffected > 0
+}
+
+// DeleteUsermetaByKey 删除指定用户的指定数据
+func DeleteUsermetaByKey(userID uint64, metaKey string) (db *gorm.DB) {
+	db = Database.Delete(Usermeta{}, "user_id = ? and meta_key = ?", userID, metaKey)
+	return
+}
@@ -80,3 +80,16 @@ func TestDeleteUsermeta(t *testing.T) {
 	}
 
 }
+
+func TestUsermetaValue(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("", model.GetUsermetaValue(1, "testUsermetaValue"))
+	assert.NoError(model.SetUsermetaValue(1, "testUsermetaValue", "a").Error)
+	assert.NoError(model.SetUsermetaValue(1, "testUsermetaValue", "b").Error)
+	assert.Equal("b", model.GetUsermetaValue(1, "testUsermetaValue"))
+	assert.False(model.CompareAndSetUsermetaValue(1, "testUsermetaValue", "a", "c"), "stale values should not be overwritten")
+	assert.True(model.CompareAndSetUsermetaValue(1, "testUsermetaValue", "b", "c"))
+	assert.Equal("c", model.GetUsermetaValue(1, "testUsermetaValue"))
+	assert.NoError(model.DeleteUsermetaByKey(1, "testUsermetaValue").Error)
+	assert.Equal("", model.GetUsermetaValue(1, "testUsermetaValue"))
+}
@@ -0,0 +1,249 @@
+package model
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+
+	"github.com/insionng/zenpress/helper"
+)
+
+const (
+	// VoteUp 赞成票
+	VoteUp = 1
+	// VoteDown 反对票
+	VoteDown = -1
+
+	// VoteUpsMetaKey 赞成票数保存在Postmeta或Commentmeta中的键名
+	VoteUpsMetaKey = "_vote_ups"
+	// VoteDownsMetaKey 反对票数保存在Postmeta或Commentmeta中的键名
+	VoteDownsMetaKey = "_vote_downs"
+	// VoterMetaKeyPrefix 投票者的投票保存在Postmeta或Commentmeta中的键名前缀，后接投票者标识，值为1或-1
+	VoterMetaKeyPrefix = "_voter_"
+	// PostHotnessMetaKey 文章热度保存在Postmeta中的键名，投票时按helper.Hotness重新计算
+	PostHotnessMetaKey = "_hotness"
+
+	// CommentOrderOption 评论的默认排序方式
+	CommentOrderOption = "comment_order"
+	// CommentOrderBest 按Wilson置信区间下限排序，好评多且票数多的评论靠前
+	CommentOrderBest = "best"
+	// CommentOrderControversial 按争议度排序，赞成与反对票数接近且票数多的评论靠前
+	CommentOrderControversial = "controversial"
+	// CommentOrderNewest 最新的评论靠前
+	CommentOrderNewest = "newest"
+	// CommentOrderOldest 最早的评论靠前
+	CommentOrderOldest = "oldest"
+
+	// HomePostOrderOption 首页文章的排序方式
+	HomePostOrderOption = "home_post_order"
+	// HomePostOrderDate 首页文章按发布时间排序
+	HomePostOrderDate = "date"
+	// HomePostOrderHot 首页文章按热度排序
+	HomePostOrderHot = "hot"
+)
+
+var (
+	// ErrInvalidVote 投票值不是1、-1或0
+	ErrInvalidVote = errors.New("无效的投票")
+	// ErrInvalidVoter 投票者标识为空
+	ErrInvalidVoter = errors.New("无法识别投票者")
+
+	votesLock sync.Mutex
+)
+
+// Votes 投票统计
+type Votes struct {
+	Ups         int64
+	Downs       int64
+	Score       int64
+	Confidence  float64
+	Controversy float64
+}
+
+// NewVotes 由赞成及反对票数计算得分、置信度及争议度
+func NewVotes(ups, downs int64) Votes {
+	return Votes{
+		Ups:         ups,
+		Downs:       downs,
+		Score:       helper.Score(ups, downs),
+		Confidence:  helper.Confidence(ups, downs),
+		Controversy: helper.Controversy(ups, downs),
+	}
+}
+
+// Voter 获得投票者标识，已登录用户按用户ID，访客按IP
+func Voter(userID uint64, ip string) string {
+	if userID > 0 {
+		return "u" + strconv.FormatUint(userID, 10)
+	}
+	if len(ip) > 0 {
+		return "ip:" + ip
+	}
+	return ""
+}
+
+// IsValidCommentOrder 检查评论排序方式是否有效
+func IsValidCommentOrder(order string) bool {
+	switch order {
+	case CommentOrderBest, CommentOrderControversial, CommentOrderNewest, CommentOrderOldest:
+		return true
+	}
+	return false
+}
+
+// GetCommentOrder 获得评论的默认排序方式，未设置时最早的评论靠前
+func GetCommentOrder() string {
+	if order := GetOptionValue(CommentOrderOption); IsValidCommentOrder(order) {
+		return order
+	}
+	return CommentOrderOldest
+}
+
+// GetHomePostOrder 获得首页文章的排序方式
+func GetHomePostOrder() string {
+	if GetOptionValue(HomePostOrderOption) == HomePostOrderHot {
+		return HomePostOrderHot
+	}
+	return HomePostOrderDate
+}
+
+// VotePost 为文章投票，vote为1、-1，为0时撤销投票，同一投票者只保留最后一次投票
+func VotePost(postID uint64, voter string, vote int) (Votes, error) {
+	if err := checkVote(voter, vote); err != nil {
+		return Votes{}, err
+	}
+	db, post := GetPost(postID)
+	if db.Error != nil {
+		return Votes{}, db.Error
+	}
+
+	votesLock.Lock()
+	defer votesLock.Unlock()
+	if vote == 0 {
+		db = DeletePostmetaByKey(postID, VoterMetaKeyPrefix+voter)
+	} else {
+		db = SetPostmetaValue(postID, VoterMetaKeyPrefix+voter, strconv.Itoa(vote))
+	}
+	if db.Error != nil {
+		return Votes{}, db.Error
+	}
+
+	ups, downs := countVotes(&Postmeta{}, "post_id", postID)
+	votes := NewVotes(ups, downs)
+	hotness := helper.Hotness(ups, downs, post.PostDateGmt.Unix())
+	for key, value := range map[string]string{
+		VoteUpsMetaKey:     strconv.FormatInt(ups, 10),
+		VoteDownsMetaKey:   strconv.FormatInt(downs, 10),
+		PostHotnessMetaKey: strconv.FormatFloat(hotness, 'f', 7, 64),
+	} {
+		if err := SetPostmetaValue(postID, key, value).Error; err != nil {
+			return votes, err
+		}
+	}
+	return votes, nil
+}
+
+// GetPostVotes 获得文章的投票统计
+func GetPostVotes(postID uint64) Votes {
+	ups, _ := strconv.ParseInt(GetPostmetaValue(postID, VoteUpsMetaKey), 10, 64)
+	downs, _ := strconv.ParseInt(GetPostmetaValue(postID, VoteDownsMetaKey), 10, 64)
+	return NewVotes(ups, downs)
+}
+
+// GetPostVote 获得投票者对文章的投票，未投票时为0
+func GetPostVote(postID uint64, voter string) int {
+	if len(voter) == 0 {
+		return 0
+	}
+	vote, _ := strconv.Atoi(GetPostmetaValue(postID, VoterMetaKeyPrefix+voter))
+	return vote
+}
+
+// VoteComment 为评论投票，vote为1、-1，为0时撤销投票，评论的CommentKarma同步为赞成票数减反对票数
+func VoteComment(commentID uint64, voter string, vote int) (Votes, error) {
+	if err := checkVote(voter, vote); err != nil {
+		return Votes{}, err
+	}
+	db, _ := GetComment(commentID)
+	if db.Error != nil {
+		return Votes{}, db.Error
+	}
+
+	votesLock.Lock()
+	defer votesLock.Unlock()
+	if vote == 0 {
+		db = DeleteCommentmetaByKey(commentID, VoterMetaKeyPrefix+voter)
+	} else {
+		db = SetCommentmetaValue(commentID, VoterMetaKeyPrefix+voter, strconv.Itoa(vote))
+	}
+	if db.Error != nil {
+		return Votes{}, db.Error
+	}
+
+	ups, downs := countVotes(&Commentmeta{}, "comment_id", commentID)
+	votes := NewVotes(ups, downs)
+	if err := SetCommentmetaValue(commentID, VoteUpsMetaKey, strconv.FormatInt(ups, 10)).Error; err != nil {
+		return votes, err
+	}
+	if err := SetCommentmetaValue(commentID, VoteDownsMetaKey, strconv.FormatInt(downs, 10)).Error; err != nil {
+		return votes, err
+	}
+	return votes, Database.Model(&Comment{}).Where("id = ?", commentID).UpdateColumn("comment_karma", votes.Score).Error
+}
+
+// GetCommentVotes 获得评论的投票统计
+func GetCommentVotes(commentID uint64) Votes {
+	ups, _ := strconv.ParseInt(GetCommentmetaValue(commentID, VoteUpsMetaKey), 10, 64)
+	downs, _ := strconv.ParseInt(GetCommentmetaValue(commentID, VoteDownsMetaKey), 10, 64)
+	return NewVotes(ups, downs)
+}
+
+// GetCommentVote 获得投票者对评论的投票，未投票时为0
+func GetCommentVote(commentID uint64, voter string) int {
+	if len(voter) == 0 {
+		return 0
+	}
+	vote, _ := strconv.Atoi(GetCommentmetaValue(commentID, VoterMetaKeyPrefix+voter))
+	return vote
+}
+
+// PageHomePosts 分页获得首页的已发布文章，按设置的排序方式排序
+func PageHomePosts(pageNo, pageSize int) helper.Page {
+	if GetHomePostOrder() != HomePostOrderHot {
+		return PagePosts("post", PostStatusPublish, pageNo, pageSize)
+	}
+
+	var posts []Post
+	var count int
+	Database.Model(&Post{}).Where("post_type = ? and post_status = ?", "post", PostStatusPublish).Count(&count)
+	if pageNo < 1 {
+		pageNo = 1
+	}
+	//没有投票的文章热度为0，与helper.Hotness(0, 0, t)一致
+	Database.Table(Database.NewScope(&Post{}).TableName()+" p").
+		Select("p.*").
+		Joins("left join "+Database.NewScope(&Postmeta{}).TableName()+" pm on pm.post_id = p.id and pm.meta_key = ?", PostHotnessMetaKey).
+		Where("p.post_type = ? and p.post_status = ?", "post", PostStatusPublish).
+		Order("coalesce(cast(pm.meta_value as decimal(20,7)), 0) desc, p.post_date desc").
+		Offset((pageNo - 1) * pageSize).
+		Limit(pageSize).
+		Find(&posts)
+	return helper.PageUtil(count, pageNo, pageSize, posts)
+}
+
+func checkVote(voter string, vote int) error {
+	if len(voter) == 0 {
+		return ErrInvalidVoter
+	}
+	if vote != VoteUp && vote != VoteDown && vote != 0 {
+		return ErrInvalidVote
+	}
+	return nil
+}
+
+// countVotes 统计meta中投票者的赞成及反对票数
+func countVotes(meta interface{}, column string, id uint64) (ups, downs int64) {
+	Database.Model(meta).Where(column+" = ? and meta_key like ? and meta_value = ?", id, VoterMetaKeyPrefix+"%", strconv.Itoa(VoteUp)).Count(&ups)
+	Database.Model(meta).Where(column+" = ? and meta_key like ? and meta_value = ?", id, VoterMetaKeyPrefix+"%", strconv.Itoa(VoteDown)).Count(&downs)
+	return
+}
@@ -0,0 +1,105 @@
+package model_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/insionng/zenpress/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVotePost(t *testing.T) {
+	assert := assert.New(t)
+	older := &model.Post{PostTitle: "较早的文章", PostStatus: model.PostStatusPublish, PostDate: time.Now().Add(-48 * time.Hour)}
+	newer := &model.Post{PostTitle: "较新的文章", PostStatus: model.PostStatusPublish}
+	if !assert.NoError(model.InsertPost(older)) || !assert.NoError(model.InsertPost(newer)) {
+		return
+	}
+	defer model.RemovePost(older.ID)
+	defer model.RemovePost(newer.ID)
+
+	_, err := model.VotePost(older.ID, "", model.VoteUp)
+	assert.Equal(model.ErrInvalidVoter, err)
+	_, err = model.VotePost(older.ID, "u1", 2)
+	assert.Equal(model.ErrInvalidVote, err)
+
+	model.VotePost(older.ID, "u1", model.VoteUp)
+	votes, err := model.VotePost(older.ID, "u1", model.VoteUp)
+	assert.NoError(err)
+	assert.Equal(int64(1), votes.Ups, "the same voter should only be counted once")
+	model.VotePost(older.ID, "ip:127.0.0.1", model.VoteUp)
+	votes, _ = model.VotePost(older.ID, "u2", model.VoteDown)
+	assert.Equal(model.NewVotes(2, 1), votes)
+	assert.Equal(votes, model.GetPostVotes(older.ID))
+	assert.Equal(model.VoteDown, model.GetPostVote(older.ID, "u2"))
+
+	votes, _ = model.VotePost(older.ID, "u2", 0)
+	assert.Equal(int64(0), votes.Downs)
+	assert.Equal(0, model.GetPostVote(older.ID, "u2"))
+
+	defer model.DeleteOption(model.HomePostOrderOption)
+	model.SetOptionValue(model.HomePostOrderOption, model.HomePostOrderHot)
+	assert.Equal(model.HomePostOrderHot, model.GetHomePostOrder())
+	if posts, okay := model.PageHomePosts(1, 100).List.([]model.Post); assert.True(okay) && assert.NotEmpty(posts) {
+		assert.Equal(older.ID, posts[0].ID, "voted posts should rank above newer posts without votes")
+	}
+}
+
+func TestSortedThreadedComments(t *testing.T) {
+	assert := assert.New(t)
+	defer func(file string) { model.CommentSpamBayesFile = file }(model.CommentSpamBayesFile)
+	model.CommentSpamBayesFile = filepath.Join(os.TempDir(), fmt.Sprintf("zenpress-bayes-%d.gob", time.Now().UnixNano()))
+	defer os.Remove(model.CommentSpamBayesFile)
+
+	post := &model.Post{PostTitle: "测试评论排序", PostStatus: model.PostStatusPublish}
+	if !assert.NoError(model.InsertPost(post)) {
+		return
+	}
+	defer model.RemovePost(post.ID)
+
+	var ids []uint64
+	for i := 0; i < 3; i++ {
+		c := &model.Comment{
+			CommentPostID:      int64(post.ID),
+			CommentAuthor:      "投票测试",
+			CommentAuthorEmail: "vote@example.com",
+			CommentContent:     fmt.Sprintf("评论%d", i),
+		}
+		if !assert.NoError(model.InsertComment(c, nil)) {
+			return
+		}
+		assert.NoError(model.SetCommentStatus(c.ID, model.CommentApprove))
+		defer model.RemoveComment(c.ID)
+		ids = append(ids, c.ID)
+	}
+
+	//评论0：1赞成；评论1：5赞成1反对；评论2：3赞成3反对
+	model.VoteComment(ids[0], "u1", model.VoteUp)
+	for i := 1; i <= 5; i++ {
+		model.VoteComment(ids[1], fmt.Sprintf("u%d", i), model.VoteUp)
+	}
+	model.VoteComment(ids[1], "u6", model.VoteDown)
+	for i := 1; i <= 3; i++ {
+		model.VoteComment(ids[2], fmt.Sprintf("u%d", i), model.VoteUp)
+		model.VoteComment(ids[2], fmt.Sprintf("u%d", i+3), model.VoteDown)
+	}
+	_, c := model.GetComment(ids[1])
+	assert.Equal(4, c.CommentKarma)
+	assert.Equal(model.NewVotes(3, 3), model.GetCommentVotes(ids[2]))
+
+	order := func(threaded []model.ThreadedComment) (result []uint64) {
+		for _, c := range threaded {
+			result = append(result, c.ID)
+		}
+		return
+	}
+	assert.Equal(ids, order(model.GetSortedThreadedComments(post.ID, model.CommentOrderOldest)))
+	assert.Equal([]uint64{ids[2], ids[1], ids[0]}, order(model.GetSortedThreadedComments(post.ID, model.CommentOrderNewest)))
+	assert.Equal([]uint64{ids[1], ids[2], ids[0]}, order(model.GetSortedThreadedComments(post.ID, model.CommentOrderBest)))
+	assert.Equal(ids[2], model.GetSortedThreadedComments(post.ID, model.CommentOrderControversial)[0].ID)
+	assert.Equal(ids, order(model.GetThreadedComments(post.ID)), "comments should default to the oldest first")
+}
@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// SessionUserKey 会话中保存已登录用户ID的键名
+	SessionUserKey = "SignedUserID"
+	// SessionCSRFKey 会话中保存CSRF令牌的键名
+	SessionCSRFKey = "CSRFToken"
+	// CSRFField 表单中提交CSRF令牌的字段名
+	CSRFField = "_csrf"
+	// CSRFHeader Ajax请求中提交CSRF令牌的请求头
+	CSRFHeader = "X-CSRF-Token"
+	// SessionSigninKey 会话中保存登录令牌的键名
+	SessionSigninKey = "SigninToken"
+	// SessionEpochKey 会话中保存登录时用户会话版本的键名
+	SessionEpochKey = "SessionEpoch"
+	// SigninCookie 登录时签发的令牌Cookie名，须与会话中的登录令牌一致，会话ID被预先设定时也无法冒用登录状态
+	SigninCookie = "zenpress_signin"
+	// RememberCookie 保存“记住登录”令牌的Cookie名
+	RememberCookie = "zenpress_remember"
+	// RememberDuration “记住登录”的有效期
+	RememberDuration = 30 * 24 * time.Hour
+	// SigninPath 登录页面的路径
+	SigninPath = "/signin"
+	// TwoFactorSigninPath 登录时输入两步验证码的页面路径
+	TwoFactorSigninPath = "/signin/two-factor"
+	// SessionTwoFactorKey 会话中保存已通过密码验证、等待两步验证的登录状态的键名
+	SessionTwoFactorKey = "TwoFactorPending"
+	// TwoFactorPendingDuration 通过密码验证后须在此时间内完成两步验证
+	TwoFactorPendingDuration = 5 * time.Minute
+)
+
+// NewToken 生成URL安全的随机令牌
+func NewToken() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// VerifyToken 以恒定时间比较令牌，任一方为空时不通过
+func VerifyToken(expected, actual string) bool {
+	if len(expected) == 0 || len(actual) == 0 {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(actual)) == 1
+}
+
+// RequestCSRFToken 获得请求中提交的CSRF令牌，先取表单字段再取请求头
+func RequestCSRFToken(r *http.Request) string {
+	if token := r.FormValue(CSRFField); len(token) > 0 {
+		return token
+	}
+	return r.Header.Get(CSRFHeader)
+}
+
+// IsSafeMethod 判断请求方法是否不改变状态，此类请求无需校验CSRF令牌
+func IsSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// SafeRedirect 仅允许跳转到本站的路径，其它地址一律返回fallback，防止开放重定向
+func SafeRedirect(next, fallback string) string {
+	if len(next) == 0 || next[0] != '/' || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return fallback
+	}
+	if u, err := url.Parse(next); err != nil || u.IsAbs() || len(u.Host) > 0 {
+		return fallback
+	}
+	return next
+}
+
+// SigninURL 获得登录页面的地址，登录后返回next
+func SigninURL(next string) string {
+	return NextURL(SigninPath, next)
+}
+
+// NextURL 获得附带next参数的地址，next不是本站路径时忽略
+func NextURL(path, next string) string {
+	if next = SafeRedirect(next, ""); len(next) == 0 {
+		return path
+	}
+	return path + "?next=" + url.QueryEscape(next)
+}
+
+// NewTwoFactorPending 生成等待两步验证的登录状态，保存在会话中
+func NewTwoFactorPending(userID uint64, remember bool) string {
+	return fmt.Sprintf("%d:%d:%t", userID, time.Now().Unix(), remember)
+}
+
+// ParseTwoFactorPending 解析等待两步验证的登录状态，无效或超过TwoFactorPendingDuration时返回0
+func ParseTwoFactorPending(value string) (uint64, bool) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		return 0, false
+	}
+	userID, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	since, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || time.Since(time.Unix(since, 0)) > TwoFactorPendingDuration {
+		return 0, false
+	}
+	return userID, parts[2] == "true"
+}
+
+// IsSecure 判断请求是否经由HTTPS，包括反向代理转发的请求
+func IsSecure(r *http.Request) bool {
+	return r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
+
+// SetCookie 设置仅供HTTP访问的Cookie，maxAge为0时为会话Cookie，小于0时删除
+func SetCookie(w http.ResponseWriter, r *http.Request, name, value string, maxAge time.Duration) {
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   IsSecure(r),
+		SameSite: http.SameSiteLaxMode,
+	}
+	if maxAge > 0 {
+		cookie.MaxAge = int(maxAge / time.Second)
+		cookie.Expires = time.Now().Add(maxAge)
+	} else if maxAge < 0 {
+		cookie.MaxAge = -1
+		cookie.Expires = time.Unix(1, 0)
+	}
+	http.SetCookie(w, cookie)
+}
+
+// GetCookie 获得Cookie的值，不存在时返回空字符串
+func GetCookie(r *http.Request, name string) string {
+	if cookie, err := r.Cookie(name); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+// DeleteCookie 删除Cookie
+func DeleteCookie(w http.ResponseWriter, r *http.Request, name string) {
+	SetCookie(w, r, name, "", -1)
+}
@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSafeRedirect(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("/root/article?id=1", SafeRedirect("/root/article?id=1", "/"))
+	assert.Equal("/", SafeRedirect("", "/"))
+	assert.Equal("/", SafeRedirect("http://evil.example.com/", "/"))
+	assert.Equal("/", SafeRedirect("//evil.example.com/", "/"))
+	assert.Equal("/", SafeRedirect("/\\evil.example.com/", "/"))
+	assert.Equal("/", SafeRedirect("javascript:alert(1)", "/"))
+
+	assert.Equal("/signin", SigninURL(""))
+	assert.Equal("/signin", SigninURL("https://evil.example.com/"))
+	assert.Equal("/signin?next=%2Froot%2F%3Fa%3D1", SigninURL("/root/?a=1"))
+	assert.Equal("/signin/two-factor?next=%2Froot%2F", NextURL(TwoFactorSigninPath, "/root/"))
+}
+
+func TestTwoFactorPending(t *testing.T) {
+	assert := assert.New(t)
+	userID, remember := ParseTwoFactorPending(NewTwoFactorPending(42, true))
+	assert.Equal(uint64(42), userID)
+	assert.True(remember)
+	userID, remember = ParseTwoFactorPending(NewTwoFactorPending(7, false))
+	assert.Equal(uint64(7), userID)
+	assert.False(remember)
+
+	expired := fmt.Sprintf("42:%d:true", time.Now().Add(-TwoFactorPendingDuration-time.Second).Unix())
+	userID, _ = ParseTwoFactorPending(expired)
+	assert.Equal(uint64(0), userID, "expired states should be rejected")
+	userID, _ = ParseTwoFactorPending("<nil>")
+	assert.Equal(uint64(0), userID)
+}
+
+func TestCSRFToken(t *testing.T) {
+	assert := assert.New(t)
+	token := NewToken()
+	assert.Len(token, 43)
+	assert.NotEqual(token, NewToken())
+	assert.True(VerifyToken(token, token))
+	assert.False(VerifyToken(token, token[1:]))
+	assert.False(VerifyToken("", ""))
+
+	req := httptest.NewRequest(http.MethodPost, "/root/", strings.NewReader(url.Values{CSRFField: {token}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	assert.Equal(token, RequestCSRFToken(req))
+	req = httptest.NewRequest(http.MethodPost, "/root/", nil)
+	req.Header.Set(CSRFHeader, token)
+	assert.Equal(token, RequestCSRFToken(req))
+
+	assert.True(IsSafeMethod(http.MethodGet))
+	assert.False(IsSafeMethod(http.MethodPost))
+}
+
+func TestCookie(t *testing.T) {
+	assert := assert.New(t)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	SetCookie(rec, req, RememberCookie, "value", time.Hour)
+	cookie := rec.Result().Cookies()[0]
+	assert.Equal("value", cookie.Value)
+	assert.Equal(3600, cookie.MaxAge)
+	assert.True(cookie.HttpOnly)
+	assert.False(cookie.Secure)
+
+	req.Header.Set("X-Forwarded-Proto", "https")
+	rec = httptest.NewRecorder()
+	DeleteCookie(rec, req, RememberCookie)
+	cookie = rec.Result().Cookies()[0]
+	assert.True(cookie.MaxAge < 0)
+	assert.True(cookie.Secure)
+
+	req.AddCookie(&http.Cookie{Name: RememberCookie, Value: "stored"})
+	assert.Equal("stored", GetCookie(req, RememberCookie))
+	assert.Equal("", GetCookie(req, "missing"))
+}
+
+func TestThrottle(t *testing.T) {
+	assert := assert.New(t)
+	th := NewThrottle(3, time.Minute)
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		blocked, _ := th.Blocked("1.2.3.4", now)
+		assert.False(blocked)
+		th.Fail("1.2.3.4", now.Add(time.Duration(i)*time.Second))
+	}
+	blocked, wait := th.Blocked("1.2.3.4", now.Add(3*time.Second))
+	assert.True(blocked)
+	assert.Equal(57*time.Second, wait)
+	blocked, _ = th.Blocked("5.6.7.8", now)
+	assert.False(blocked)
+
+	blocked, _ = th.Blocked("1.2.3.4", now.Add(time.Minute+time.Second))
+	assert.False(blocked, "failures outside the window should expire")
+
+	th.Fail("1.2.3.4", now)
+	th.Reset("1.2.3.4")
+	blocked, _ = th.Blocked("1.2.3.4", now)
+	assert.False(blocked)
+}
+
+func TestThrottlePrune(t *testing.T) {
+	assert := assert.New(t)
+	th := NewThrottle(3, time.Minute)
+	now := time.Now()
+	for i := 0; i < 100; i++ {
+		th.Fail(fmt.Sprintf("10.0.0.%d", i), now)
+	}
+	assert.Equal(100, th.Len())
+
+	th.Fail("10.0.1.1", now.Add(2*time.Minute))
+	assert.Equal(1, th.Len(), "expired keys should be pruned")
+}
+
+func TestBearerToken(t *testing.T) {
+	assert := assert.New(t)
+	req := httptest.NewRequest(http.MethodGet, "/root/", nil)
+	assert.Equal("", BearerToken(req))
+	req.Header.Set("Authorization", "bearer  zp_abc ")
+	assert.Equal("zp_abc", BearerToken(req))
+	req.Header.Set("Authorization", "Basic dXNlcjpwYXNz")
+	assert.Equal("", BearerToken(req))
+
+	assert.Equal([]string{ScopeRead}, RequiredScopes(http.MethodGet, "/"))
+	assert.Equal([]string{ScopeWrite}, RequiredScopes(http.MethodPost, "/comment"))
+	assert.Equal([]string{ScopeRead, ScopeAdmin}, RequiredScopes(http.MethodGet, "/root"))
+	assert.Equal([]string{ScopeWrite, ScopeAdmin}, RequiredScopes(http.MethodPost, "/root/article"))
+	assert.Equal([]string{ScopeRead}, RequiredScopes(http.MethodGet, "/rootless"))
+	assert.True(SessionOnly("/root/user/profile"))
+	assert.True(SessionOnly("/root/user"))
+	assert.False(SessionOnly("/root/users"))
+	assert.False(SessionOnly("/root/article"))
+
+	assert.True(HasScope([]string{ScopeWrite}, ScopeRead), "write should include read")
+	assert.False(HasScope([]string{ScopeRead}, ScopeWrite))
+	assert.False(HasScope([]string{ScopeRead, ScopeWrite}, ScopeAdmin))
+	assert.False(HasScope(nil, ScopeRead))
+}
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+)
+
+// 访问令牌的权限范围
+const (
+	// ScopeRead 读取，允许GET、HEAD、OPTIONS请求
+	ScopeRead = "read"
+	// ScopeWrite 写入，允许改变状态的请求，同时包含读取
+	ScopeWrite = "write"
+	// ScopeAdmin 管理后台，访问/root下的地址时须具有
+	ScopeAdmin = "admin"
+)
+
+const (
+	// ContextTokenUserKey 以访问令牌认证的请求中保存用户的键名
+	ContextTokenUserKey = "TokenUser"
+	// ContextAccessTokenKey 以访问令牌认证的请求中保存令牌记录的键名
+	ContextAccessTokenKey = "AccessToken"
+	// AdminPathPrefix 管理后台的路径前缀
+	AdminPathPrefix = "/root"
+	// AccountPathPrefix 个人资料、两步验证等账号安全设置的路径前缀
+	AccountPathPrefix = "/root/user"
+)
+
+// Scopes 全部可用的权限范围
+var Scopes = []string{ScopeRead, ScopeWrite, ScopeAdmin}
+
+// BearerToken 获得Authorization请求头中的Bearer令牌，不存在时返回空字符串
+func BearerToken(r *http.Request) string {
+	header := strings.TrimSpace(r.Header.Get("Authorization"))
+	if len(header) < 7 || !strings.EqualFold(header[:7], "Bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(header[7:])
+}
+
+// RequiredScopes 获得请求须具有的权限范围：按请求方法须有读取或写入，管理后台的地址另须有admin
+func RequiredScopes(method, path string) []string {
+	scopes := []string{ScopeWrite}
+	if IsSafeMethod(method) {
+		scopes[0] = ScopeRead
+	}
+	if path == AdminPathPrefix || strings.HasPrefix(path, AdminPathPrefix+"/") {
+		scopes = append(scopes, ScopeAdmin)
+	}
+	return scopes
+}
+
+// SessionOnly 判断路径是否只能以会话访问：账号安全设置不接受访问令牌，以免令牌签发新令牌或关闭两步验证
+func SessionOnly(path string) bool {
+	return path == AccountPathPrefix || strings.HasPrefix(path, AccountPathPrefix+"/")
+}
+
+// HasScope 判断权限范围中是否包含scope，写入包含读取
+func HasScope(granted []string, scope string) bool {
+	for _, s := range granted {
+		if s == scope || (s == ScopeWrite && scope == ScopeRead) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// Throttle 在时间窗口内统计失败次数，超过上限后暂时拒绝，用于限制登录尝试
+type Throttle struct {
+	Limit  int
+	Window time.Duration
+
+	mu       sync.Mutex
+	failures map[string][]time.Time
+	pruned   time.Time
+}
+
+// NewThrottle 创建在window时间内最多允许limit次失败的限制器
+func NewThrottle(limit int, window time.Duration) *Throttle {
+	return &Throttle{Limit: limit, Window: window, failures: make(map[string][]time.Time)}
+}
+
+// recent 清除窗口外的记录并返回窗口内的失败时间，调用者需持有锁
+func (t *Throttle) recent(key string, now time.Time) []time.Time {
+	list := t.failures[key]
+	i := 0
+	for i < len(list) && !list[i].After(now.Add(-t.Window)) {
+		i++
+	}
+	if list = list[i:]; len(list) == 0 {
+		delete(t.failures, key)
+	} else {
+		t.failures[key] = list
+	}
+	return list
+}
+
+// prune 每隔一个时间窗口清除所有已过期的key，避免不再出现的key一直占用内存，调用者需持有锁
+func (t *Throttle) prune(now time.Time) {
+	if now.Sub(t.pruned) < t.Window {
+		return
+	}
+	t.pruned = now
+	for key := range t.failures {
+		t.recent(key, now)
+	}
+}
+
+// Len 返回仍在记录失败次数的key数量
+func (t *Throttle) Len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.failures)
+}
+
+// Blocked 判断key是否已被限制，被限制时同时返回需等待的时长
+func (t *Throttle) Blocked(key string, now time.Time) (bool, time.Duration) {
+	if len(key) == 0 || t.Limit <= 0 {
+		return false, 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	list := t.recent(key, now)
+	if len(list) < t.Limit {
+		return false, 0
+	}
+	return true, list[len(list)-t.Limit].Add(t.Window).Sub(now)
+}
+
+// Fail 记录key的一次失败
+func (t *Throttle) Fail(key string, now time.Time) {
+	if len(key) == 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.prune(now)
+	t.failures[key] = append(t.recent(key, now), now)
+}
+
+// Reset 清除key的失败记录
+func (t *Throttle) Reset(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.failures, key)
+}
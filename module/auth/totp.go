@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// 基于时间的一次性密码（TOTP，RFC 6238），与常见的身份验证器应用兼容
+const (
+	// TOTPDigits 验证码的位数
+	TOTPDigits = 6
+	// TOTPPeriod 验证码的有效时长（秒）
+	TOTPPeriod = 30
+	// TOTPSkew 校验时前后各容许的时间步数，以应对时钟误差
+	TOTPSkew = 1
+	// TOTPSecretSize 密钥的字节数
+	TOTPSecretSize = 20
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewTOTPSecret 生成Base32编码的随机密钥
+func NewTOTPSecret() string {
+	b := make([]byte, TOTPSecretSize)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return totpEncoding.EncodeToString(b)
+}
+
+// decodeTOTPSecret 解码密钥，忽略空格、大小写及末尾的填充
+func decodeTOTPSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.Replace(secret, " ", "", -1))
+	return totpEncoding.DecodeString(strings.TrimRight(secret, "="))
+}
+
+// hotp 按RFC 4226计算指定计数的验证码
+func hotp(key []byte, counter uint64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", TOTPDigits, value%1000000)
+}
+
+// TOTPStep 获得指定时间所在的时间步
+func TOTPStep(t time.Time) uint64 {
+	return uint64(t.Unix()) / TOTPPeriod
+}
+
+// TOTPCode 获得指定时间的验证码
+func TOTPCode(secret string, t time.Time) (string, error) {
+	key, err := decodeTOTPSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	return hotp(key, TOTPStep(t)), nil
+}
+
+// VerifyTOTP 校验验证码，通过时返回其所在的时间步，调用方据此拒绝重复使用的验证码
+func VerifyTOTP(secret, code string, t time.Time) (bool, uint64) {
+	code = strings.TrimSpace(code)
+	if len(code) != TOTPDigits {
+		return false, 0
+	}
+	key, err := decodeTOTPSecret(secret)
+	if err != nil || len(key) == 0 {
+		return false, 0
+	}
+	step := TOTPStep(t)
+	for i := -TOTPSkew; i <= TOTPSkew; i++ {
+		counter := step + uint64(i)
+		if VerifyToken(hotp(key, counter), code) {
+			return true, counter
+		}
+	}
+	return false, 0
+}
+
+// TOTPURI 获得供身份验证器应用扫描的otpauth地址
+func TOTPURI(issuer, account, secret string) string {
+	label := url.PathEscape(account)
+	if len(issuer) > 0 {
+		label = url.PathEscape(issuer) + ":" + label
+	}
+	query := url.Values{}
+	query.Set("secret", secret)
+	if len(issuer) > 0 {
+		query.Set("issuer", issuer)
+	}
+	query.Set("digits", fmt.Sprint(TOTPDigits))
+	query.Set("period", fmt.Sprint(TOTPPeriod))
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+// QRCodeDataURI 生成内容的二维码，返回可直接用于img标签的data地址
+func QRCodeDataURI(content string, size int) (string, error) {
+	png, err := qrcode.Encode(content, qrcode.Medium, size)
+	if err != nil {
+		return "", err
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(png), nil
+}
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTOTPCode(t *testing.T) {
+	assert := assert.New(t)
+	// RFC 6238 附录B的SHA1测试数据，取后6位
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	for unix, code := range map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1111111111: "050471",
+		1234567890: "005924",
+		2000000000: "279037",
+	} {
+		got, err := TOTPCode(secret, time.Unix(unix, 0))
+		assert.NoError(err)
+		assert.Equal(code, got, "t=%d", unix)
+	}
+	got, _ := TOTPCode(strings.ToLower(strings.TrimRight(secret, "=")), time.Unix(59, 0))
+	assert.Equal("287082", got, "secrets should be accepted without padding in any case")
+	_, err := TOTPCode("not base32!", time.Now())
+	assert.Error(err)
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	assert := assert.New(t)
+	secret := NewTOTPSecret()
+	assert.Len(secret, 32)
+	assert.NotEqual(secret, NewTOTPSecret())
+
+	now := time.Unix(1500000000, 0)
+	code, _ := TOTPCode(secret, now)
+	ok, step := VerifyTOTP(secret, code, now)
+	assert.True(ok)
+	assert.Equal(TOTPStep(now), step)
+	ok, step = VerifyTOTP(secret, " "+code+" ", now.Add(TOTPPeriod*time.Second))
+	assert.True(ok, "codes from the previous step should be accepted")
+	assert.Equal(TOTPStep(now), step)
+	ok, _ = VerifyTOTP(secret, code, now.Add(2*TOTPPeriod*time.Second))
+	assert.False(ok)
+	ok, _ = VerifyTOTP(secret, code[1:], now)
+	assert.False(ok)
+	ok, _ = VerifyTOTP("", "", now)
+	assert.False(ok)
+}
+
+func TestTOTPURI(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("otpauth://totp/Zen%20Press:admin@example.com?digits=6&issuer=Zen+Press&period=30&secret=ABC",
+		TOTPURI("Zen Press", "admin@example.com", "ABC"))
+	assert.Equal("otpauth://totp/admin?digits=6&period=30&secret=ABC", TOTPURI("", "admin", "ABC"))
+
+	uri, err := QRCodeDataURI(TOTPURI("Zen Press", "admin", "ABC"), 128)
+	assert.NoError(err)
+	assert.True(strings.HasPrefix(uri, "data:image/png;base64,"))
+}
@@ -3,7 +3,9 @@ package core
 import (
 	"fmt"
 	"io/ioutil"
+	"log"
 	"os"
+	"time"
 
 	gomakross "github.com/insionng/makross"
 	"github.com/insionng/makross/cache"
@@ -13,8 +15,11 @@ import (
 	gopongor "github.com/insionng/makross/pongor"
 	gosession "github.com/insionng/makross/session"
 	gostatic "github.com/insionng/makross/static"
+	goimager "github.com/insionng/zenpress/module/imager"
 	goswitchr "github.com/insionng/zenpress/module/switchr"
+	gotheme "github.com/insionng/zenpress/module/theme"
 
+	"github.com/insionng/zenpress/model"
 	"github.com/insionng/zenpress/module/qimport"
 	"qlang.io/cl/qlang"
 )
@@ -92,7 +97,7 @@ func Codes(theme string) []byte {
 	var themeApps, rootApps string
 
 	//读取前端逻辑代码
-	files := []string{"IndexHandler", "SingleHandler", "PageHandler", "CategoryHandler", "TagHandler", "TaxonomyHandler", "AuthorHandler", "AttachmentHandler", "DateHandler", "ArchiveHandler", "SearchHandler", "NotFoundHandler"}
+	files := []string{"IndexHandler", "SingleHandler", "PageHandler", "CategoryHandler", "TagHandler", "TaxonomyHandler", "AuthorHandler", "AttachmentHandler", "DateHandler", "ArchiveHandler", "SearchHandler", "CommentHandler", "VoteHandler", "SigninHandler", "SignupHandler", "PasswordHandler", "PostTypeHandler", "NotFoundHandler"}
 	for _, file := range files {
 		var b []byte
 		var e error
@@ -105,7 +110,7 @@ func Codes(theme string) []byte {
 	}
 
 	//读取后端逻辑代码
-	rootfiles := []string{"DashboardHandler", "ArticleHandler", "MediaHandler", "LinkHandler", "PageHandler", "CommentHandler", "ThemeHandler", "PluginHandler", "UserHandler", "ToolHandler", "OptionHandler", "NotFoundHandler"}
+	rootfiles := []string{"AuthHandler", "DashboardHandler", "ArticleHandler", "MediaHandler", "LinkHandler", "PageHandler", "CommentHandler", "ThemeHandler", "PluginHandler", "UserHandler", "ToolHandler", "OptionHandler", "MenuHandler", "PostTypeHandler", "TaxonomyHandler", "NotFoundHandler"}
 	for _, file := range rootfiles {
 		var b []byte
 		var e error
@@ -139,7 +144,7 @@ func FrontCodes(theme string) []byte {
 	var themeApps, rootApps string
 
 	//读取前端控制器逻辑代码
-	files := []string{"IndexHandler", "SingleHandler", "PageHandler", "CategoryHandler", "TagHandler", "TaxonomyHandler", "AuthorHandler", "AttachmentHandler", "DateHandler", "ArchiveHandler", "SearchHandler", "SigninHandler", "NotFoundHandler"}
+	files := []string{"IndexHandler", "SingleHandler", "PageHandler", "CategoryHandler", "TagHandler", "TaxonomyHandler", "AuthorHandler", "AttachmentHandler", "DateHandler", "ArchiveHandler", "SearchHandler", "CommentHandler", "VoteHandler", "SigninHandler", "SignupHandler", "PasswordHandler", "PostTypeHandler", "NotFoundHandler"}
 	for _, file := range files {
 		var b []byte
 		var e error
@@ -171,7 +176,7 @@ func BackCodes(theme string) []byte {
 	var themeApps, rootApps string
 
 	//读取后端逻辑代码
-	rootfiles := []string{"DashboardHandler", "ArticleHandler", "MediaHandler", "LinkHandler", "PageHandler", "CommentHandler", "ThemeHandler", "PluginHandler", "UserHandler", "ToolHandler", "OptionHandler", "NotFoundHandler"}
+	rootfiles := []string{"AuthHandler", "DashboardHandler", "ArticleHandler", "MediaHandler", "LinkHandler", "PageHandler", "CommentHandler", "ThemeHandler", "PluginHandler", "UserHandler", "ToolHandler", "OptionHandler", "MenuHandler", "PostTypeHandler", "TaxonomyHandler", "NotFoundHandler"}
 	for _, file := range rootfiles {
 		var b []byte
 		var e error
@@ -209,6 +214,13 @@ func GetAppByTheme(theme string, filter bool, reload bool) (*gomakross.Makross,
 	app.Use(gostatic.Static(fmt.Sprintf("content/theme/%s/public", theme)))
 	app.Use(gosession.Sessioner(gosession.Options{"file", `{"cookieName":"makrossSessionId","gcLifetime":3600,"providerConfig":"./content/storage/session"}`}))
 	app.Use(goswitchr.SwitchrWithConfig(goswitchr.SwitchrConfig{Theme: theme, Filter: filter, Reload: reload}))
+	app.Use(gotheme.Themer(theme))
+	if err := gotheme.RegisterImageSizes(theme); err != nil {
+		log.Println(err)
+	}
+	model.SetEmailRenderer(gotheme.EmailRenderer(theme))
+	model.StartMailQueue(time.Minute)
+	model.Init()
 	/*------------------------------------*/
 	app.Use(cache.Cacher())
 	/*------------------------------------*/
@@ -261,9 +273,11 @@ func GetAppByTheme(theme string, filter bool, reload bool) (*gomakross.Makross,
 
 	app, okay = m.(*gomakross.Makross)
 	if okay {
+		app.Get(goimager.Route, goimager.Imager)
 		app.Get("/*", file.Server(file.PathMap{
 			//"/":      fmt.Sprintf("content/theme/%s/public", theme),
-			"/root/": "/public/",
+			"/root/":   "/public/",
+			"/upload/": "/content/storage/upload/",
 		}))
 	}
 
@@ -13,6 +13,7 @@ var (
 	// DefaultPriority 默认优先级为0值
 	DefaultPriority int
 	callback        func([]byte) []byte
+	hooksMutex      sync.Mutex
 )
 
 // NewPriorityQueue New PriorityQueue
@@ -171,6 +172,50 @@ func DoFilterHook(key string, function func() []byte) []byte {
 	return nil
 }
 
+// ApplyFilterHook  以指定内容按优先级依次执行过滤钩子，不沿用上一次的过滤结果，执行后钩子仍保留，可多次触发
+func ApplyFilterHook(key string, value []byte) []byte {
+	for _, function := range filterHooks(key) {
+		value = function(value)
+	}
+	if FiltersMap == nil {
+		FiltersMap = new(sync.Map)
+	}
+	FiltersMap.Store(key, value)
+	return value
+}
+
+// filterHooks  按优先级获得过滤钩子的全部函数，取出后放回队列
+func filterHooks(key string) []func([]byte) []byte {
+	hooksMutex.Lock()
+	defer hooksMutex.Unlock()
+	if QueuesMap == nil {
+		return nil
+	}
+	value, okay := QueuesMap.Load(key)
+	if !okay {
+		return nil
+	}
+	pq, okay := value.(*prior.PriorityQueue)
+	if !okay {
+		return nil
+	}
+
+	var nodes []*prior.Node
+	for pq.Length() > 0 {
+		if node := pq.Pop(); node != nil {
+			nodes = append(nodes, node)
+		}
+	}
+	functions := make([]func([]byte) []byte, 0, len(nodes))
+	for _, node := range nodes {
+		pq.Push(node)
+		if function, okay := node.GetValue().(func([]byte) []byte); okay {
+			functions = append(functions, function)
+		}
+	}
+	return functions
+}
+
 // RegisterActivationHook Set the activation hook for a plugin.
 /* 启用插件
  * When a plugin is activated, the action 'activate_PLUGINNAME' hook is
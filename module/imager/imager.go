@@ -0,0 +1,31 @@
+package imager
+
+import (
+	"net/http"
+
+	"github.com/insionng/makross"
+	"github.com/insionng/zenpress/model"
+)
+
+// Route 图片处理的路由，如 /img/300x200/crop/ab/cd/photo.jpg
+const Route = "/img/<size>/<mode>/<file:.*>"
+
+// Imager 按请求的尺寸及模式处理上传的图片，处理结果缓存在 model.ImageCacheDir 下
+func Imager(c *makross.Context) error {
+	t, err := model.ParseImageTransform(c.Param("size").String(), c.Param("mode").String(), c.Param("file").String(), c.Request.URL.Query().Get("sig"))
+	switch err {
+	case nil:
+	case model.ErrImageTransformForbidden:
+		return makross.NewHTTPError(http.StatusForbidden, err.Error())
+	default:
+		return makross.NewHTTPError(http.StatusNotFound, err.Error())
+	}
+
+	file, err := model.MakeImageTransform(t)
+	if err != nil {
+		return err
+	}
+	c.Response.Header().Set("Cache-Control", "public, max-age=2592000")
+	http.ServeFile(c.Response, c.Request, file)
+	return nil
+}
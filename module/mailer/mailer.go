@@ -0,0 +1,336 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNoRecipients 邮件没有收件人
+	ErrNoRecipients = errors.New("邮件没有收件人")
+	// ErrNoSender 邮件没有发件人
+	ErrNoSender = errors.New("邮件没有发件人")
+
+	htmlBreakRegexp = regexp.MustCompile(`(?i)<br\s*/?>|</p>|</div>|</li>|</h[1-6]>|</tr>|</blockquote>`)
+	htmlTagRegexp   = regexp.MustCompile(`(?s)<style.*?</style>|<script.*?</script>|<[^>]*>`)
+	blankRegexp     = regexp.MustCompile(`\n[ \t]*\n(\s*\n)+`)
+)
+
+// Mailer 邮件发送接口
+type Mailer interface {
+	Send(msg *Message) error
+}
+
+// Attachment 邮件附件
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// Message 邮件，HTML与Text同时存在时生成multipart/alternative，有附件时外层为multipart/mixed
+type Message struct {
+	From        string //发件人，可带显示名称，如 站点 <noreply@example.com>
+	To          []string
+	Cc          []string
+	Bcc         []string
+	ReplyTo     string
+	Subject     string
+	Text        string
+	HTML        string
+	Attachments []Attachment
+	Headers     map[string]string
+	Date        time.Time
+	MessageID   string
+}
+
+// Attach 添加附件，未指定类型时按文件扩展名判断
+func (m *Message) Attach(filename string, data []byte) {
+	m.Attachments = append(m.Attachments, Attachment{Filename: filename, Data: data})
+}
+
+// AttachFile 以文件内容添加附件
+func (m *Message) AttachFile(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	m.Attach(filepath.Base(path), data)
+	return nil
+}
+
+// Recipients 获得信封收件人地址，包含抄送及密送
+func (m *Message) Recipients() ([]string, error) {
+	var list []string
+	for _, field := range [][]string{m.To, m.Cc, m.Bcc} {
+		for _, s := range field {
+			addrs, err := mail.ParseAddressList(s)
+			if err != nil {
+				return nil, fmt.Errorf("收件人地址[%s]无效：%v", s, err)
+			}
+			for _, a := range addrs {
+				list = append(list, a.Address)
+			}
+		}
+	}
+	if len(list) == 0 {
+		return nil, ErrNoRecipients
+	}
+	return list, nil
+}
+
+// Sender 获得信封发件人地址
+func (m *Message) Sender() (string, error) {
+	if len(strings.TrimSpace(m.From)) == 0 {
+		return "", ErrNoSender
+	}
+	a, err := mail.ParseAddress(m.From)
+	if err != nil {
+		return "", fmt.Errorf("发件人地址[%s]无效：%v", m.From, err)
+	}
+	return a.Address, nil
+}
+
+// Bytes 生成MIME编码的邮件原文，标题及显示名称按RFC 2047编码，正文使用quoted-printable，附件使用base64
+func (m *Message) Bytes() ([]byte, error) {
+	from, err := formatAddressList([]string{m.From})
+	if err != nil || len(from) == 0 {
+		return nil, ErrNoSender
+	}
+	to, err := formatAddressList(m.To)
+	if err != nil {
+		return nil, err
+	}
+	cc, err := formatAddressList(m.Cc)
+	if err != nil {
+		return nil, err
+	}
+	if len(to) == 0 && len(cc) == 0 && len(m.Bcc) == 0 {
+		return nil, ErrNoRecipients
+	}
+
+	date := m.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+	messageID := m.MessageID
+	if len(messageID) == 0 {
+		messageID = newMessageID(from)
+	}
+
+	var buf bytes.Buffer
+	writeHeader(&buf, "From", from)
+	if len(to) > 0 {
+		writeHeader(&buf, "To", to)
+	}
+	if len(cc) > 0 {
+		writeHeader(&buf, "Cc", cc)
+	}
+	if len(m.ReplyTo) > 0 {
+		replyTo, err := formatAddressList([]string{m.ReplyTo})
+		if err != nil {
+			return nil, err
+		}
+		writeHeader(&buf, "Reply-To", replyTo)
+	}
+	writeHeader(&buf, "Subject", mime.BEncoding.Encode("UTF-8", m.Subject))
+	writeHeader(&buf, "Date", date.Format(time.RFC1123Z))
+	writeHeader(&buf, "Message-ID", messageID)
+	writeHeader(&buf, "MIME-Version", "1.0")
+	keys := make([]string, 0, len(m.Headers))
+	for k := range m.Headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		writeHeader(&buf, textproto.CanonicalMIMEHeaderKey(k), mime.QEncoding.Encode("UTF-8", m.Headers[k]))
+	}
+
+	body, header, err := m.body()
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range []string{"Content-Type", "Content-Transfer-Encoding"} {
+		if v := header.Get(k); len(v) > 0 {
+			writeHeader(&buf, k, v)
+		}
+	}
+	buf.WriteString("\r\n")
+	buf.Write(body)
+	return buf.Bytes(), nil
+}
+
+// body 生成邮件正文及其头部
+func (m *Message) body() ([]byte, textproto.MIMEHeader, error) {
+	text := m.Text
+	if len(text) == 0 && len(m.HTML) > 0 {
+		text = HTMLToText(m.HTML)
+	}
+
+	var parts []textPart
+	parts = append(parts, textPart{"text/plain", text})
+	if len(m.HTML) > 0 {
+		parts = append(parts, textPart{"text/html", m.HTML})
+	}
+	content, header, err := alternative(parts)
+	if err != nil || len(m.Attachments) == 0 {
+		return content, header, err
+	}
+
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	part, err := w.CreatePart(header)
+	if err != nil {
+		return nil, nil, err
+	}
+	part.Write(content)
+	for _, a := range m.Attachments {
+		contentType := a.ContentType
+		if len(contentType) == 0 {
+			if contentType = mime.TypeByExtension(filepath.Ext(a.Filename)); len(contentType) == 0 {
+				contentType = "application/octet-stream"
+			}
+		}
+		h := textproto.MIMEHeader{}
+		h.Set("Content-Type", contentType)
+		h.Set("Content-Transfer-Encoding", "base64")
+		h.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename}))
+		part, err := w.CreatePart(h)
+		if err != nil {
+			return nil, nil, err
+		}
+		writeBase64(part, a.Data)
+	}
+	if err := w.Close(); err != nil {
+		return nil, nil, err
+	}
+	header = textproto.MIMEHeader{}
+	header.Set("Content-Type", mime.FormatMediaType("multipart/mixed", map[string]string{"boundary": w.Boundary()}))
+	return buf.Bytes(), header, nil
+}
+
+type textPart struct {
+	contentType string
+	content     string
+}
+
+// alternative 生成单个正文，或多个正文组成的multipart/alternative
+func alternative(parts []textPart) ([]byte, textproto.MIMEHeader, error) {
+	if len(parts) == 1 {
+		header := textPartHeader(parts[0].contentType)
+		body, err := encodeQuotedPrintable(parts[0].content)
+		return body, header, err
+	}
+
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for _, p := range parts {
+		part, err := w.CreatePart(textPartHeader(p.contentType))
+		if err != nil {
+			return nil, nil, err
+		}
+		body, err := encodeQuotedPrintable(p.content)
+		if err != nil {
+			return nil, nil, err
+		}
+		part.Write(body)
+	}
+	if err := w.Close(); err != nil {
+		return nil, nil, err
+	}
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", mime.FormatMediaType("multipart/alternative", map[string]string{"boundary": w.Boundary()}))
+	return buf.Bytes(), header, nil
+}
+
+func textPartHeader(contentType string) textproto.MIMEHeader {
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Type", contentType+"; charset=UTF-8")
+	h.Set("Content-Transfer-Encoding", "quoted-printable")
+	return h
+}
+
+func encodeQuotedPrintable(s string) ([]byte, error) {
+	var buf bytes.Buffer
+	w := quotedprintable.NewWriter(&buf)
+	if _, err := w.Write([]byte(s)); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeBase64 以每行76个字符写入base64编码的内容
+func writeBase64(w io.Writer, data []byte) {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		io.WriteString(w, encoded[:76]+"\r\n")
+		encoded = encoded[76:]
+	}
+	io.WriteString(w, encoded+"\r\n")
+}
+
+func writeHeader(buf *bytes.Buffer, key, value string) {
+	buf.WriteString(key + ": " + value + "\r\n")
+}
+
+// formatAddressList 解析并格式化地址列表，显示名称按RFC 2047编码
+func formatAddressList(list []string) (string, error) {
+	var formatted []string
+	for _, s := range list {
+		if len(strings.TrimSpace(s)) == 0 {
+			continue
+		}
+		addrs, err := mail.ParseAddressList(s)
+		if err != nil {
+			return "", fmt.Errorf("邮件地址[%s]无效：%v", s, err)
+		}
+		for _, a := range addrs {
+			formatted = append(formatted, a.String())
+		}
+	}
+	return strings.Join(formatted, ", "), nil
+}
+
+func newMessageID(from string) string {
+	domain := "localhost"
+	if a, err := mail.ParseAddress(from); err == nil {
+		if i := strings.LastIndex(a.Address, "@"); i >= 0 {
+			domain = a.Address[i+1:]
+		}
+	}
+	b := make([]byte, 12)
+	rand.Read(b)
+	return fmt.Sprintf("<%d.%s@%s>", time.Now().UnixNano(), hex.EncodeToString(b), domain)
+}
+
+// HTMLToText 将HTML正文转换为纯文本，供不支持HTML的邮件客户端显示
+func HTMLToText(s string) string {
+	s = htmlBreakRegexp.ReplaceAllString(s, "$0\n")
+	s = htmlTagRegexp.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+	lines := strings.Split(strings.Replace(s, "\r\n", "\n", -1), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.TrimSpace(blankRegexp.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
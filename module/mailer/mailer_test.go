@@ -0,0 +1,174 @@
+package mailer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageBytes(t *testing.T) {
+	msg := &Message{
+		From:    "站点 <noreply@example.com>",
+		To:      []string{"张三 <zhang@example.com>", "li@example.com"},
+		Subject: "新评论：你好",
+		HTML:    "<p>你好，<b>世界</b></p><p>第二段</p>",
+	}
+	msg.Attach("说明.txt", []byte("附件内容"))
+
+	data, err := msg.Bytes()
+	assert.NoError(t, err)
+
+	m, err := mail.ReadMessage(bytes.NewReader(data))
+	assert.NoError(t, err)
+	subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	assert.NoError(t, err)
+	assert.Equal(t, "新评论：你好", subject)
+	to, err := m.Header.AddressList("To")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(to))
+	assert.Equal(t, "张三", to[0].Name)
+	assert.Equal(t, "li@example.com", to[1].Address)
+	assert.NotEmpty(t, m.Header.Get("Message-ID"))
+
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	assert.NoError(t, err)
+	assert.Equal(t, "multipart/mixed", mediaType)
+
+	mixed := multipart.NewReader(m.Body, params["boundary"])
+	part, err := mixed.NextPart()
+	assert.NoError(t, err)
+	mediaType, params, _ = mime.ParseMediaType(part.Header.Get("Content-Type"))
+	assert.Equal(t, "multipart/alternative", mediaType)
+
+	var bodies []string
+	alt := multipart.NewReader(part, params["boundary"])
+	for {
+		p, err := alt.NextPart()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		b, _ := ioutil.ReadAll(p)
+		bodies = append(bodies, p.Header.Get("Content-Type")+"|"+string(b))
+	}
+	assert.Equal(t, []string{
+		"text/plain; charset=UTF-8|你好，世界\r\n第二段",
+		"text/html; charset=UTF-8|<p>你好，<b>世界</b></p><p>第二段</p>",
+	}, bodies)
+
+	part, err = mixed.NextPart()
+	assert.NoError(t, err)
+	assert.Equal(t, "说明.txt", part.FileName())
+	assert.Equal(t, "text/plain; charset=utf-8", part.Header.Get("Content-Type"))
+	b, _ := ioutil.ReadAll(base64.NewDecoder(base64.StdEncoding, part))
+	assert.Equal(t, "附件内容", string(b))
+
+	_, err = (&Message{From: "noreply@example.com"}).Bytes()
+	assert.Equal(t, ErrNoRecipients, err)
+	_, err = (&Message{To: []string{"li@example.com"}}).Bytes()
+	assert.Equal(t, ErrNoSender, err)
+}
+
+func TestHTMLToText(t *testing.T) {
+	assert.Equal(t, "标题\n\n第一行\n第二行 & <标签>", HTMLToText("<style>p{}</style><h1>标题</h1>\n\n\n<p>第一行<br/>第二行 &amp; &lt;标签&gt;</p>"))
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(os.TempDir(), "zenpress_mailer_test")
+	os.RemoveAll(dir)
+	defer os.RemoveAll(dir)
+
+	f := &FileMailer{Dir: dir}
+	assert.Equal(t, ErrNoRecipients, f.Send(&Message{From: "noreply@example.com", Subject: "无收件人"}))
+	assert.NoError(t, f.Send(&Message{From: "noreply@example.com", To: []string{"li@example.com"}, Subject: "测试", Text: "正文"}))
+
+	files, err := f.Files()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(files))
+	data, _ := ioutil.ReadFile(files[0])
+	m, err := mail.ReadMessage(bytes.NewReader(data))
+	assert.NoError(t, err)
+	to, err := m.Header.AddressList("To")
+	assert.NoError(t, err)
+	assert.Equal(t, "li@example.com", to[0].Address)
+	assert.Equal(t, "text/plain; charset=UTF-8", m.Header.Get("Content-Type"))
+}
+
+func TestSMTPMailer(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer ln.Close()
+
+	received := make(chan []string, 1)
+	go fakeSMTPServer(ln, received)
+
+	port := ln.Addr().(*net.TCPAddr).Port
+	s := &SMTPMailer{Host: "127.0.0.1", Port: port}
+	err = s.Send(&Message{From: "noreply@example.com", To: []string{"li@example.com"}, Cc: []string{"wang@example.com"}, Subject: "测试", Text: "正文"})
+	assert.NoError(t, err)
+
+	commands := <-received
+	assert.Equal(t, "MAIL FROM:<noreply@example.com>", commands[1])
+	assert.Equal(t, "RCPT TO:<li@example.com>", commands[2])
+	assert.Equal(t, "RCPT TO:<wang@example.com>", commands[3])
+	assert.Equal(t, "DATA", commands[4])
+
+	s.Encryption = EncryptionSTARTTLS
+	go fakeSMTPServer(ln, received)
+	assert.Equal(t, ErrSTARTTLSNotSupported, s.Send(&Message{From: "noreply@example.com", To: []string{"li@example.com"}}))
+	<-received
+}
+
+// fakeSMTPServer 处理一个SMTP连接，不支持任何扩展，记录收到的命令
+func fakeSMTPServer(ln net.Listener, received chan<- []string) {
+	conn, err := ln.Accept()
+	if err != nil {
+		received <- nil
+		return
+	}
+	defer conn.Close()
+
+	var commands []string
+	r := bufio.NewReader(conn)
+	io.WriteString(conn, "220 localhost ESMTP\r\n")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			break
+		}
+		line = strings.TrimRight(line, "\r\n")
+		commands = append(commands, line)
+		switch {
+		case strings.HasPrefix(line, "EHLO"):
+			io.WriteString(conn, "250 localhost\r\n")
+		case line == "DATA":
+			io.WriteString(conn, "354 go ahead\r\n")
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil || l == ".\r\n" {
+					break
+				}
+			}
+			io.WriteString(conn, "250 ok\r\n")
+		case line == "QUIT":
+			io.WriteString(conn, "221 bye\r\n")
+			received <- commands
+			return
+		default:
+			io.WriteString(conn, "250 ok\r\n")
+		}
+	}
+	received <- commands
+}
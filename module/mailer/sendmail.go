@@ -0,0 +1,52 @@
+package mailer
+
+import (
+	"net"
+	"strconv"
+	"strings"
+)
+
+// 以下为SendEmail使用的SMTP设置，默认为空，站点邮件请在后台的邮件设置中配置
+var (
+	SmtpHost     = ""
+	SmtpPort     = "25"
+	MailUser     = "" //发送邮件的邮箱
+	MailPassword = "" //发送邮件邮箱的密码
+	MailAdline   = ""
+)
+
+/**
+* user : example@example.com login smtp server user
+* password: xxxxx login smtp server password
+* host: smtp.example.com:port   smtp.163.com:25
+* to: example@example.com;example1@163.com;example2@sina.com.cn;...
+* subject:The subject of mail
+* body: The content of mail
+* mailtype: mail type html or text
+*
+* Deprecated: 请使用 model.QueueMail 经后台队列发送，或直接使用 SMTPMailer
+ */
+func SendMail(user, password, host, to, subject, body, mailtype string) error {
+	hostname, port := host, 25
+	if h, p, err := net.SplitHostPort(host); err == nil {
+		hostname = h
+		port, _ = strconv.Atoi(p)
+	}
+	msg := &Message{From: user, Subject: subject}
+	for _, addr := range strings.Split(to, ";") {
+		if addr = strings.TrimSpace(addr); len(addr) > 0 {
+			msg.To = append(msg.To, addr)
+		}
+	}
+	if mailtype == "html" {
+		msg.HTML = body
+	} else {
+		msg.Text = body
+	}
+	return (&SMTPMailer{Host: hostname, Port: port, Username: user, Password: password}).Send(msg)
+}
+
+// Deprecated: 请使用 model.QueueMail
+func SendEmail(to, subject, body, mailtype string) error {
+	return SendMail(MailUser, MailPassword, (SmtpHost + ":" + SmtpPort), to, subject, body, mailtype)
+}
@@ -0,0 +1,229 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/smtp"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+const (
+	// EncryptionNone 不加密连接
+	EncryptionNone = ""
+	// EncryptionSTARTTLS 以明文连接后通过STARTTLS升级为加密连接，通常使用587端口
+	EncryptionSTARTTLS = "starttls"
+	// EncryptionSSL 直接建立TLS连接，通常使用465端口
+	EncryptionSSL = "ssl"
+
+	// DefaultSendmailPath sendmail程序的默认路径
+	DefaultSendmailPath = "/usr/sbin/sendmail"
+	// DefaultTimeout 连接邮件服务器的默认超时时间
+	DefaultTimeout = 30 * time.Second
+)
+
+var (
+	// ErrSTARTTLSNotSupported 邮件服务器不支持STARTTLS
+	ErrSTARTTLSNotSupported = errors.New("邮件服务器不支持STARTTLS")
+	// ErrInvalidEncryption 不支持的加密方式
+	ErrInvalidEncryption = errors.New("不支持的加密方式")
+)
+
+// SMTPMailer 通过SMTP服务器发送邮件
+type SMTPMailer struct {
+	Host               string
+	Port               int
+	Username           string
+	Password           string
+	Encryption         string //加密方式，可选 EncryptionNone、EncryptionSTARTTLS、EncryptionSSL
+	InsecureSkipVerify bool   //不校验服务器证书，仅用于测试环境
+	Timeout            time.Duration
+}
+
+// Send 发送邮件
+func (s *SMTPMailer) Send(msg *Message) error {
+	from, err := msg.Sender()
+	if err != nil {
+		return err
+	}
+	to, err := msg.Recipients()
+	if err != nil {
+		return err
+	}
+	data, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+
+	client, err := s.dial()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if len(s.Username) > 0 {
+		if ok, _ := client.Extension("AUTH"); ok {
+			if err = client.Auth(smtp.PlainAuth("", s.Username, s.Password, s.Host)); err != nil {
+				return err
+			}
+		}
+	}
+	if err = client.Mail(from); err != nil {
+		return err
+	}
+	for _, addr := range to {
+		if err = client.Rcpt(addr); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(data); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// dial 连接邮件服务器，按加密方式建立TLS连接或升级为加密连接
+func (s *SMTPMailer) dial() (*smtp.Client, error) {
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	port := s.Port
+	if port <= 0 {
+		switch s.Encryption {
+		case EncryptionSSL:
+			port = 465
+		case EncryptionSTARTTLS:
+			port = 587
+		default:
+			port = 25
+		}
+	}
+	addr := net.JoinHostPort(s.Host, strconv.Itoa(port))
+	config := &tls.Config{ServerName: s.Host, InsecureSkipVerify: s.InsecureSkipVerify}
+	dialer := &net.Dialer{Timeout: timeout}
+
+	var conn net.Conn
+	var err error
+	switch s.Encryption {
+	case EncryptionSSL:
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, config)
+	case EncryptionNone, EncryptionSTARTTLS:
+		conn, err = dialer.Dial("tcp", addr)
+	default:
+		return nil, ErrInvalidEncryption
+	}
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	client, err := smtp.NewClient(conn, s.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if s.Encryption == EncryptionSTARTTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			client.Close()
+			return nil, ErrSTARTTLSNotSupported
+		}
+		if err = client.StartTLS(config); err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
+	return client, nil
+}
+
+// SendmailMailer 通过本机sendmail程序发送邮件
+type SendmailMailer struct {
+	Path string   //sendmail程序路径，为空时使用 DefaultSendmailPath
+	Args []string //sendmail参数，为空时使用 -t -i，即从邮件头读取收件人
+}
+
+// Send 发送邮件
+func (s *SendmailMailer) Send(msg *Message) error {
+	if _, err := msg.Recipients(); err != nil {
+		return err
+	}
+	data, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+	path := s.Path
+	if len(path) == 0 {
+		path = DefaultSendmailPath
+	}
+	args := s.Args
+	if len(args) == 0 {
+		args = []string{"-t", "-i"}
+	}
+	if from, err := msg.Sender(); err == nil {
+		args = append([]string{"-f", from}, args...)
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(path, args...)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if stderr.Len() > 0 {
+			return fmt.Errorf("%v: %s", err, bytes.TrimSpace(stderr.Bytes()))
+		}
+		return err
+	}
+	return nil
+}
+
+// FileMailer 将邮件保存为目录中的.eml文件而不实际发送，用于开发及测试环境
+type FileMailer struct {
+	Dir string
+}
+
+// Send 保存邮件
+func (f *FileMailer) Send(msg *Message) error {
+	if _, err := msg.Recipients(); err != nil {
+		return err
+	}
+	data, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(f.Dir, 0755); err != nil {
+		return err
+	}
+	b := make([]byte, 4)
+	rand.Read(b)
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102-150405.000000000"), hex.EncodeToString(b))
+	tmp := filepath.Join(f.Dir, "."+name+".tmp")
+	if err = ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, filepath.Join(f.Dir, name)); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// Files 获得目录中已保存的邮件文件，按保存时间排序
+func (f *FileMailer) Files() ([]string, error) {
+	return filepath.Glob(filepath.Join(f.Dir, "*.eml"))
+}
@@ -29,6 +29,7 @@ import (
 	_ "qlang.io/lib/chan"
 
 	extFmt "github.com/insionng/zenpress/extension/fmt"
+	extLog "github.com/insionng/zenpress/extension/log"
 	extStrings "github.com/insionng/zenpress/extension/strings"
 
 	"github.com/insionng/zenpress/extension/github.com/insionng/makross"
@@ -40,8 +41,11 @@ import (
 	"github.com/insionng/zenpress/extension/github.com/insionng/makross/static"
 	"github.com/insionng/zenpress/extension/github.com/insionng/zenpress/helper"
 	"github.com/insionng/zenpress/extension/github.com/insionng/zenpress/model"
+	"github.com/insionng/zenpress/extension/github.com/insionng/zenpress/module/auth"
 	"github.com/insionng/zenpress/extension/github.com/insionng/zenpress/module/hook"
+	"github.com/insionng/zenpress/extension/github.com/insionng/zenpress/module/mailer"
 	"github.com/insionng/zenpress/extension/github.com/insionng/zenpress/module/switchr"
+	"github.com/insionng/zenpress/extension/github.com/insionng/zenpress/module/theme"
 )
 
 // -----------------------------------------------------------------------------
@@ -94,7 +98,11 @@ func InitSafe(safeMode bool) {
 	qlang.Import("helper", helper.Exports)
 
 	qlang.Import("hook", hook.Exports)
+	qlang.Import("auth", auth.Exports)
+	qlang.Import("mailer", mailer.Exports)
+	qlang.Import("themes", theme.Exports)
 	qlang.Import("fmt", extFmt.Exports)
+	qlang.Import("log", extLog.Exports)
 	qlang.Import("strings", extStrings.Exports)
 }
 
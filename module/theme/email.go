@@ -0,0 +1,33 @@
+package theme
+
+import (
+	"fmt"
+
+	"github.com/flosch/pongo2"
+	"github.com/insionng/zenpress/helper"
+	"github.com/insionng/zenpress/model"
+)
+
+var (
+	// EmailTemplateDir 默认邮件模板所在目录，主题可在template/email目录下提供同名模板覆盖
+	EmailTemplateDir = "template/email"
+)
+
+// LocateEmail 查找邮件模板文件，主题中存在同名模板时优先使用主题的模板
+func LocateEmail(theme, name string) string {
+	if file := fmt.Sprintf("%s/%s/template/email/%s.html", ThemeDir, theme, name); helper.IsExist(file) {
+		return file
+	}
+	return fmt.Sprintf("%s/%s.html", EmailTemplateDir, name)
+}
+
+// EmailRenderer 获得以主题模板渲染邮件的渲染器，供model.SetEmailRenderer使用
+func EmailRenderer(theme string) model.EmailRenderer {
+	return func(name string, data map[string]interface{}) (string, error) {
+		tpl, err := pongo2.FromFile(LocateEmail(theme, name))
+		if err != nil {
+			return "", err
+		}
+		return tpl.Execute(pongo2.Context(data))
+	}
+}
@@ -0,0 +1,121 @@
+package theme
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/insionng/zenpress/model"
+
+	"github.com/flosch/pongo2"
+)
+
+// SingleTemplates 文章页的模板层级，按WordPress的规则依次为自定义模板、别名模板、ID模板、类型模板
+func SingleTemplates(post model.Post) []string {
+	var names []string
+	if custom := model.GetPostmetaValue(post.ID, "_wp_page_template"); len(custom) > 0 && custom != "default" {
+		names = append(names, strings.TrimSuffix(custom, ".html"))
+	}
+
+	switch post.PostType {
+	case "page":
+		if len(post.PostName) > 0 {
+			names = append(names, "page-"+post.PostName)
+		}
+		names = append(names, fmt.Sprintf("page-%d", post.ID), "page")
+	case "attachment":
+		if mimes := strings.SplitN(post.PostMimeType, "/", 2); len(mimes) == 2 {
+			names = append(names, mimes[0], mimes[1], mimes[0]+"_"+mimes[1])
+		}
+		names = append(names, "attachment", "single-attachment", "single")
+	default:
+		if len(post.PostName) > 0 {
+			names = append(names, fmt.Sprintf("single-%s-%s", post.PostType, post.PostName))
+		}
+		names = append(names, "single-"+post.PostType, "single")
+	}
+	return names
+}
+
+// PostTypeArchiveTemplates 文章类型归档页的模板层级
+func PostTypeArchiveTemplates(postType string) []string {
+	return []string{"archive-" + postType, "archive"}
+}
+
+// TaxonomyTemplates 分类归档页的模板层级，分类目录为category-{slug}、category-{id}、category，
+// 标签为tag-{slug}、tag-{id}、tag，其他分类法为taxonomy-{taxonomy}-{slug}、taxonomy-{taxonomy}、taxonomy
+func TaxonomyTemplates(term *model.TaxonomyTerm) []string {
+	var names []string
+	switch term.Taxonomy {
+	case "category", "post_tag":
+		prefix := "category"
+		if term.Taxonomy == "post_tag" {
+			prefix = "tag"
+		}
+		if len(term.Slug) > 0 {
+			names = append(names, prefix+"-"+term.Slug)
+		}
+		names = append(names, fmt.Sprintf("%s-%d", prefix, term.TermID), prefix)
+	default:
+		if len(term.Slug) > 0 {
+			names = append(names, fmt.Sprintf("taxonomy-%s-%s", term.Taxonomy, term.Slug))
+		}
+		names = append(names, "taxonomy-"+term.Taxonomy, "taxonomy")
+	}
+	return append(names, "archive")
+}
+
+// AuthorTemplates 作者归档页的模板层级，依次为author-{nicename}、author-{id}、author
+func AuthorTemplates(user model.User) []string {
+	var names []string
+	if len(user.UserNicename) > 0 {
+		names = append(names, "author-"+user.UserNicename)
+	}
+	return append(names, fmt.Sprintf("author-%d", user.ID), "author", "archive")
+}
+
+// LocateSingle 查找文章页模板
+func LocateSingle(theme string, post model.Post) string {
+	return Locate(theme, SingleTemplates(post)...)
+}
+
+// LocatePostTypeArchive 查找文章类型归档页模板
+func LocatePostTypeArchive(theme string, postType string) string {
+	return Locate(theme, PostTypeArchiveTemplates(postType)...)
+}
+
+// LocateTaxonomy 查找分类归档页模板
+func LocateTaxonomy(theme string, term *model.TaxonomyTerm) string {
+	return Locate(theme, TaxonomyTemplates(term)...)
+}
+
+// LocateAuthor 查找作者归档页模板
+func LocateAuthor(theme string, user model.User) string {
+	return Locate(theme, AuthorTemplates(user)...)
+}
+
+// TermLink 获得分类链接，供模板调用
+func TermLink(term *model.TaxonomyTerm) string {
+	return model.GetTaxonomyTermLink(term)
+}
+
+// Permalink 获得文章链接，供模板调用，可传入Post或*Post
+func Permalink(post interface{}) string {
+	switch p := post.(type) {
+	case model.Post:
+		return model.GetPermalink(&p)
+	case *model.Post:
+		return model.GetPermalink(p)
+	}
+	return ""
+}
+
+// Content 获得文章过滤后的HTML内容
+func Content(post interface{}) *pongo2.Value {
+	switch p := post.(type) {
+	case model.Post:
+		return pongo2.AsSafeValue(model.GetPostContentHTML(&p))
+	case *model.Post:
+		return pongo2.AsSafeValue(model.GetPostContentHTML(p))
+	}
+	return pongo2.AsValue("")
+}
@@ -0,0 +1,129 @@
+package theme
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strings"
+
+	"github.com/flosch/pongo2"
+	"github.com/insionng/makross"
+	"github.com/insionng/zenpress/helper"
+	"github.com/insionng/zenpress/model"
+)
+
+// NavMenu 渲染菜单位置上的菜单项，外层ul由主题模板决定，currentPath用于标记当前菜单项。
+// 菜单位置未指定菜单时以首页及分类列表代替
+func NavMenu(location string, currentPath string) template.HTML {
+	var items []*model.NavMenuItem
+	if menuID, okay := model.GetNavMenuLocations()[location]; okay && menuID > 0 {
+		items = model.GetNavMenuTree(menuID)
+	} else {
+		items = fallbackNavMenuItems(model.GetTaxonomyTermTree("category"))
+	}
+	if len(items) == 0 {
+		return ""
+	}
+
+	var buf bytes.Buffer
+	renderNavMenuItems(&buf, items, currentPath)
+	return template.HTML(buf.String())
+}
+
+// Funcs 主题模板中可用的函数
+func Funcs(theme string, currentPath string) map[string]interface{} {
+	return map[string]interface{}{
+		"nav_menu": func(location string) *pongo2.Value {
+			return pongo2.AsSafeValue(NavMenu(location, currentPath))
+		},
+		"permalink":    Permalink,
+		"the_content":  Content,
+		"term_link":    TermLink,
+		"get_archives": model.GetYearlyArchives,
+		"date_link":    model.GetDateArchiveLink,
+		"author_link":  model.GetAuthorLink,
+		"attachment_image": func(id *pongo2.Value, size string) *pongo2.Value {
+			return pongo2.AsSafeValue(model.GetAttachmentImage(uint64(id.Integer()), size))
+		},
+		"attachment_image_url": func(id *pongo2.Value, size string) string {
+			src, _, _ := model.GetAttachmentImageSrc(uint64(id.Integer()), size)
+			return src
+		},
+		"image_srcset": func(id *pongo2.Value, size string) string {
+			return model.GetAttachmentImageSrcset(uint64(id.Integer()), size)
+		},
+		"image_sizes": func(id *pongo2.Value, size string) string {
+			return model.GetAttachmentImageSizes(uint64(id.Integer()), size)
+		},
+		"image_transform":  model.ImageTransformURL,
+		"size_format":      helper.SizeFormat,
+		"safe_url":         helper.SafeURL,
+		"gravatar":         helper.Gravatar,
+		"admin_post_types": model.GetAdminPostTypes,
+		"admin_taxonomies": model.GetAdminTaxonomies,
+	}
+}
+
+// fallbackNavMenuItems 以首页及分类树生成菜单项
+func fallbackNavMenuItems(terms []*model.TaxonomyTerm) []*model.NavMenuItem {
+	items := []*model.NavMenuItem{{Title: "首页", URL: "/", Type: "custom", Object: "custom", Classes: "menu-item-home"}}
+	return append(items, termNavMenuItems(terms)...)
+}
+
+func termNavMenuItems(terms []*model.TaxonomyTerm) (items []*model.NavMenuItem) {
+	for _, term := range terms {
+		items = append(items, &model.NavMenuItem{
+			ID:       term.TermTaxonomyID,
+			Title:    term.Name,
+			URL:      model.GetTaxonomyTermLink(term),
+			Type:     "taxonomy",
+			Object:   term.Taxonomy,
+			ObjectID: term.TermTaxonomyID,
+			Children: termNavMenuItems(term.Children),
+		})
+	}
+	return
+}
+
+func renderNavMenuItems(buf *bytes.Buffer, items []*model.NavMenuItem, currentPath string) {
+	for _, item := range items {
+		classes := []string{
+			"menu-item",
+			"menu-item-type-" + item.Type,
+			"menu-item-object-" + item.Object,
+			fmt.Sprintf("menu-item-%d", item.ID),
+		}
+		if len(item.Children) > 0 {
+			classes = append(classes, "menu-item-has-children")
+		}
+		if len(currentPath) > 0 && item.URL == currentPath {
+			classes = append(classes, "current-menu-item")
+		}
+		if len(item.Classes) > 0 {
+			classes = append(classes, strings.Fields(item.Classes)...)
+		}
+
+		buf.WriteString(fmt.Sprintf(`<li id="menu-item-%d" class="%s"><a href="%s"`, item.ID, template.HTMLEscapeString(strings.Join(classes, " ")), template.HTMLEscapeString(item.URL)))
+		if len(item.Target) > 0 {
+			buf.WriteString(fmt.Sprintf(` target="%s"`, template.HTMLEscapeString(item.Target)))
+		}
+		buf.WriteString(">" + template.HTMLEscapeString(item.Title) + "</a>")
+
+		if len(item.Children) > 0 {
+			buf.WriteString(`<ul class="sub-menu">`)
+			renderNavMenuItems(buf, item.Children, currentPath)
+			buf.WriteString("</ul>")
+		}
+		buf.WriteString("</li>")
+	}
+}
+
+// Themer 返回主题中间件，为主题模板注入模板函数
+func Themer(theme string) makross.Handler {
+	return func(c *makross.Context) error {
+		for name, function := range Funcs(theme, c.Request.URL.Path) {
+			c.Set(name, function)
+		}
+		return c.Next()
+	}
+}
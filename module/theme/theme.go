@@ -0,0 +1,88 @@
+package theme
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sync"
+
+	"github.com/insionng/zenpress/helper"
+	"github.com/insionng/zenpress/model"
+)
+
+var (
+	// ThemeDir 主题所在目录
+	ThemeDir = "content/theme"
+
+	// themeImageSizes 上一次由主题清单注册的图片尺寸，切换或重新加载主题时先行注销
+	themeImageSizes     []string
+	themeImageSizesLock sync.Mutex
+)
+
+// Manifest 主题清单，对应主题目录下的theme.json文件
+type Manifest struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Version     string            `json:"version"`
+	Author      string            `json:"author"`
+	Menus       map[string]string `json:"menus"` //菜单位置 => 菜单位置描述
+	// ImageSizes 主题使用的图片尺寸，上传图片时按这些尺寸生成缩放后的图片
+	ImageSizes map[string]model.ImageSize `json:"image_sizes"`
+}
+
+// LoadManifest 读取主题清单，主题未提供清单时返回空清单
+func LoadManifest(theme string) (*Manifest, error) {
+	manifest := &Manifest{Name: theme, Menus: map[string]string{}}
+
+	file := fmt.Sprintf("%s/%s/theme.json", ThemeDir, theme)
+	if !helper.IsExist(file) {
+		return manifest, nil
+	}
+
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return manifest, err
+	}
+	if err = json.Unmarshal(b, manifest); err != nil {
+		return manifest, fmt.Errorf("主题[%s]清单解析出错:%v", theme, err)
+	}
+	if manifest.Menus == nil {
+		manifest.Menus = map[string]string{}
+	}
+	return manifest, nil
+}
+
+// RegisterImageSizes 注册主题清单中声明的图片尺寸，并注销之前加载的主题注册的尺寸
+func RegisterImageSizes(theme string) error {
+	themeImageSizesLock.Lock()
+	defer themeImageSizesLock.Unlock()
+	for _, name := range themeImageSizes {
+		model.UnregisterImageSize(name)
+	}
+	themeImageSizes = nil
+
+	manifest, err := LoadManifest(theme)
+	if err != nil {
+		return err
+	}
+	for name, size := range manifest.ImageSizes {
+		if err := model.RegisterImageSize(name, size.Width, size.Height, size.Crop); err != nil {
+			return fmt.Errorf("主题[%s]：%v", theme, err)
+		}
+		themeImageSizes = append(themeImageSizes, name)
+	}
+	return nil
+}
+
+// Locate 按模板层级顺序查找主题中第一个存在的模板，均不存在时返回index
+func Locate(theme string, names ...string) string {
+	for _, name := range names {
+		if len(name) == 0 {
+			continue
+		}
+		if helper.IsExist(fmt.Sprintf("%s/%s/template/%s.html", ThemeDir, theme, name)) {
+			return name
+		}
+	}
+	return "index"
+}
@@ -0,0 +1,140 @@
+package theme
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/insionng/zenpress/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocate(t *testing.T) {
+	dir := filepath.Join(os.TempDir(), "zenpress_theme_test")
+	os.MkdirAll(filepath.Join(dir, "demo", "template"), os.ModePerm)
+	defer os.RemoveAll(dir)
+
+	f, _ := os.Create(filepath.Join(dir, "demo", "template", "category.html"))
+	f.Close()
+
+	old := ThemeDir
+	ThemeDir = dir
+	defer func() { ThemeDir = old }()
+
+	assert.Equal(t, "category", Locate("demo", "category-news", "category", "archive"))
+	assert.Equal(t, "index", Locate("demo", "tag-news", "tag", "archive"))
+}
+
+func TestLoadManifest(t *testing.T) {
+	manifest, err := LoadManifest("not-exist-theme")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "not-exist-theme", manifest.Name)
+		assert.Equal(t, 0, len(manifest.Menus))
+	}
+}
+
+func TestRenderNavMenuItems(t *testing.T) {
+	items := []*model.NavMenuItem{
+		{ID: 1, Title: "首页", URL: "/", Type: "custom", Object: "custom", Children: []*model.NavMenuItem{
+			{ID: 2, Title: "新闻", URL: "/category/news", Type: "taxonomy", Object: "category", Target: "_blank"},
+		}},
+	}
+
+	var buf bytes.Buffer
+	renderNavMenuItems(&buf, items, "/category/news")
+	html := buf.String()
+
+	assert.Contains(t, html, `id="menu-item-1"`)
+	assert.Contains(t, html, "menu-item-has-children")
+	assert.Contains(t, html, `<ul class="sub-menu">`)
+	assert.Contains(t, html, `current-menu-item`)
+	assert.Contains(t, html, `target="_blank"`)
+}
+
+func TestFallbackNavMenuItems(t *testing.T) {
+	terms := []*model.TaxonomyTerm{
+		{TermTaxonomyID: 3, Name: "新闻", Slug: "news", Taxonomy: "category", Children: []*model.TaxonomyTerm{
+			{TermTaxonomyID: 4, Name: "公司", Slug: "companies", Taxonomy: "category", Parent: 3},
+		}},
+	}
+
+	var buf bytes.Buffer
+	renderNavMenuItems(&buf, fallbackNavMenuItems(terms), "/")
+	html := buf.String()
+
+	assert.Contains(t, html, `current-menu-item menu-item-home`)
+	assert.Contains(t, html, `<a href="/category/news">新闻</a>`)
+	assert.Contains(t, html, `<ul class="sub-menu"><li id="menu-item-4"`)
+}
+
+func TestSingleTemplates(t *testing.T) {
+	assert.Equal(t, []string{"page-about", "page-2", "page"}, SingleTemplates(model.Post{ID: 2, PostType: "page", PostName: "about"}))
+	assert.Equal(t, []string{"single-product-phone", "single-product", "single"}, SingleTemplates(model.Post{PostType: "product", PostName: "phone"}))
+	assert.Equal(t, []string{"image", "png", "image_png", "attachment", "single-attachment", "single"}, SingleTemplates(model.Post{PostType: "attachment", PostMimeType: "image/png"}))
+	assert.Equal(t, []string{"archive-product", "archive"}, PostTypeArchiveTemplates("product"))
+}
+
+func TestTaxonomyTemplates(t *testing.T) {
+	assert.Equal(t, []string{"category-news", "category-3", "category", "archive"}, TaxonomyTemplates(&model.TaxonomyTerm{TermID: 3, Slug: "news", Taxonomy: "category"}))
+	assert.Equal(t, []string{"tag-go", "tag-5", "tag", "archive"}, TaxonomyTemplates(&model.TaxonomyTerm{TermID: 5, Slug: "go", Taxonomy: "post_tag"}))
+	assert.Equal(t, []string{"taxonomy-genre-jazz", "taxonomy-genre", "taxonomy", "archive"}, TaxonomyTemplates(&model.TaxonomyTerm{TermID: 7, Slug: "jazz", Taxonomy: "genre"}))
+}
+
+func TestAuthorTemplates(t *testing.T) {
+	assert.Equal(t, []string{"author-insion", "author-1", "author", "archive"}, AuthorTemplates(model.User{ID: 1, UserNicename: "insion"}))
+}
+
+func TestRegisterImageSizes(t *testing.T) {
+	dir := filepath.Join(os.TempDir(), "zenpress_theme_sizes_test")
+	os.MkdirAll(filepath.Join(dir, "demo"), os.ModePerm)
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "demo", "theme.json"), []byte(`{"image_sizes": {"demo-card": {"width": 320, "height": 180, "crop": true}}}`), 0644)
+	os.MkdirAll(filepath.Join(dir, "other"), os.ModePerm)
+	ioutil.WriteFile(filepath.Join(dir, "other", "theme.json"), []byte(`{"image_sizes": {"other-hero": {"width": 1200, "height": 400}}}`), 0644)
+
+	old := ThemeDir
+	ThemeDir = dir
+	defer func() { ThemeDir = old }()
+
+	if assert.NoError(t, RegisterImageSizes("demo")) {
+		defer model.UnregisterImageSize("demo-card")
+		size, okay := model.GetImageSize("demo-card")
+		if assert.True(t, okay) {
+			assert.Equal(t, model.ImageSize{Name: "demo-card", Width: 320, Height: 180, Crop: true}, *size)
+		}
+	}
+
+	if assert.NoError(t, RegisterImageSizes("other")) {
+		defer model.UnregisterImageSize("other-hero")
+		_, okay := model.GetImageSize("demo-card")
+		assert.False(t, okay, "sizes of the previous theme should be unregistered")
+		_, okay = model.GetImageSize("other-hero")
+		assert.True(t, okay)
+	}
+}
+
+func TestEmailRenderer(t *testing.T) {
+	dir := filepath.Join(os.TempDir(), "zenpress_email_test")
+	os.MkdirAll(filepath.Join(dir, "demo", "template", "email"), os.ModePerm)
+	os.MkdirAll(filepath.Join(dir, "default"), os.ModePerm)
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "default", "reply.html"), []byte("Subject: 默认 {{name}}\n<p>默认</p>"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "demo", "template", "email", "reply.html"), []byte("Subject: 主题 {{name}}\n<p>主题</p>"), 0644)
+
+	oldTheme, oldEmail := ThemeDir, EmailTemplateDir
+	ThemeDir, EmailTemplateDir = dir, filepath.Join(dir, "default")
+	defer func() { ThemeDir, EmailTemplateDir = oldTheme, oldEmail }()
+
+	out, err := EmailRenderer("demo")("reply", map[string]interface{}{"name": "评论"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Subject: 主题 评论\n<p>主题</p>", out)
+	}
+	out, err = EmailRenderer("other")("reply", map[string]interface{}{"name": "评论"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Subject: 默认 评论\n<p>默认</p>", out)
+	}
+	_, err = EmailRenderer("demo")("missing", nil)
+	assert.Error(t, err)
+}